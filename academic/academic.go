@@ -0,0 +1,327 @@
+// Package academic describes the UCL academic year: its terms, reading
+// weeks and closure days. A calendar is built in, and the operator can
+// replace it with an updated YAML file using Load.
+package academic
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed calendar.yaml
+var builtIn []byte
+
+var current = mustParse(builtIn)
+
+// Load replaces the built-in calendar with the YAML file at path. An empty
+// path keeps the built-in one. It must be called before the bot starts.
+func Load(path string) error {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	cal, err := Parse(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	current = cal
+	return nil
+}
+
+// Current returns the calendar in use.
+func Current() *Calendar {
+	return current
+}
+
+type Calendar struct {
+	Years []Year `yaml:"years"`
+}
+
+// Year is one academic year, covering Start to End.
+type Year struct {
+	Name     string   `yaml:"name"`
+	Start    Date     `yaml:"start"`
+	End      Date     `yaml:"end"`
+	Terms    []Term   `yaml:"terms"`
+	Closures []Period `yaml:"closures"`
+}
+
+// Term is a teaching term. ReadingWeeks holds the Monday of each of its
+// reading weeks.
+type Term struct {
+	Name         string `yaml:"name"`
+	Start        Date   `yaml:"start"`
+	End          Date   `yaml:"end"`
+	ReadingWeeks []Date `yaml:"reading_weeks"`
+}
+
+// Period is a named run of days, from Start to End inclusive.
+type Period struct {
+	Name  string `yaml:"name"`
+	Start Date   `yaml:"start"`
+	End   Date   `yaml:"end"`
+}
+
+// Date is a day without a time or timezone, written YYYY-MM-DD. It is held
+// as midnight UTC so dates can be compared and subtracted exactly.
+type Date struct {
+	time.Time
+}
+
+func (d *Date) UnmarshalYAML(node *yaml.Node) error {
+	t, err := time.Parse(time.DateOnly, node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: invalid date %q, want YYYY-MM-DD", node.Line, node.Value)
+	}
+	d.Time = t
+	return nil
+}
+
+// dateOf returns the day t falls on in its own location.
+func dateOf(t time.Time) Date {
+	return Date{time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)}
+}
+
+func (p Period) contains(d Date) bool {
+	return !d.Before(p.Start.Time) && !d.After(p.End.Time)
+}
+
+// Parse reads a calendar from YAML and checks that its periods are in order.
+func Parse(data []byte) (*Calendar, error) {
+	var cal Calendar
+	if err := yaml.Unmarshal(data, &cal); err != nil {
+		return nil, err
+	}
+	var errs []error
+	check := func(what string, start, end Date) {
+		if start.IsZero() || end.IsZero() {
+			errs = append(errs, fmt.Errorf("%s: start and end are required", what))
+		} else if end.Before(start.Time) {
+			errs = append(errs, fmt.Errorf("%s: end is before start", what))
+		}
+	}
+	for _, year := range cal.Years {
+		check("year "+year.Name, year.Start, year.End)
+		for _, term := range year.Terms {
+			check(year.Name+" "+term.Name, term.Start, term.End)
+		}
+		for _, closure := range year.Closures {
+			check(year.Name+" "+closure.Name, closure.Start, closure.End)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return &cal, nil
+}
+
+func mustParse(data []byte) *Calendar {
+	cal, err := Parse(data)
+	if err != nil {
+		panic("academic: invalid built-in calendar: " + err.Error())
+	}
+	return cal
+}
+
+// Position is where a day falls in the academic year.
+type Position struct {
+	// Year is nil when the calendar does not cover the day.
+	Year *Year
+	// Term is nil outside term. Week counts the weeks of the term from 1.
+	Term        *Term
+	Week        int
+	ReadingWeek bool
+	// Closure is set when UCL is closed on the day.
+	Closure *Period
+}
+
+// At returns the position of the day t falls on.
+func (c *Calendar) At(t time.Time) Position {
+	day := dateOf(t)
+	var pos Position
+	for i := range c.Years {
+		year := &c.Years[i]
+		if !(Period{Start: year.Start, End: year.End}).contains(day) {
+			continue
+		}
+		pos.Year = year
+		for j := range year.Terms {
+			term := &year.Terms[j]
+			if !(Period{Start: term.Start, End: term.End}).contains(day) {
+				continue
+			}
+			pos.Term = term
+			pos.Week = int(day.Sub(term.Start.Time).Hours()/24)/7 + 1
+			for _, monday := range term.ReadingWeeks {
+				if (Period{Start: monday, End: Date{monday.AddDate(0, 0, 6)}}).contains(day) {
+					pos.ReadingWeek = true
+				}
+			}
+		}
+		for j := range year.Closures {
+			if year.Closures[j].contains(day) {
+				pos.Closure = &year.Closures[j]
+			}
+		}
+		break
+	}
+	return pos
+}
+
+// Known reports whether the calendar covers the day.
+func (p Position) Known() bool {
+	return p.Year != nil
+}
+
+// InTerm reports whether the day is in term and UCL is open.
+func (p Position) InTerm() bool {
+	return p.Term != nil && p.Closure == nil
+}
+
+// term1UCLWeek is the UCL week term 1 starts in. UCL numbers the weeks of
+// its year from five weeks before term 1.
+const term1UCLWeek = 6
+
+// UCLWeek returns the UCL week number of the day t falls on, counted from
+// the start of term 1 of its academic year so that it agrees with TermWeek.
+// Days the calendar does not cover assume term 1 starts on the last Monday
+// of September.
+func (c *Calendar) UCLWeek(t time.Time) int {
+	day := dateOf(t)
+	return int(day.Sub(c.weekOne(day).Time).Hours()/24)/7 + 1
+}
+
+// weekOne returns the Monday starting UCL week 1 of the academic year that
+// day is numbered in. Days before it count in the previous year.
+func (c *Calendar) weekOne(day Date) Date {
+	for _, year := range c.Years {
+		if len(year.Terms) == 0 || !(Period{Start: year.Start, End: year.End}).contains(day) {
+			continue
+		}
+		start := Date{year.Terms[0].Start.AddDate(0, 0, -7*(term1UCLWeek-1))}
+		if day.Before(start.Time) {
+			return c.weekOne(Date{year.Start.AddDate(0, 0, -1)})
+		}
+		return start
+	}
+	start := assumedWeekOne(day.Year())
+	if day.Before(start.Time) {
+		start = assumedWeekOne(day.Year() - 1)
+	}
+	return start
+}
+
+// assumedWeekOne is week 1 of the academic year starting in year when term 1
+// starts on the last Monday of September.
+func assumedWeekOne(year int) Date {
+	lastMonday := time.Date(year, time.September, 30, 0, 0, 0, 0, time.UTC)
+	lastMonday = lastMonday.AddDate(0, 0, -int((lastMonday.Weekday()+6)%7))
+	return Date{lastMonday.AddDate(0, 0, -7*(term1UCLWeek-1))}
+}
+
+// TermWeek labels the day's week, such as "Term 1 Week 5", or returns ""
+// outside term.
+func (p Position) TermWeek() string {
+	if p.Term == nil {
+		return ""
+	}
+	label := fmt.Sprintf("%s Week %d", p.Term.Name, p.Week)
+	if p.ReadingWeek {
+		label += " (reading week)"
+	}
+	return label
+}
+
+// NextTerm returns the first term starting after the day t falls on, or nil
+// if the calendar has none.
+func (c *Calendar) NextTerm(t time.Time) *Term {
+	day := dateOf(t)
+	for i := range c.Years {
+		for j := range c.Years[i].Terms {
+			if term := &c.Years[i].Terms[j]; term.Start.After(day.Time) {
+				return term
+			}
+		}
+	}
+	return nil
+}
+
+// NextClosure returns the first closure starting after the day t falls on,
+// or nil if the calendar has none.
+func (c *Calendar) NextClosure(t time.Time) *Period {
+	day := dateOf(t)
+	for i := range c.Years {
+		for j := range c.Years[i].Closures {
+			if closure := &c.Years[i].Closures[j]; closure.Start.After(day.Time) {
+				return closure
+			}
+		}
+	}
+	return nil
+}
+
+// TermDay reports whether t falls in term, on any day of the week. Days the
+// calendar does not cover count as term days, so an out-of-date calendar
+// never silences the bot.
+func (c *Calendar) TermDay(t time.Time) bool {
+	pos := c.At(t)
+	return !pos.Known() || pos.InTerm()
+}
+
+// TeachingDay reports whether t falls on a weekday in term.
+func (c *Calendar) TeachingDay(t time.Time) bool {
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		return false
+	}
+	return c.TermDay(t)
+}
+
+// NextTeachingDay returns t, or the same time of day on the first teaching
+// day after it. It gives up and returns t if there is none within a year.
+func (c *Calendar) NextTeachingDay(t time.Time) time.Time {
+	return nextDay(t, c.TeachingDay)
+}
+
+// NextTermDay is NextTeachingDay counting weekends in term as well.
+func (c *Calendar) NextTermDay(t time.Time) time.Time {
+	return nextDay(t, c.TermDay)
+}
+
+func nextDay(t time.Time, ok func(time.Time) bool) time.Time {
+	for next, i := t, 0; i < 366; next, i = next.AddDate(0, 0, 1), i+1 {
+		if ok(next) {
+			return next
+		}
+	}
+	return t
+}
+
+// TeachingWeek reports whether any weekday of the week starting on monday
+// is a teaching day.
+func (c *Calendar) TeachingWeek(monday time.Time) bool {
+	for i := 0; i < 5; i++ {
+		if c.TeachingDay(monday.AddDate(0, 0, i)) {
+			return true
+		}
+	}
+	return false
+}
+
+// WeekLabel labels the week starting on monday by the term it falls in,
+// such as "Term 1 Week 5", or returns "" outside term.
+func (c *Calendar) WeekLabel(monday time.Time) string {
+	for i := 0; i < 5; i++ {
+		if label := c.At(monday.AddDate(0, 0, i)).TermWeek(); label != "" {
+			return label
+		}
+	}
+	return ""
+}
@@ -0,0 +1,47 @@
+package academic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUCLWeekAgreesWithTermWeek(t *testing.T) {
+	cal := mustParse(builtIn)
+	for _, year := range cal.Years {
+		for _, term := range year.Terms {
+			for day := term.Start.Time; !day.After(term.End.Time); day = day.AddDate(0, 0, 1) {
+				pos := cal.At(day)
+				first := cal.UCLWeek(term.Start.Time)
+				if got, want := cal.UCLWeek(day), first+pos.Week-1; got != want {
+					t.Errorf("%s: UCLWeek = %d, want %d for %s", day.Format(time.DateOnly), got, want, pos.TermWeek())
+				}
+			}
+		}
+		if got := cal.UCLWeek(year.Terms[0].Start.Time); got != term1UCLWeek {
+			t.Errorf("%s term 1 starts in UCL week %d, want %d", year.Name, got, term1UCLWeek)
+		}
+	}
+}
+
+func TestUCLWeek(t *testing.T) {
+	cal := mustParse(builtIn)
+	tests := []struct {
+		day  time.Time
+		want int
+	}{
+		{time.Date(2025, time.September, 29, 0, 0, 0, 0, time.UTC), 6},
+		{time.Date(2025, time.December, 12, 0, 0, 0, 0, time.UTC), 16},
+		{time.Date(2026, time.January, 12, 0, 0, 0, 0, time.UTC), 21},
+		// Before week 1, days count in the previous academic year.
+		{time.Date(2026, time.August, 3, 0, 0, 0, 0, time.UTC), 50},
+		{time.Date(2026, time.August, 24, 0, 0, 0, 0, time.UTC), 1},
+		// Beyond the calendar, term 1 starts on the last Monday of September.
+		{time.Date(2030, time.October, 7, 0, 0, 0, 0, time.UTC), 7},
+		{time.Date(2031, time.January, 6, 0, 0, 0, 0, time.UTC), 20},
+	}
+	for _, tt := range tests {
+		if got := cal.UCLWeek(tt.day); got != tt.want {
+			t.Errorf("UCLWeek(%s) = %d, want %d", tt.day.Format(time.DateOnly), got, tt.want)
+		}
+	}
+}
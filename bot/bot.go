@@ -2,78 +2,220 @@ package bot
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
+	"strings"
 
+	"github.com/artem-streltsov/ucl-timetable-bot/config"
 	"github.com/artem-streltsov/ucl-timetable-bot/database"
+	"github.com/artem-streltsov/ucl-timetable-bot/discord"
+	"github.com/artem-streltsov/ucl-timetable-bot/email"
+	"github.com/artem-streltsov/ucl-timetable-bot/frontend"
 	"github.com/artem-streltsov/ucl-timetable-bot/handlers"
+	"github.com/artem-streltsov/ucl-timetable-bot/logging"
+	"github.com/artem-streltsov/ucl-timetable-bot/matrix"
+	"github.com/artem-streltsov/ucl-timetable-bot/messenger"
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
 	"github.com/artem-streltsov/ucl-timetable-bot/scheduler"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
+// apiLogger routes tgbotapi's internal logging through slog.
+type apiLogger struct{}
+
+func (apiLogger) Println(v ...interface{}) {
+	slog.Warn(strings.TrimSpace(fmt.Sprintln(v...)), "component", "tgbotapi")
+}
+
+func (apiLogger) Printf(format string, v ...interface{}) {
+	slog.Warn(strings.TrimSpace(fmt.Sprintf(format, v...)), "component", "tgbotapi")
+}
+
+// frontEnd is a chat platform other than Telegram that feeds updates to a
+// dispatcher.
+type frontEnd interface {
+	Run(ctx context.Context, dispatcher *frontend.Dispatcher) error
+}
+
 type Bot struct {
 	api       *tgbotapi.BotAPI
-	db        *database.DB
+	frontEnds map[string]frontEnd
+	db        database.Store
 	handler   *handlers.Handler
 	updates   tgbotapi.UpdatesChannel
+	webhook   *webhook
 	scheduler *scheduler.Scheduler
+	limiter   *rateLimiter
+	cfg       *config.Config
 }
 
-func NewBot(token string, db *database.DB) (*Bot, error) {
-	api, err := tgbotapi.NewBotAPI(token)
+func NewBot(ctx context.Context, cfg *config.Config, db database.Store) (*Bot, error) {
+	apiURL := cfg.TelegramAPIURL
+	if apiURL == "" {
+		apiURL = tgbotapi.APIEndpoint
+	}
+	if err := tgbotapi.SetLogger(apiLogger{}); err != nil {
+		return nil, err
+	}
+	api, err := tgbotapi.NewBotAPIWithAPIEndpoint(cfg.TelegramBotToken, apiURL)
 	if err != nil {
 		return nil, err
 	}
 
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
+	var mailer email.Sender
+	if cfg.SMTPHost != "" {
+		mailer = email.NewSMTP(email.Config{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+			// Port 465 is SMTP over TLS from the start; others use STARTTLS.
+			ImplicitTLS: cfg.SMTPPort == 465,
+		})
+	}
 
-	updates := api.GetUpdatesChan(u)
+	router := messenger.NewRouter(messenger.NewTelegram(api), db.GetAccounts)
+	frontEnds := make(map[string]frontEnd)
+	if cfg.DiscordBotToken != "" {
+		d, err := discord.New(cfg.DiscordBotToken)
+		if err != nil {
+			return nil, err
+		}
+		router.Register(models.PlatformDiscord, d)
+		frontEnds[models.PlatformDiscord] = d
+	}
+	if cfg.MatrixHomeserverURL != "" {
+		mx := matrix.New(cfg.MatrixHomeserverURL, cfg.MatrixAccessToken)
+		router.Register(models.PlatformMatrix, mx)
+		frontEnds[models.PlatformMatrix] = mx
+	}
+
+	var updates tgbotapi.UpdatesChannel
+	var wh *webhook
+	if cfg.BotMode == config.ModeWebhook {
+		wh, err = newWebhook(api, cfg.WebhookURL, cfg.WebhookListenAddr, cfg.WebhookSecretToken, cfg.WebhookCertFile, cfg.WebhookKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := wh.start(); err != nil {
+			return nil, err
+		}
+		updates = wh.updates
+	} else {
+		u := tgbotapi.NewUpdate(0)
+		u.Timeout = 60
 
-	scheduler := scheduler.NewScheduler(api, db)
-	scheduler.ScheduleAll()
+		updates = api.GetUpdatesChan(u)
+	}
 
-	handler := handlers.NewHandler(api, db, scheduler)
+	scheduler := scheduler.NewScheduler(router, mailer, db)
+	scheduler.ScheduleAll(ctx)
+
+	handler := handlers.NewHandler(router, mailer, db, scheduler, cfg)
 
 	return &Bot{
 		api:       api,
+		frontEnds: frontEnds,
 		db:        db,
 		handler:   handler,
 		updates:   updates,
+		webhook:   wh,
 		scheduler: scheduler,
+		limiter:   newRateLimiter(cfg.RateLimitPerMinute, cfg.RateLimitBurst),
+		cfg:       cfg,
 	}, nil
 }
 
 func (b *Bot) Run(ctx context.Context) error {
-	log.Println("Bot started")
+	slog.Info("bot started", "username", b.api.Self.UserName)
+	for platform, fe := range b.frontEnds {
+		dispatcher := frontend.NewDispatcher(platform, b.handler, b.db, b.allow)
+		go func(platform string, fe frontEnd) {
+			if err := fe.Run(ctx, dispatcher); err != nil {
+				slog.Error("front-end stopped", "platform", platform, "error", err)
+			}
+		}(platform, fe)
+	}
 	for {
 		select {
 		case update, ok := <-b.updates:
 			if !ok {
 				return nil
 			}
-			if cb := update.CallbackQuery; cb != nil {
-				b.handler.HandleCallbackQuery(cb)
-			}
-			if msg := update.Message; msg != nil {
-				username := msg.From.UserName
-				if msg.IsCommand() {
-					cmd := msg.Command()
-					b.handler.HandleCommand(msg.Chat.ID, cmd, username)
-				} else {
-					b.handler.HandleMessage(msg.Chat.ID, msg.Text, username)
-				}
-			}
+			b.handleUpdate(ctx, update)
 		case <-ctx.Done():
-			log.Println("Context canceled, stopping bot")
-			b.api.StopReceivingUpdates()
+			slog.Info("context canceled, stopping bot")
+			b.stopReceiving()
 			b.scheduler.StopAll()
 			return nil
 		}
 	}
 }
 
+func (b *Bot) handleUpdate(ctx context.Context, update tgbotapi.Update) {
+	if update.CallbackQuery != nil && update.CallbackQuery.Message == nil {
+		return
+	}
+	chat := update.FromChat()
+	if chat == nil {
+		return
+	}
+	ctx = logging.With(logging.WithCorrelationID(ctx, chat.ID), "update_id", update.UpdateID)
+
+	cb, isCallback := messenger.CallbackFromTelegram(update.CallbackQuery)
+	if ok, warn := b.allow(chat.ID); !ok {
+		logging.FromContext(ctx).Warn("rate limit exceeded, dropping update")
+		if isCallback {
+			b.handler.RateLimited(ctx, chat.ID, &cb, warn)
+		} else if update.Message != nil {
+			b.handler.RateLimited(ctx, chat.ID, nil, warn)
+		}
+		return
+	}
+
+	if isCallback {
+		b.handler.HandleCallback(ctx, cb)
+	}
+	if msg := update.Message; msg != nil {
+		username := msg.From.UserName
+		if msg.IsCommand() {
+			b.handler.HandleCommand(ctx, msg.Chat.ID, msg.Command(), msg.CommandArguments(), username)
+		} else {
+			b.handler.HandleMessage(ctx, msg.Chat.ID, msg.Text, username)
+		}
+	}
+}
+
+// allow applies the per-chat rate limit, which admins are exempt from. warn
+// reports whether the chat should be told its update was dropped.
+func (b *Bot) allow(chatID int64) (ok, warn bool) {
+	if b.cfg.IsAdmin(chatID) {
+		return true, false
+	}
+	return b.limiter.allow(chatID)
+}
+
+// Ping checks that the Telegram Bot API is reachable with our token.
+func (b *Bot) Ping(ctx context.Context) error {
+	_, err := b.api.GetMe()
+	return err
+}
+
+func (b *Bot) ActiveTimers() int {
+	return b.scheduler.ActiveTimers()
+}
+
 func (b *Bot) Stop() {
-	b.api.StopReceivingUpdates()
+	b.stopReceiving()
 	b.scheduler.StopAll()
 }
+
+func (b *Bot) stopReceiving() {
+	if b.webhook != nil {
+		b.webhook.shutdown()
+		return
+	}
+	b.api.StopReceivingUpdates()
+}
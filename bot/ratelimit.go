@@ -0,0 +1,62 @@
+package bot
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter is a per-chat token bucket. Each chat may send burst updates at
+// once, refilled at perMinute tokens a minute.
+type rateLimiter struct {
+	perMinute int
+	burst     int
+	buckets   map[int64]*bucket
+	mu        sync.Mutex
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// warned is set once the chat has been told it is being limited, and
+	// cleared when an update is allowed again.
+	warned bool
+}
+
+func newRateLimiter(perMinute, burst int) *rateLimiter {
+	return &rateLimiter{
+		perMinute: perMinute,
+		burst:     burst,
+		buckets:   make(map[int64]*bucket),
+	}
+}
+
+// allow takes a token for chatID. When there is none, warn reports whether
+// this is the first update dropped since the chat was last allowed one.
+func (l *rateLimiter) allow(chatID int64) (ok, warn bool) {
+	if l.perMinute <= 0 {
+		return true, false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	b, ok := l.buckets[chatID]
+	if !ok {
+		b = &bucket{tokens: float64(l.burst), last: now}
+		l.buckets[chatID] = b
+	}
+
+	refill := now.Sub(b.last).Minutes() * float64(l.perMinute)
+	b.tokens = min(float64(l.burst), b.tokens+refill)
+	b.last = now
+
+	if b.tokens < 1 {
+		warn = !b.warned
+		b.warned = true
+		return false, warn
+	}
+	b.tokens--
+	b.warned = false
+	return true, false
+}
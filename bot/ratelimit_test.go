@@ -0,0 +1,39 @@
+package bot
+
+import "testing"
+
+func TestRateLimiterWarnsOncePerRun(t *testing.T) {
+	l := newRateLimiter(1, 2)
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.allow(1); !ok {
+			t.Fatalf("update %d within the burst was dropped", i+1)
+		}
+	}
+	if ok, warn := l.allow(1); ok || !warn {
+		t.Fatalf("first update over the burst = (%v, %v), want (false, true)", ok, warn)
+	}
+	if ok, warn := l.allow(1); ok || warn {
+		t.Fatalf("second update over the burst = (%v, %v), want (false, false)", ok, warn)
+	}
+	if ok, _ := l.allow(2); !ok {
+		t.Fatal("another chat was limited")
+	}
+
+	l.buckets[1].tokens = 1
+	if ok, _ := l.allow(1); !ok {
+		t.Fatal("update after refilling was dropped")
+	}
+	if ok, warn := l.allow(1); ok || !warn {
+		t.Fatalf("update over the burst after a refill = (%v, %v), want (false, true)", ok, warn)
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	l := newRateLimiter(0, 0)
+	for i := 0; i < 100; i++ {
+		if ok, _ := l.allow(1); !ok {
+			t.Fatal("update dropped with rate limiting disabled")
+		}
+	}
+}
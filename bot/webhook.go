@@ -0,0 +1,135 @@
+package bot
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+type webhook struct {
+	api      *tgbotapi.BotAPI
+	server   *http.Server
+	listener net.Listener
+	updates  chan tgbotapi.Update
+	url      string
+	path     string
+	secret   string
+}
+
+// newWebhook binds listenAddr straight away, so a busy port or a bad
+// certificate fails startup instead of leaving Telegram pointed at nothing.
+// Only POST requests to the path of webhookURL are accepted.
+func newWebhook(api *tgbotapi.BotAPI, webhookURL, listenAddr, secret, certFile, keyFile string) (*webhook, error) {
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook URL: %v", err)
+	}
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+
+	var tlsConfig *tls.Config
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load webhook certificate: %v", err)
+		}
+		tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+
+	listener, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		return nil, fmt.Errorf("could not listen for webhook requests: %v", err)
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	wh := &webhook{
+		api:      api,
+		listener: listener,
+		updates:  make(chan tgbotapi.Update, api.Buffer),
+		url:      u.String(),
+		path:     path,
+		secret:   secret,
+	}
+	wh.server = &http.Server{
+		Handler:           wh,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return wh, nil
+}
+
+// start serves requests on the bound listener and then tells Telegram where
+// to deliver updates. setWebhook is called directly because
+// tgbotapi.WebhookConfig has no secret_token field.
+func (wh *webhook) start() error {
+	go wh.serve()
+
+	params := tgbotapi.Params{"url": wh.url}
+	params.AddNonEmpty("secret_token", wh.secret)
+	if _, err := wh.api.MakeRequest("setWebhook", params); err != nil {
+		wh.shutdown()
+		return fmt.Errorf("could not set webhook: %v", err)
+	}
+	return nil
+}
+
+func (wh *webhook) serve() {
+	if err := wh.server.Serve(wh.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("webhook server stopped", "error", err)
+	}
+}
+
+func (wh *webhook) shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := wh.server.Shutdown(ctx); err != nil {
+		slog.Error("failed to shut down webhook server", "error", err)
+	}
+}
+
+func (wh *webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != wh.path {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := r.Header.Get(secretTokenHeader)
+	if wh.secret != "" && subtle.ConstantTimeCompare([]byte(token), []byte(wh.secret)) != 1 {
+		slog.Warn("rejected webhook request with invalid secret token", "remote_addr", r.RemoteAddr)
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	update, err := wh.api.HandleUpdate(r)
+	if err != nil {
+		slog.Warn("rejected malformed webhook request", "remote_addr", r.RemoteAddr, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	select {
+	case wh.updates <- *update:
+		w.WriteHeader(http.StatusOK)
+	case <-r.Context().Done():
+		http.Error(w, "request canceled", http.StatusServiceUnavailable)
+	}
+}
@@ -0,0 +1,137 @@
+package bot
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const testSecret = "s3cret"
+
+// fakeTelegram answers getMe and setWebhook. When setWebhook arrives it
+// posts an update to the webhook, which must already be listening.
+func fakeTelegram(t *testing.T, webhookAddr *string) (*httptest.Server, <-chan int) {
+	t.Helper()
+	delivered := make(chan int, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/getMe"):
+			w.Write([]byte(`{"ok":true,"result":{"id":1,"is_bot":true,"first_name":"bot","username":"bot"}}`))
+		case strings.HasSuffix(r.URL.Path, "/setWebhook"):
+			if got := r.FormValue("secret_token"); got != testSecret {
+				t.Errorf("setWebhook secret_token = %q, want %q", got, testSecret)
+			}
+			req, _ := http.NewRequest(http.MethodPost, "http://"+*webhookAddr+"/hook", strings.NewReader(`{"update_id":7}`))
+			req.Header.Set(secretTokenHeader, testSecret)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Errorf("webhook not listening when setWebhook was called: %v", err)
+				delivered <- 0
+			} else {
+				resp.Body.Close()
+				delivered <- resp.StatusCode
+			}
+			w.Write([]byte(`{"ok":true,"result":true}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, delivered
+}
+
+func newTestAPI(t *testing.T, server *httptest.Server) *tgbotapi.BotAPI {
+	t.Helper()
+	api, err := tgbotapi.NewBotAPIWithAPIEndpoint("token", server.URL+"/bot%s/%s")
+	if err != nil {
+		t.Fatalf("NewBotAPIWithAPIEndpoint: %v", err)
+	}
+	return api
+}
+
+func TestWebhookListensBeforeRegistering(t *testing.T) {
+	var addr string
+	server, delivered := fakeTelegram(t, &addr)
+	api := newTestAPI(t, server)
+
+	wh, err := newWebhook(api, "https://example.com/hook", "127.0.0.1:0", testSecret, "", "")
+	if err != nil {
+		t.Fatalf("newWebhook: %v", err)
+	}
+	defer wh.shutdown()
+	addr = wh.listener.Addr().String()
+
+	if err := wh.start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if status := <-delivered; status != http.StatusOK {
+		t.Fatalf("update during setWebhook got status %d, want 200", status)
+	}
+	if update := <-wh.updates; update.UpdateID != 7 {
+		t.Errorf("UpdateID = %d, want 7", update.UpdateID)
+	}
+}
+
+func TestWebhookBindError(t *testing.T) {
+	var addr string
+	server, _ := fakeTelegram(t, &addr)
+	api := newTestAPI(t, server)
+
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+
+	if _, err := newWebhook(api, "https://example.com/hook", taken.Addr().String(), "", "", ""); err == nil {
+		t.Fatal("newWebhook on a port in use succeeded, want an error")
+	}
+}
+
+func TestWebhookServeHTTP(t *testing.T) {
+	var addr string
+	server, _ := fakeTelegram(t, &addr)
+	api := newTestAPI(t, server)
+
+	wh, err := newWebhook(api, "https://example.com/hook", "127.0.0.1:0", testSecret, "", "")
+	if err != nil {
+		t.Fatalf("newWebhook: %v", err)
+	}
+	defer wh.listener.Close()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		secret string
+		want   int
+	}{
+		{"update", http.MethodPost, "/hook", testSecret, http.StatusOK},
+		{"wrong path", http.MethodPost, "/other", testSecret, http.StatusNotFound},
+		{"root path", http.MethodPost, "/", testSecret, http.StatusNotFound},
+		{"GET", http.MethodGet, "/hook", testSecret, http.StatusMethodNotAllowed},
+		{"PUT", http.MethodPut, "/hook", testSecret, http.StatusMethodNotAllowed},
+		{"wrong secret", http.MethodPost, "/hook", "nope", http.StatusForbidden},
+		{"no secret", http.MethodPost, "/hook", "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(`{"update_id":1}`))
+			if tt.secret != "" {
+				req.Header.Set(secretTokenHeader, tt.secret)
+			}
+			rec := httptest.NewRecorder()
+			wh.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if rec.Code == http.StatusOK {
+				<-wh.updates
+			}
+		})
+	}
+}
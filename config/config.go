@@ -1,36 +1,360 @@
 package config
 
 import (
+	"bytes"
 	"errors"
-	"log"
+	"flag"
+	"fmt"
+	"io/fs"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/utils"
 
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ModePolling = "polling"
+	ModeWebhook = "webhook"
 )
 
 type Config struct {
-	TelegramBotToken string
-	DBPath           string
+	TelegramBotToken   string `yaml:"telegram_bot_token"`
+	DBDriver           string `yaml:"db_driver"`
+	DBPath             string `yaml:"db_path"`
+	DatabaseURL        string `yaml:"database_url"`
+	MigrationsPath     string `yaml:"migrations_path"`
+	TelegramAPIURL     string `yaml:"telegram_api_url"`
+	BotMode            string `yaml:"bot_mode"`
+	WebhookURL         string `yaml:"webhook_url"`
+	WebhookListenAddr  string `yaml:"webhook_listen_addr"`
+	WebhookSecretToken string `yaml:"webhook_secret_token"`
+	WebhookCertFile    string `yaml:"webhook_cert_file"`
+	WebhookKeyFile     string `yaml:"webhook_key_file"`
+	HTTPListenAddr     string `yaml:"http_listen_addr"`
+	LogLevel           string `yaml:"log_level"`
+	LogFormat          string `yaml:"log_format"`
+	// PublicURL is where the HTTP server can be reached from outside, used
+	// in calendar feed links. Feeds are disabled while it is empty.
+	PublicURL string `yaml:"public_url"`
+
+	Timezone              string `yaml:"timezone"`
+	DefaultDailyTime      string `yaml:"default_daily_time"`
+	DefaultWeeklyTime     string `yaml:"default_weekly_time"`
+	DefaultReminderOffset string `yaml:"default_reminder_offset"`
+	// AcademicCalendarPath replaces the built-in academic calendar when set.
+	AcademicCalendarPath string `yaml:"academic_calendar_path"`
+
+	// CacheTTL is how long a fetched calendar is reused before fetching it
+	// again. Zero disables caching.
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+	// RateLimitPerMinute caps the updates handled per chat. Zero disables
+	// rate limiting.
+	RateLimitPerMinute int     `yaml:"rate_limit_per_minute"`
+	RateLimitBurst     int     `yaml:"rate_limit_burst"`
+	AdminIDs           []int64 `yaml:"admin_ids"`
+
+	// SMTPHost enables email delivery when set.
+	SMTPHost     string `yaml:"smtp_host"`
+	SMTPPort     int    `yaml:"smtp_port"`
+	SMTPUsername string `yaml:"smtp_username"`
+	SMTPPassword string `yaml:"smtp_password"`
+	SMTPFrom     string `yaml:"smtp_from"`
+
+	// DiscordBotToken enables the Discord front-end when set.
+	DiscordBotToken string `yaml:"discord_bot_token"`
+	// MatrixHomeserverURL enables the Matrix front-end when set.
+	MatrixHomeserverURL string `yaml:"matrix_homeserver_url"`
+	MatrixAccessToken   string `yaml:"matrix_access_token"`
 }
 
-func Load() (*Config, error) {
-	if err := godotenv.Load(); err != nil {
-		log.Fatalf("Error loading .env file: %v", err)
+func defaults() *Config {
+	return &Config{
+		DBDriver:              "sqlite3",
+		BotMode:               ModePolling,
+		WebhookListenAddr:     ":8443",
+		LogLevel:              "info",
+		LogFormat:             "text",
+		Timezone:              "Europe/London",
+		DefaultDailyTime:      "07:00",
+		DefaultWeeklyTime:     "SUN 18:00",
+		DefaultReminderOffset: "15",
+		CacheTTL:              5 * time.Minute,
+		RateLimitPerMinute:    30,
+		RateLimitBurst:        10,
+		SMTPPort:              587,
 	}
+}
+
+// option is a setting that can be overridden from the environment and from
+// the command line.
+type option struct {
+	env   string
+	flag  string
+	usage string
+	set   func(c *Config, value string) error
+}
+
+func stringOption(env, flag, usage string, field func(c *Config) *string) option {
+	return option{env, flag, usage, func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}}
+}
+
+func intOption(env, flag, usage string, field func(c *Config) *int) option {
+	return option{env, flag, usage, func(c *Config, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		*field(c) = n
+		return nil
+	}}
+}
 
-	token := os.Getenv("TELEGRAM_BOT_TOKEN")
-	dbPath := os.Getenv("DB_PATH")
+var options = []option{
+	stringOption("TELEGRAM_BOT_TOKEN", "", "", func(c *Config) *string { return &c.TelegramBotToken }),
+	stringOption("DB_DRIVER", "db-driver", "database driver: sqlite3 or postgres", func(c *Config) *string { return &c.DBDriver }),
+	stringOption("DB_PATH", "db-path", "path to the SQLite database", func(c *Config) *string { return &c.DBPath }),
+	stringOption("DATABASE_URL", "", "", func(c *Config) *string { return &c.DatabaseURL }),
+	stringOption("MIGRATIONS_PATH", "migrations-path", "directory of migrations to use instead of the embedded ones", func(c *Config) *string { return &c.MigrationsPath }),
+	stringOption("TELEGRAM_API_URL", "telegram-api-url", "Bot API endpoint format, e.g. http://localhost:8081/bot%s/%s", func(c *Config) *string { return &c.TelegramAPIURL }),
+	stringOption("BOT_MODE", "mode", "update mode: polling or webhook", func(c *Config) *string { return &c.BotMode }),
+	stringOption("WEBHOOK_URL", "webhook-url", "public URL Telegram delivers updates to", func(c *Config) *string { return &c.WebhookURL }),
+	stringOption("WEBHOOK_LISTEN_ADDR", "webhook-listen-addr", "address for the webhook server", func(c *Config) *string { return &c.WebhookListenAddr }),
+	stringOption("WEBHOOK_SECRET_TOKEN", "", "", func(c *Config) *string { return &c.WebhookSecretToken }),
+	stringOption("WEBHOOK_CERT_FILE", "webhook-cert-file", "TLS certificate for the webhook server", func(c *Config) *string { return &c.WebhookCertFile }),
+	stringOption("WEBHOOK_KEY_FILE", "webhook-key-file", "TLS key for the webhook server", func(c *Config) *string { return &c.WebhookKeyFile }),
+	stringOption("HTTP_LISTEN_ADDR", "http-listen-addr", "address for the health and metrics server", func(c *Config) *string { return &c.HTTPListenAddr }),
+	stringOption("PUBLIC_URL", "public-url", "public base URL of the HTTP server, used in calendar feed links", func(c *Config) *string { return &c.PublicURL }),
+	stringOption("LOG_LEVEL", "log-level", "debug, info, warn or error", func(c *Config) *string { return &c.LogLevel }),
+	stringOption("LOG_FORMAT", "log-format", "text or json", func(c *Config) *string { return &c.LogFormat }),
+	stringOption("TIMEZONE", "timezone", "timezone used for all schedules", func(c *Config) *string { return &c.Timezone }),
+	stringOption("DEFAULT_DAILY_TIME", "default-daily-time", "daily summary time for new users", func(c *Config) *string { return &c.DefaultDailyTime }),
+	stringOption("DEFAULT_WEEKLY_TIME", "default-weekly-time", "weekly summary day and time for new users", func(c *Config) *string { return &c.DefaultWeeklyTime }),
+	stringOption("DEFAULT_REMINDER_OFFSET", "default-reminder-offset", "lecture reminder offset in minutes for new users", func(c *Config) *string { return &c.DefaultReminderOffset }),
+	stringOption("ACADEMIC_CALENDAR_PATH", "academic-calendar", "YAML academic calendar to use instead of the built-in one", func(c *Config) *string { return &c.AcademicCalendarPath }),
+	{"CACHE_TTL", "cache-ttl", "how long fetched calendars are cached", func(c *Config, value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		c.CacheTTL = d
+		return nil
+	}},
+	intOption("RATE_LIMIT_PER_MINUTE", "rate-limit-per-minute", "updates handled per chat per minute", func(c *Config) *int { return &c.RateLimitPerMinute }),
+	intOption("RATE_LIMIT_BURST", "rate-limit-burst", "updates a chat may send in a burst", func(c *Config) *int { return &c.RateLimitBurst }),
+	{"ADMIN_IDS", "admin-ids", "comma-separated chat IDs of bot administrators", func(c *Config, value string) error {
+		ids, err := parseIDs(value)
+		c.AdminIDs = ids
+		return err
+	}},
+	stringOption("SMTP_HOST", "smtp-host", "SMTP server for email delivery; empty disables email", func(c *Config) *string { return &c.SMTPHost }),
+	intOption("SMTP_PORT", "smtp-port", "SMTP server port", func(c *Config) *int { return &c.SMTPPort }),
+	stringOption("SMTP_USERNAME", "smtp-username", "SMTP username", func(c *Config) *string { return &c.SMTPUsername }),
+	stringOption("SMTP_PASSWORD", "", "", func(c *Config) *string { return &c.SMTPPassword }),
+	stringOption("SMTP_FROM", "smtp-from", "sender address for emails", func(c *Config) *string { return &c.SMTPFrom }),
+	stringOption("DISCORD_BOT_TOKEN", "", "", func(c *Config) *string { return &c.DiscordBotToken }),
+	stringOption("MATRIX_HOMESERVER_URL", "matrix-homeserver-url", "Matrix homeserver for the Matrix front-end; empty disables it", func(c *Config) *string { return &c.MatrixHomeserverURL }),
+	stringOption("MATRIX_ACCESS_TOKEN", "", "", func(c *Config) *string { return &c.MatrixAccessToken }),
+}
 
-	if token == "" {
-		return nil, errors.New("TELEGRAM_BOT_TOKEN not set")
+// Load builds the configuration from, in increasing order of precedence,
+// built-in defaults, a YAML file, the environment (including an optional
+// .env file) and command-line flags.
+func Load(args []string) (*Config, error) {
+	cfg, _, err := load(args, true)
+	return cfg, err
+}
+
+// LoadMigrate loads the configuration for the migrate subcommand, which does
+// not need a bot token. It also returns the arguments left after the flags.
+func LoadMigrate(args []string) (*Config, []string, error) {
+	return load(args, false)
+}
+
+func load(args []string, requireToken bool) (*Config, []string, error) {
+	fset := flag.NewFlagSet("ucl-timetable-bot", flag.ContinueOnError)
+	configFile := fset.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML configuration file")
+	flagValues := make(map[string]*string)
+	for _, opt := range options {
+		if opt.flag != "" {
+			flagValues[opt.flag] = fset.String(opt.flag, "", opt.usage)
+		}
+	}
+	if err := fset.Parse(args); err != nil {
+		return nil, nil, err
 	}
 
-	if dbPath == "" {
-		return nil, errors.New("DB_PATH not set")
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, fmt.Errorf("error loading .env file: %v", err)
 	}
 
-	return &Config{
-		TelegramBotToken: token,
-		DBPath:           dbPath,
-	}, nil
+	cfg := defaults()
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var errs []error
+	for _, opt := range options {
+		if value, ok := os.LookupEnv(opt.env); ok && value != "" {
+			if err := opt.set(cfg, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %v", opt.env, err))
+			}
+		}
+	}
+	fset.Visit(func(f *flag.Flag) {
+		for _, opt := range options {
+			if opt.flag == f.Name {
+				if err := opt.set(cfg, *flagValues[f.Name]); err != nil {
+					errs = append(errs, fmt.Errorf("-%s: %v", f.Name, err))
+				}
+			}
+		}
+	})
+
+	errs = append(errs, cfg.validate(requireToken)...)
+	if len(errs) > 0 {
+		return nil, nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return cfg, fset.Args(), nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not read config file: %v", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("could not parse config file %s: %v", path, err)
+	}
+	return nil
+}
+
+func (c *Config) validate(requireToken bool) []error {
+	var errs []error
+	if requireToken && c.TelegramBotToken == "" {
+		errs = append(errs, errors.New("TELEGRAM_BOT_TOKEN not set"))
+	}
+	switch c.DBDriver {
+	case "sqlite3":
+		if c.DBPath == "" {
+			errs = append(errs, errors.New("DB_PATH not set"))
+		}
+	case "postgres":
+		if c.DatabaseURL == "" {
+			errs = append(errs, errors.New("DATABASE_URL not set"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("DB_DRIVER must be sqlite3 or postgres, got %q", c.DBDriver))
+	}
+
+	switch c.BotMode {
+	case ModePolling:
+	case ModeWebhook:
+		if c.WebhookURL == "" {
+			errs = append(errs, errors.New("WEBHOOK_URL not set"))
+		}
+		if c.WebhookListenAddr == "" {
+			errs = append(errs, errors.New("WEBHOOK_LISTEN_ADDR not set"))
+		}
+		if (c.WebhookCertFile == "") != (c.WebhookKeyFile == "") {
+			errs = append(errs, errors.New("WEBHOOK_CERT_FILE and WEBHOOK_KEY_FILE must be set together"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("BOT_MODE must be polling or webhook, got %q", c.BotMode))
+	}
+
+	if c.PublicURL != "" && c.HTTPListenAddr == "" {
+		errs = append(errs, errors.New("HTTP_LISTEN_ADDR must be set when PUBLIC_URL is set"))
+	}
+
+	switch strings.ToLower(c.LogLevel) {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("LOG_LEVEL must be debug, info, warn or error, got %q", c.LogLevel))
+	}
+	switch strings.ToLower(c.LogFormat) {
+	case "text", "json":
+	default:
+		errs = append(errs, fmt.Errorf("LOG_FORMAT must be text or json, got %q", c.LogFormat))
+	}
+
+	if _, err := time.LoadLocation(c.Timezone); err != nil {
+		errs = append(errs, fmt.Errorf("TIMEZONE: %v", err))
+	}
+	if !utils.IsValidTime(c.DefaultDailyTime) {
+		errs = append(errs, fmt.Errorf("DEFAULT_DAILY_TIME must be HH:MM, got %q", c.DefaultDailyTime))
+	}
+	if parts := strings.SplitN(c.DefaultWeeklyTime, " ", 2); len(parts) != 2 || !utils.IsValidDay(parts[0]) || !utils.IsValidTime(parts[1]) {
+		errs = append(errs, fmt.Errorf("DEFAULT_WEEKLY_TIME must be DAY HH:MM, got %q", c.DefaultWeeklyTime))
+	}
+	if !utils.IsValidOffset(c.DefaultReminderOffset) {
+		errs = append(errs, fmt.Errorf("DEFAULT_REMINDER_OFFSET must be 1-%d minutes, got %q", utils.MaxReminderOffset, c.DefaultReminderOffset))
+	}
+
+	if c.CacheTTL < 0 {
+		errs = append(errs, errors.New("CACHE_TTL must not be negative"))
+	}
+	if c.RateLimitPerMinute < 0 {
+		errs = append(errs, errors.New("RATE_LIMIT_PER_MINUTE must not be negative"))
+	}
+	if c.RateLimitPerMinute > 0 && c.RateLimitBurst < 1 {
+		errs = append(errs, errors.New("RATE_LIMIT_BURST must be at least 1 when rate limiting is enabled"))
+	}
+	if c.SMTPHost != "" {
+		if c.SMTPFrom == "" {
+			errs = append(errs, errors.New("SMTP_FROM must be set when SMTP_HOST is set"))
+		}
+		if c.SMTPPort < 1 || c.SMTPPort > 65535 {
+			errs = append(errs, fmt.Errorf("SMTP_PORT must be between 1 and 65535, got %d", c.SMTPPort))
+		}
+	}
+	if c.MatrixHomeserverURL != "" && c.MatrixAccessToken == "" {
+		errs = append(errs, errors.New("MATRIX_ACCESS_TOKEN must be set when MATRIX_HOMESERVER_URL is set"))
+	}
+	return errs
+}
+
+// DSN returns the data source name for the configured database driver.
+func (c *Config) DSN() string {
+	if c.DBDriver == "postgres" {
+		return c.DatabaseURL
+	}
+	return c.DBPath
+}
+
+// IsAdmin reports whether chatID belongs to a bot administrator.
+func (c *Config) IsAdmin(chatID int64) bool {
+	for _, id := range c.AdminIDs {
+		if id == chatID {
+			return true
+		}
+	}
+	return false
+}
+
+func parseIDs(value string) ([]int64, error) {
+	var ids []int64
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chat ID %q", part)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
 }
@@ -1,43 +1,65 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/artem-streltsov/ucl-timetable-bot/models"
 
 	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database/sqlite3"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 )
 
+const (
+	DriverSQLite   = "sqlite3"
+	DriverPostgres = "postgres"
+)
+
+// DB is the SQL implementation of Store. Queries are written with ?
+// placeholders and rewritten for drivers that number them.
 type DB struct {
-	conn *sql.DB
+	conn   *sql.DB
+	driver string
 }
 
-func New(dbPath string) (*DB, error) {
-	dbConn, err := sql.Open("sqlite3", dbPath)
+// Open connects to the database for driver and applies pending migrations.
+func Open(driver, dsn, migrationsPath string) (*DB, error) {
+	switch driver {
+	case DriverSQLite, DriverPostgres:
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", driver)
+	}
+
+	dbConn, err := sql.Open(driver, dsn)
 	if err != nil {
 		return nil, err
 	}
-	if err := runMigrations(dbConn); err != nil {
+	if err := runMigrations(dbConn, driver, migrationsPath); err != nil {
+		dbConn.Close()
 		return nil, err
 	}
-	return &DB{conn: dbConn}, nil
+	return &DB{conn: dbConn, driver: driver}, nil
 }
 
-func runMigrations(db *sql.DB) error {
-	driver, err := sqlite3.WithInstance(db, &sqlite3.Config{})
-	if err != nil {
-		return fmt.Errorf("could not create migration driver: %v", err)
-	}
+// New opens the SQLite database at dbPath.
+func New(dbPath, migrationsPath string) (*DB, error) {
+	return Open(DriverSQLite, dbPath, migrationsPath)
+}
+
+// NewPostgres opens the PostgreSQL database at the connection URL dsn.
+func NewPostgres(dsn, migrationsPath string) (*DB, error) {
+	return Open(DriverPostgres, dsn, migrationsPath)
+}
 
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://migrations",
-		"sqlite3", driver)
+func runMigrations(db *sql.DB, driver, migrationsPath string) error {
+	m, err := newMigrate(db, driver, migrationsPath)
 	if err != nil {
-		return fmt.Errorf("could not create migration instance: %v", err)
+		return err
 	}
 
 	err = m.Up()
@@ -48,45 +70,141 @@ func runMigrations(db *sql.DB) error {
 	return nil
 }
 
+func (db *DB) rebind(query string) string {
+	if db.driver != DriverPostgres {
+		return query
+	}
+	var sb strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			sb.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func (db *DB) exec(query string, args ...any) (sql.Result, error) {
+	return db.conn.Exec(db.rebind(query), args...)
+}
+
+func (db *DB) query(query string, args ...any) (*sql.Rows, error) {
+	return db.conn.Query(db.rebind(query), args...)
+}
+
+func (db *DB) queryRow(query string, args ...any) *sql.Row {
+	return db.conn.QueryRow(db.rebind(query), args...)
+}
+
 func (db *DB) Close() error {
 	return db.conn.Close()
 }
 
-func (db *DB) GetUser(chatID int64) (*models.User, error) {
-	row := db.conn.QueryRow(`SELECT chat_id, username, webcal_url, daily_time, weekly_time, reminder_offset FROM users WHERE chat_id = ?`, chatID)
+func (db *DB) Ping(ctx context.Context) error {
+	return db.conn.PingContext(ctx)
+}
+
+// pausedUntil stores the pause end in UTC, or NULL for an open-ended pause.
+func pausedUntil(user *models.User) any {
+	if user.PausedUntil == nil {
+		return nil
+	}
+	return user.PausedUntil.UTC()
+}
+
+var userColumns = []string{
+	"chat_id", "username", "webcal_url", "daily_time", "weekly_time",
+	"email", "email_verified", "daily_channel", "weekly_channel", "reminder_channel", "feed_token",
+	"moodle_url", "paused", "paused_until",
+	"daily_disabled", "weekly_disabled", "reminders_disabled", "daily_weekends", "daily_skip_empty",
+	"reminders_first_only", "evening_time", "commute_minutes", "leave_disabled",
+}
+
+func userFields(user *models.User) []any {
+	return []any{
+		&user.ChatID, &user.Username, &user.WebCalURL, &user.DailyTime, &user.WeeklyTime,
+		&user.Email, &user.EmailVerified, &user.DailyChannel, &user.WeeklyChannel, &user.ReminderChannel, &user.FeedToken,
+		&user.MoodleURL, &user.Paused, &user.PausedUntil,
+		&user.DailyDisabled, &user.WeeklyDisabled, &user.RemindersDisabled, &user.DailyWeekends, &user.DailySkipEmpty,
+		&user.RemindersFirstOnly, &user.EveningTime, &user.CommuteMinutes, &user.LeaveDisabled,
+	}
+}
+
+func userValues(user *models.User) []any {
+	return []any{
+		user.ChatID, user.Username, user.WebCalURL, user.DailyTime, user.WeeklyTime,
+		user.Email, user.EmailVerified, user.DailyChannel, user.WeeklyChannel, user.ReminderChannel, user.FeedToken,
+		user.MoodleURL, user.Paused, pausedUntil(user),
+		user.DailyDisabled, user.WeeklyDisabled, user.RemindersDisabled, user.DailyWeekends, user.DailySkipEmpty,
+		user.RemindersFirstOnly, user.EveningTime, user.CommuteMinutes, user.LeaveDisabled,
+	}
+}
+
+var (
+	selectUsers = `SELECT ` + strings.Join(userColumns, ", ") + ` FROM users`
+	upsertUser  = buildUpsertUser()
+)
+
+func buildUpsertUser() string {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(userColumns)), ", ")
+	var updates []string
+	for _, column := range userColumns[1:] {
+		updates = append(updates, column+"=excluded."+column)
+	}
+	return `INSERT INTO users (` + strings.Join(userColumns, ", ") + `)
+        VALUES (` + placeholders + `)
+        ON CONFLICT(chat_id) DO UPDATE SET ` + strings.Join(updates, ", ")
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row scanner) (*models.User, error) {
 	var user models.User
-	err := row.Scan(&user.ChatID, &user.Username, &user.WebCalURL, &user.DailyTime, &user.WeeklyTime, &user.ReminderOffset)
+	if err := row.Scan(userFields(&user)...); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (db *DB) GetUser(chatID int64) (*models.User, error) {
+	user, err := scanUser(db.queryRow(selectUsers+` WHERE chat_id = ?`, chatID))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
-	return &user, err
+	return user, err
 }
 
-func (db *DB) GetUserByUsername(username string) (*models.User, error) {
-	row := db.conn.QueryRow(`SELECT chat_id, username, webcal_url, daily_time, weekly_time, reminder_offset FROM users WHERE username = ?`, username)
-	var user models.User
-	err := row.Scan(&user.ChatID, &user.Username, &user.WebCalURL, &user.DailyTime, &user.WeeklyTime, &user.ReminderOffset)
+func (db *DB) GetTelegramUserByUsername(username string) (*models.User, error) {
+	user, err := scanUser(db.queryRow(selectUsers+` WHERE username = ? AND chat_id > ?`, username, int64(models.SyntheticChatIDBase)))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return user, err
+}
+
+func (db *DB) GetUserByFeedToken(token string) (*models.User, error) {
+	if token == "" {
+		return nil, nil
+	}
+	user, err := scanUser(db.queryRow(selectUsers+` WHERE feed_token = ?`, token))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
-	return &user, err
+	return user, err
 }
 
 func (db *DB) SaveUser(user *models.User) error {
-	_, err := db.conn.Exec(`INSERT INTO users (chat_id, username, webcal_url, daily_time, weekly_time, reminder_offset) 
-        VALUES (?, ?, ?, ?, ?, ?) 
-        ON CONFLICT(chat_id) DO UPDATE SET 
-            username=excluded.username, 
-            webcal_url=excluded.webcal_url, 
-            daily_time=excluded.daily_time, 
-            weekly_time=excluded.weekly_time,
-            reminder_offset=excluded.reminder_offset`,
-		user.ChatID, user.Username, user.WebCalURL, user.DailyTime, user.WeeklyTime, user.ReminderOffset)
+	_, err := db.exec(upsertUser, userValues(user)...)
 	return err
 }
 
 func (db *DB) GetAllUsers() ([]*models.User, error) {
-	rows, err := db.conn.Query(`SELECT chat_id, username, webcal_url, daily_time, weekly_time, reminder_offset FROM users`)
+	rows, err := db.query(selectUsers)
 	if err != nil {
 		return nil, err
 	}
@@ -94,21 +212,26 @@ func (db *DB) GetAllUsers() ([]*models.User, error) {
 
 	var users []*models.User
 	for rows.Next() {
-		var user models.User
-		err := rows.Scan(&user.ChatID, &user.Username, &user.WebCalURL, &user.DailyTime, &user.WeeklyTime, &user.ReminderOffset)
+		user, err := scanUser(rows)
 		if err != nil {
 			return nil, err
 		}
-		users = append(users, &user)
+		users = append(users, user)
 	}
-	return users, nil
+	return users, rows.Err()
+}
+
+func (db *DB) CountUsers() (int, error) {
+	var count int
+	err := db.queryRow(`SELECT COUNT(*) FROM users`).Scan(&count)
+	return count, err
 }
 
 func (db *DB) AreFriends(userID1, userID2 int64) (bool, error) {
 	if userID1 > userID2 {
 		userID1, userID2 = userID2, userID1
 	}
-	row := db.conn.QueryRow(`SELECT 1 FROM friends WHERE user_id1 = ? AND user_id2 = ?`, userID1, userID2)
+	row := db.queryRow(`SELECT 1 FROM friends WHERE user_id1 = ? AND user_id2 = ?`, userID1, userID2)
 	var exists int
 	err := row.Scan(&exists)
 	if err == sql.ErrNoRows {
@@ -118,7 +241,7 @@ func (db *DB) AreFriends(userID1, userID2 int64) (bool, error) {
 }
 
 func (db *DB) FriendRequestExists(requestorID, requesteeID int64) (bool, error) {
-	row := db.conn.QueryRow(`SELECT 1 FROM friend_requests WHERE requestor_id = ? AND requestee_id = ?`, requestorID, requesteeID)
+	row := db.queryRow(`SELECT 1 FROM friend_requests WHERE requestor_id = ? AND requestee_id = ?`, requestorID, requesteeID)
 	var exists int
 	err := row.Scan(&exists)
 	if err == sql.ErrNoRows {
@@ -128,7 +251,7 @@ func (db *DB) FriendRequestExists(requestorID, requesteeID int64) (bool, error)
 }
 
 func (db *DB) AddFriendRequest(requestorID, requesteeID int64) error {
-	_, err := db.conn.Exec(`INSERT INTO friend_requests (requestor_id, requestee_id) VALUES (?, ?)`, requestorID, requesteeID)
+	_, err := db.exec(`INSERT INTO friend_requests (requestor_id, requestee_id) VALUES (?, ?)`, requestorID, requesteeID)
 	return err
 }
 
@@ -143,12 +266,12 @@ func (db *DB) AcceptFriendRequest(requestorID, requesteeID int64) error {
 	if user1 > user2 {
 		user1, user2 = user2, user1
 	}
-	_, err = tx.Exec(`INSERT INTO friends (user_id1, user_id2) VALUES (?, ?)`, user1, user2)
+	_, err = tx.Exec(db.rebind(`INSERT INTO friends (user_id1, user_id2) VALUES (?, ?)`), user1, user2)
 	if err != nil {
 		return err
 	}
 
-	_, err = tx.Exec(`DELETE FROM friend_requests WHERE requestor_id = ? AND requestee_id = ?`, requestorID, requesteeID)
+	_, err = tx.Exec(db.rebind(`DELETE FROM friend_requests WHERE requestor_id = ? AND requestee_id = ?`), requestorID, requesteeID)
 	if err != nil {
 		return err
 	}
@@ -157,7 +280,7 @@ func (db *DB) AcceptFriendRequest(requestorID, requesteeID int64) error {
 }
 
 func (db *DB) GetPendingFriendRequests(userID int64) ([]int64, error) {
-	rows, err := db.conn.Query(`SELECT requestor_id FROM friend_requests WHERE requestee_id = ?`, userID)
+	rows, err := db.query(`SELECT requestor_id FROM friend_requests WHERE requestee_id = ?`, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -173,3 +296,250 @@ func (db *DB) GetPendingFriendRequests(userID int64) ([]int64, error) {
 	}
 	return requestors, nil
 }
+
+func (db *DB) GetAccount(platform, externalID string) (*models.Account, error) {
+	var account models.Account
+	err := db.queryRow(`SELECT platform, external_id, address, chat_id FROM accounts WHERE platform = ? AND external_id = ?`, platform, externalID).
+		Scan(&account.Platform, &account.ExternalID, &account.Address, &account.ChatID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (db *DB) GetAccounts(chatID int64) ([]models.Account, error) {
+	rows, err := db.query(`SELECT platform, external_id, address, chat_id FROM accounts WHERE chat_id = ? ORDER BY platform, external_id`, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []models.Account
+	for rows.Next() {
+		var account models.Account
+		if err := rows.Scan(&account.Platform, &account.ExternalID, &account.Address, &account.ChatID); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
+
+func (db *DB) SaveAccount(account *models.Account) error {
+	_, err := db.exec(`INSERT INTO accounts (platform, external_id, address, chat_id) VALUES (?, ?, ?, ?)
+        ON CONFLICT(platform, external_id) DO UPDATE SET address=excluded.address, chat_id=excluded.chat_id`,
+		account.Platform, account.ExternalID, account.Address, account.ChatID)
+	return err
+}
+
+// MergeUser moves every account of fromChatID over to intoChatID and deletes
+// the user fromChatID along with its friendships.
+func (db *DB) MergeUser(fromChatID, intoChatID int64) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	statements := []struct {
+		query string
+		args  []any
+	}{
+		{`UPDATE accounts SET chat_id = ? WHERE chat_id = ?`, []any{intoChatID, fromChatID}},
+		{`DELETE FROM friend_requests WHERE requestor_id = ? OR requestee_id = ?`, []any{fromChatID, fromChatID}},
+		{`DELETE FROM friends WHERE user_id1 = ? OR user_id2 = ?`, []any{fromChatID, fromChatID}},
+		{`DELETE FROM lecture_rules WHERE chat_id = ?`, []any{fromChatID}},
+		{`DELETE FROM events WHERE chat_id = ?`, []any{fromChatID}},
+		{`DELETE FROM deadlines WHERE chat_id = ?`, []any{fromChatID}},
+		{`DELETE FROM reminder_offsets WHERE chat_id = ?`, []any{fromChatID}},
+		{`DELETE FROM reminder_acks WHERE chat_id = ?`, []any{fromChatID}},
+		{`DELETE FROM users WHERE chat_id = ?`, []any{fromChatID}},
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(db.rebind(stmt.query), stmt.args...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (db *DB) GetRules(chatID int64) ([]models.Rule, error) {
+	rows, err := db.query(`SELECT id, chat_id, action, match_on, pattern, replacement FROM lecture_rules WHERE chat_id = ? ORDER BY id`, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []models.Rule
+	for rows.Next() {
+		var rule models.Rule
+		if err := rows.Scan(&rule.ID, &rule.ChatID, &rule.Action, &rule.Match, &rule.Pattern, &rule.Replacement); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// AddRule stores rule and sets its ID.
+func (db *DB) AddRule(rule *models.Rule) error {
+	return db.queryRow(`INSERT INTO lecture_rules (chat_id, action, match_on, pattern, replacement) VALUES (?, ?, ?, ?, ?) RETURNING id`,
+		rule.ChatID, rule.Action, rule.Match, rule.Pattern, rule.Replacement).Scan(&rule.ID)
+}
+
+// DeleteRule removes one of chatID's rules and reports whether it existed.
+func (db *DB) DeleteRule(chatID, ruleID int64) (bool, error) {
+	result, err := db.exec(`DELETE FROM lecture_rules WHERE id = ? AND chat_id = ?`, ruleID, chatID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+func (db *DB) GetEvents(chatID int64) ([]models.Event, error) {
+	rows, err := db.query(`SELECT id, chat_id, title, location, start_at, end_at, weekly FROM events WHERE chat_id = ? ORDER BY start_at, id`, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.Event
+	for rows.Next() {
+		var event models.Event
+		if err := rows.Scan(&event.ID, &event.ChatID, &event.Title, &event.Location, &event.Start, &event.End, &event.Weekly); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// AddEvent stores event and sets its ID.
+func (db *DB) AddEvent(event *models.Event) error {
+	return db.queryRow(`INSERT INTO events (chat_id, title, location, start_at, end_at, weekly) VALUES (?, ?, ?, ?, ?, ?) RETURNING id`,
+		event.ChatID, event.Title, event.Location, event.Start.UTC(), event.End.UTC(), event.Weekly).Scan(&event.ID)
+}
+
+// DeleteEvent removes one of chatID's events and reports whether it existed.
+func (db *DB) DeleteEvent(chatID, eventID int64) (bool, error) {
+	result, err := db.exec(`DELETE FROM events WHERE id = ? AND chat_id = ?`, eventID, chatID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+func (db *DB) GetDeadlines(chatID int64) ([]models.Deadline, error) {
+	rows, err := db.query(`SELECT id, chat_id, module, title, due_at, uid FROM deadlines WHERE chat_id = ? ORDER BY due_at, id`, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deadlines []models.Deadline
+	for rows.Next() {
+		var deadline models.Deadline
+		if err := rows.Scan(&deadline.ID, &deadline.ChatID, &deadline.Module, &deadline.Title, &deadline.Due, &deadline.UID); err != nil {
+			return nil, err
+		}
+		deadlines = append(deadlines, deadline)
+	}
+	return deadlines, rows.Err()
+}
+
+// AddDeadline stores deadline and sets its ID.
+func (db *DB) AddDeadline(deadline *models.Deadline) error {
+	return db.queryRow(`INSERT INTO deadlines (chat_id, module, title, due_at, uid) VALUES (?, ?, ?, ?, ?) RETURNING id`,
+		deadline.ChatID, deadline.Module, deadline.Title, deadline.Due.UTC(), deadline.UID).Scan(&deadline.ID)
+}
+
+// UpdateDeadline saves a changed module, title and due time.
+func (db *DB) UpdateDeadline(deadline *models.Deadline) error {
+	_, err := db.exec(`UPDATE deadlines SET module = ?, title = ?, due_at = ? WHERE id = ? AND chat_id = ?`,
+		deadline.Module, deadline.Title, deadline.Due.UTC(), deadline.ID, deadline.ChatID)
+	return err
+}
+
+// DeleteDeadline removes one of chatID's deadlines and reports whether it
+// existed.
+func (db *DB) DeleteDeadline(chatID, deadlineID int64) (bool, error) {
+	result, err := db.exec(`DELETE FROM deadlines WHERE id = ? AND chat_id = ?`, deadlineID, chatID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// GetReminderOffsets returns a user's reminder offsets grouped by module and
+// session type, longest first within each group.
+func (db *DB) GetReminderOffsets(chatID int64) ([]models.ReminderOffset, error) {
+	rows, err := db.query(`SELECT id, chat_id, minutes, session_type, module FROM reminder_offsets WHERE chat_id = ? ORDER BY module, session_type, minutes DESC`, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var offsets []models.ReminderOffset
+	for rows.Next() {
+		var offset models.ReminderOffset
+		if err := rows.Scan(&offset.ID, &offset.ChatID, &offset.Minutes, &offset.SessionType, &offset.Module); err != nil {
+			return nil, err
+		}
+		offsets = append(offsets, offset)
+	}
+	return offsets, rows.Err()
+}
+
+// SetReminderOffsets replaces chatID's offsets for one session type and
+// module, where "" means any, with minutes. No minutes removes them.
+func (db *DB) SetReminderOffsets(chatID int64, sessionType, module string, minutes []int) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(db.rebind(`DELETE FROM reminder_offsets WHERE chat_id = ? AND session_type = ? AND module = ?`), chatID, sessionType, module); err != nil {
+		return err
+	}
+	for _, m := range minutes {
+		if _, err := tx.Exec(db.rebind(`INSERT INTO reminder_offsets (chat_id, minutes, session_type, module) VALUES (?, ?, ?, ?)`), chatID, m, sessionType, module); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetReminderAcks returns chatID's answers to reminders for lectures
+// starting from from until to, in the order they were given.
+func (db *DB) GetReminderAcks(chatID int64, from, to time.Time) ([]models.ReminderAck, error) {
+	rows, err := db.query(`SELECT id, chat_id, lecture_uid, module, title, starts_at, action, acked_at FROM reminder_acks WHERE chat_id = ? AND starts_at >= ? AND starts_at < ? ORDER BY acked_at, id`,
+		chatID, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var acks []models.ReminderAck
+	for rows.Next() {
+		var ack models.ReminderAck
+		if err := rows.Scan(&ack.ID, &ack.ChatID, &ack.LectureUID, &ack.Module, &ack.Title, &ack.Start, &ack.Action, &ack.At); err != nil {
+			return nil, err
+		}
+		acks = append(acks, ack)
+	}
+	return acks, rows.Err()
+}
+
+// AddReminderAck stores ack and sets its ID.
+func (db *DB) AddReminderAck(ack *models.ReminderAck) error {
+	return db.queryRow(`INSERT INTO reminder_acks (chat_id, lecture_uid, module, title, starts_at, action, acked_at) VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING id`,
+		ack.ChatID, ack.LectureUID, ack.Module, ack.Title, ack.Start.UTC(), ack.Action, ack.At.UTC()).Scan(&ack.ID)
+}
@@ -0,0 +1,542 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+)
+
+var (
+	errFriendRequestExists = errors.New("UNIQUE constraint failed: friend_requests.requestor_id, friend_requests.requestee_id")
+	errFriendRequestSelf   = errors.New("CHECK constraint failed: chk_requestor_requestee")
+	errFriendsExist        = errors.New("UNIQUE constraint failed: friends.user_id1, friends.user_id2")
+	errFriendsOrder        = errors.New("CHECK constraint failed: chk_user_order")
+	errFeedTokenExists     = errors.New("UNIQUE constraint failed: users.feed_token")
+	errRuleAction          = errors.New("CHECK constraint failed: chk_action")
+	errRuleMatch           = errors.New("CHECK constraint failed: chk_match_on")
+	errEventEnd            = errors.New("CHECK constraint failed: chk_event_end")
+	errDeadlineUIDExists   = errors.New("UNIQUE constraint failed: deadlines.chat_id, deadlines.uid")
+	errReminderExists      = errors.New("UNIQUE constraint failed: reminder_offsets.chat_id, reminder_offsets.session_type, reminder_offsets.module, reminder_offsets.minutes")
+	errReminderMinutes     = errors.New("CHECK constraint failed: chk_minutes")
+	errAckAction           = errors.New("CHECK constraint failed: chk_ack_action")
+)
+
+type friendPair struct {
+	first, second int64
+}
+
+type accountKey struct {
+	platform, externalID string
+}
+
+// Memory is a Store held entirely in memory, for tests that should not need
+// a database file, such as the handler tests. It mirrors the SQLite
+// behaviour: SaveUser upserts, friend pairs are stored lowest ID first, and
+// constraint violations are errors. TestStore holds it to the same cases as
+// the SQL backends.
+type Memory struct {
+	users      map[int64]models.User
+	friends    map[friendPair]bool
+	requests   []friendPair
+	accounts   map[accountKey]models.Account
+	rules      []models.Rule
+	ruleID     int64
+	events     []models.Event
+	eventID    int64
+	deadlines  []models.Deadline
+	deadlineID int64
+	reminders  []models.ReminderOffset
+	reminderID int64
+	acks       []models.ReminderAck
+	ackID      int64
+	mu         sync.RWMutex
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		users:    make(map[int64]models.User),
+		friends:  make(map[friendPair]bool),
+		accounts: make(map[accountKey]models.Account),
+	}
+}
+
+var _ Store = (*Memory)(nil)
+
+func (m *Memory) Close() error {
+	return nil
+}
+
+func (m *Memory) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func (m *Memory) GetUser(chatID int64) (*models.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	user, ok := m.users[chatID]
+	if !ok {
+		return nil, nil
+	}
+	return &user, nil
+}
+
+func (m *Memory) GetTelegramUserByUsername(username string) (*models.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, chatID := range m.sortedChatIDs() {
+		if user := m.users[chatID]; user.Username == username && !models.IsSyntheticChatID(chatID) {
+			return &user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *Memory) GetUserByFeedToken(token string) (*models.User, error) {
+	if token == "" {
+		return nil, nil
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, user := range m.users {
+		if user.FeedToken == token {
+			return &user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *Memory) SaveUser(user *models.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if user.FeedToken != "" {
+		for chatID, other := range m.users {
+			if chatID != user.ChatID && other.FeedToken == user.FeedToken {
+				return errFeedTokenExists
+			}
+		}
+	}
+	stored := *user
+	if user.PausedUntil != nil {
+		until := user.PausedUntil.UTC()
+		stored.PausedUntil = &until
+	}
+	m.users[user.ChatID] = stored
+	return nil
+}
+
+func (m *Memory) GetAllUsers() ([]*models.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var users []*models.User
+	for _, chatID := range m.sortedChatIDs() {
+		user := m.users[chatID]
+		users = append(users, &user)
+	}
+	return users, nil
+}
+
+func (m *Memory) CountUsers() (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.users), nil
+}
+
+// sortedChatIDs returns user IDs in the order SQLite scans its rowid table.
+func (m *Memory) sortedChatIDs() []int64 {
+	ids := make([]int64, 0, len(m.users))
+	for chatID := range m.users {
+		ids = append(ids, chatID)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func (m *Memory) AreFriends(userID1, userID2 int64) (bool, error) {
+	if userID1 > userID2 {
+		userID1, userID2 = userID2, userID1
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.friends[friendPair{userID1, userID2}], nil
+}
+
+func (m *Memory) FriendRequestExists(requestorID, requesteeID int64) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.requestIndex(requestorID, requesteeID) >= 0, nil
+}
+
+func (m *Memory) AddFriendRequest(requestorID, requesteeID int64) error {
+	if requestorID == requesteeID {
+		return errFriendRequestSelf
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.requestIndex(requestorID, requesteeID) >= 0 {
+		return errFriendRequestExists
+	}
+	m.requests = append(m.requests, friendPair{requestorID, requesteeID})
+	return nil
+}
+
+// AcceptFriendRequest applies both changes or neither, like the SQL
+// transaction.
+func (m *Memory) AcceptFriendRequest(requestorID, requesteeID int64) error {
+	user1, user2 := requestorID, requesteeID
+	if user1 > user2 {
+		user1, user2 = user2, user1
+	}
+	if user1 == user2 {
+		return errFriendsOrder
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pair := friendPair{user1, user2}
+	if m.friends[pair] {
+		return errFriendsExist
+	}
+	m.friends[pair] = true
+	if i := m.requestIndex(requestorID, requesteeID); i >= 0 {
+		m.requests = append(m.requests[:i], m.requests[i+1:]...)
+	}
+	return nil
+}
+
+func (m *Memory) GetPendingFriendRequests(userID int64) ([]int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var requestors []int64
+	for _, request := range m.requests {
+		if request.second == userID {
+			requestors = append(requestors, request.first)
+		}
+	}
+	return requestors, nil
+}
+
+func (m *Memory) requestIndex(requestorID, requesteeID int64) int {
+	for i, request := range m.requests {
+		if request.first == requestorID && request.second == requesteeID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *Memory) GetAccount(platform, externalID string) (*models.Account, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	account, ok := m.accounts[accountKey{platform, externalID}]
+	if !ok {
+		return nil, nil
+	}
+	return &account, nil
+}
+
+func (m *Memory) GetAccounts(chatID int64) ([]models.Account, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var accounts []models.Account
+	for _, account := range m.accounts {
+		if account.ChatID == chatID {
+			accounts = append(accounts, account)
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		if accounts[i].Platform != accounts[j].Platform {
+			return accounts[i].Platform < accounts[j].Platform
+		}
+		return accounts[i].ExternalID < accounts[j].ExternalID
+	})
+	return accounts, nil
+}
+
+func (m *Memory) SaveAccount(account *models.Account) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.accounts[accountKey{account.Platform, account.ExternalID}] = *account
+	return nil
+}
+
+func (m *Memory) MergeUser(fromChatID, intoChatID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, account := range m.accounts {
+		if account.ChatID == fromChatID {
+			account.ChatID = intoChatID
+			m.accounts[key] = account
+		}
+	}
+	requests := m.requests[:0]
+	for _, request := range m.requests {
+		if request.first != fromChatID && request.second != fromChatID {
+			requests = append(requests, request)
+		}
+	}
+	m.requests = requests
+	for pair := range m.friends {
+		if pair.first == fromChatID || pair.second == fromChatID {
+			delete(m.friends, pair)
+		}
+	}
+	rules := m.rules[:0]
+	for _, rule := range m.rules {
+		if rule.ChatID != fromChatID {
+			rules = append(rules, rule)
+		}
+	}
+	m.rules = rules
+	events := m.events[:0]
+	for _, event := range m.events {
+		if event.ChatID != fromChatID {
+			events = append(events, event)
+		}
+	}
+	m.events = events
+	deadlines := m.deadlines[:0]
+	for _, deadline := range m.deadlines {
+		if deadline.ChatID != fromChatID {
+			deadlines = append(deadlines, deadline)
+		}
+	}
+	m.deadlines = deadlines
+	reminders := m.reminders[:0]
+	for _, reminder := range m.reminders {
+		if reminder.ChatID != fromChatID {
+			reminders = append(reminders, reminder)
+		}
+	}
+	m.reminders = reminders
+	acks := m.acks[:0]
+	for _, ack := range m.acks {
+		if ack.ChatID != fromChatID {
+			acks = append(acks, ack)
+		}
+	}
+	m.acks = acks
+	delete(m.users, fromChatID)
+	return nil
+}
+
+func (m *Memory) GetRules(chatID int64) ([]models.Rule, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var rules []models.Rule
+	for _, rule := range m.rules {
+		if rule.ChatID == chatID {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+func (m *Memory) AddRule(rule *models.Rule) error {
+	if rule.Action != models.RuleHide && rule.Action != models.RuleRename {
+		return errRuleAction
+	}
+	if rule.Match != models.MatchModule && rule.Match != models.MatchTitle {
+		return errRuleMatch
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ruleID++
+	rule.ID = m.ruleID
+	m.rules = append(m.rules, *rule)
+	return nil
+}
+
+func (m *Memory) DeleteRule(chatID, ruleID int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, rule := range m.rules {
+		if rule.ID == ruleID && rule.ChatID == chatID {
+			m.rules = append(m.rules[:i], m.rules[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *Memory) GetEvents(chatID int64) ([]models.Event, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var events []models.Event
+	for _, event := range m.events {
+		if event.ChatID == chatID {
+			events = append(events, event)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Start.Before(events[j].Start)
+	})
+	return events, nil
+}
+
+// AddEvent stores times in UTC, as they come back from the SQL stores.
+func (m *Memory) AddEvent(event *models.Event) error {
+	if event.End.Before(event.Start) {
+		return errEventEnd
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.eventID++
+	event.ID = m.eventID
+	stored := *event
+	stored.Start, stored.End = event.Start.UTC(), event.End.UTC()
+	m.events = append(m.events, stored)
+	return nil
+}
+
+func (m *Memory) DeleteEvent(chatID, eventID int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, event := range m.events {
+		if event.ID == eventID && event.ChatID == chatID {
+			m.events = append(m.events[:i], m.events[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *Memory) GetDeadlines(chatID int64) ([]models.Deadline, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var deadlines []models.Deadline
+	for _, deadline := range m.deadlines {
+		if deadline.ChatID == chatID {
+			deadlines = append(deadlines, deadline)
+		}
+	}
+	sort.SliceStable(deadlines, func(i, j int) bool {
+		return deadlines[i].Due.Before(deadlines[j].Due)
+	})
+	return deadlines, nil
+}
+
+func (m *Memory) AddDeadline(deadline *models.Deadline) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if deadline.UID != "" {
+		for _, other := range m.deadlines {
+			if other.ChatID == deadline.ChatID && other.UID == deadline.UID {
+				return errDeadlineUIDExists
+			}
+		}
+	}
+	m.deadlineID++
+	deadline.ID = m.deadlineID
+	stored := *deadline
+	stored.Due = deadline.Due.UTC()
+	m.deadlines = append(m.deadlines, stored)
+	return nil
+}
+
+func (m *Memory) UpdateDeadline(deadline *models.Deadline) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, stored := range m.deadlines {
+		if stored.ID == deadline.ID && stored.ChatID == deadline.ChatID {
+			m.deadlines[i].Module = deadline.Module
+			m.deadlines[i].Title = deadline.Title
+			m.deadlines[i].Due = deadline.Due.UTC()
+		}
+	}
+	return nil
+}
+
+func (m *Memory) DeleteDeadline(chatID, deadlineID int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, deadline := range m.deadlines {
+		if deadline.ID == deadlineID && deadline.ChatID == chatID {
+			m.deadlines = append(m.deadlines[:i], m.deadlines[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *Memory) GetReminderOffsets(chatID int64) ([]models.ReminderOffset, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var offsets []models.ReminderOffset
+	for _, offset := range m.reminders {
+		if offset.ChatID == chatID {
+			offsets = append(offsets, offset)
+		}
+	}
+	sort.SliceStable(offsets, func(i, j int) bool {
+		a, b := offsets[i], offsets[j]
+		if a.Module != b.Module {
+			return a.Module < b.Module
+		}
+		if a.SessionType != b.SessionType {
+			return a.SessionType < b.SessionType
+		}
+		return a.Minutes > b.Minutes
+	})
+	return offsets, nil
+}
+
+func (m *Memory) SetReminderOffsets(chatID int64, sessionType, module string, minutes []int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kept := make([]models.ReminderOffset, 0, len(m.reminders))
+	for _, offset := range m.reminders {
+		if offset.ChatID != chatID || offset.SessionType != sessionType || offset.Module != module {
+			kept = append(kept, offset)
+		}
+	}
+	seen := make(map[int]bool)
+	for _, n := range minutes {
+		if n <= 0 {
+			return errReminderMinutes
+		}
+		if seen[n] {
+			return errReminderExists
+		}
+		seen[n] = true
+		m.reminderID++
+		kept = append(kept, models.ReminderOffset{ID: m.reminderID, ChatID: chatID, Minutes: n, SessionType: sessionType, Module: module})
+	}
+	m.reminders = kept
+	return nil
+}
+
+func (m *Memory) GetReminderAcks(chatID int64, from, to time.Time) ([]models.ReminderAck, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var acks []models.ReminderAck
+	for _, ack := range m.acks {
+		if ack.ChatID == chatID && !ack.Start.Before(from) && ack.Start.Before(to) {
+			acks = append(acks, ack)
+		}
+	}
+	sort.SliceStable(acks, func(i, j int) bool {
+		return acks[i].At.Before(acks[j].At)
+	})
+	return acks, nil
+}
+
+func (m *Memory) AddReminderAck(ack *models.ReminderAck) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch ack.Action {
+	case models.AckGotIt, models.AckSnooze, models.AckSkip:
+	default:
+		return errAckAction
+	}
+	m.ackID++
+	ack.ID = m.ackID
+	stored := *ack
+	stored.Start = ack.Start.UTC()
+	stored.At = ack.At.UTC()
+	m.acks = append(m.acks, stored)
+	return nil
+}
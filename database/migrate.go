@@ -0,0 +1,113 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/migrations"
+
+	"github.com/golang-migrate/migrate/v4"
+	migratedb "github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// newMigrate reads migrations from migrationsPath when it is set, and from
+// the copies embedded in the binary for driver otherwise.
+func newMigrate(db *sql.DB, driverName, migrationsPath string) (*migrate.Migrate, error) {
+	var driver migratedb.Driver
+	var err error
+	switch driverName {
+	case DriverPostgres:
+		driver, err = postgres.WithInstance(db, &postgres.Config{})
+	default:
+		driver, err = sqlite3.WithInstance(db, &sqlite3.Config{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not create migration driver: %v", err)
+	}
+
+	var m *migrate.Migrate
+	if migrationsPath != "" {
+		m, err = migrate.NewWithDatabaseInstance("file://"+migrationsPath, driverName, driver)
+	} else {
+		source, sourceErr := iofs.New(migrations.FS, migrations.Dir(driverName))
+		if sourceErr != nil {
+			return nil, fmt.Errorf("could not load embedded migrations: %v", sourceErr)
+		}
+		m, err = migrate.NewWithInstance("iofs", source, driverName, driver)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not create migration instance: %v", err)
+	}
+	return m, nil
+}
+
+// Migrator exposes manual schema management for the migrate subcommand.
+type Migrator struct {
+	m *migrate.Migrate
+}
+
+func NewMigrator(driver, dsn, migrationsPath string) (*Migrator, error) {
+	dbConn, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	m, err := newMigrate(dbConn, driver, migrationsPath)
+	if err != nil {
+		dbConn.Close()
+		return nil, err
+	}
+	return &Migrator{m: m}, nil
+}
+
+// Up applies all pending migrations, or at most steps of them when steps is
+// positive.
+func (mg *Migrator) Up(steps int) error {
+	var err error
+	if steps > 0 {
+		err = mg.m.Steps(steps)
+	} else {
+		err = mg.m.Up()
+	}
+	return ignoreNoChange(err)
+}
+
+// Down rolls back the given number of migrations.
+func (mg *Migrator) Down(steps int) error {
+	if steps < 1 {
+		return errors.New("steps must be at least 1")
+	}
+	return ignoreNoChange(mg.m.Steps(-steps))
+}
+
+// Version returns the current schema version and whether the last migration
+// failed part-way. A database without any migrations reports version 0.
+func (mg *Migrator) Version() (uint, bool, error) {
+	version, dirty, err := mg.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// Force sets the schema version without running any migrations, clearing the
+// dirty flag after a failed migration has been fixed by hand.
+func (mg *Migrator) Force(version int) error {
+	return mg.m.Force(version)
+}
+
+func (mg *Migrator) Close() error {
+	sourceErr, dbErr := mg.m.Close()
+	return errors.Join(sourceErr, dbErr)
+}
+
+func ignoreNoChange(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateReminderOffsets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bot.db")
+	mg, err := NewMigrator(DriverSQLite, path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mg.Close()
+	if err := mg.Up(15); err != nil {
+		t.Fatalf("migrate to 15: %v", err)
+	}
+
+	conn, err := sql.Open(DriverSQLite, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	offsets := map[int64]any{1: "30", 2: nil, 3: "", 4: "soon", 5: "10m", 6: "0"}
+	for chatID, offset := range offsets {
+		if _, err := conn.Exec(`INSERT INTO users (chat_id, reminder_offset) VALUES (?, ?)`, chatID, offset); err != nil {
+			t.Fatalf("insert user %d: %v", chatID, err)
+		}
+	}
+
+	if err := mg.Up(1); err != nil {
+		t.Fatalf("migrate to 16: %v", err)
+	}
+	got := make(map[int64]int)
+	rows, err := conn.Query(`SELECT chat_id, minutes FROM reminder_offsets`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var chatID int64
+		var minutes int
+		if err := rows.Scan(&chatID, &minutes); err != nil {
+			t.Fatal(err)
+		}
+		got[chatID] = minutes
+	}
+	// Offsets must be positive, so "0" is not carried over.
+	want := map[int64]int{1: 30, 2: 15, 3: 15, 4: 15, 5: 15}
+	if len(got) != len(want) {
+		t.Errorf("reminder offsets = %v, want %v", got, want)
+	}
+	for chatID, minutes := range want {
+		if got[chatID] != minutes {
+			t.Errorf("user %d: offset = %d, want %d", chatID, got[chatID], minutes)
+		}
+	}
+}
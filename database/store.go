@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+)
+
+// Store persists users, their accounts on other chat platforms, the
+// friendships between them and their timetable preferences.
+type Store interface {
+	GetUser(chatID int64) (*models.User, error)
+	// GetTelegramUserByUsername returns the user who registered through
+	// Telegram with username, or nil. Usernames on other platforms are not
+	// unique across them, so those users cannot be found this way.
+	GetTelegramUserByUsername(username string) (*models.User, error)
+	// GetUserByFeedToken returns nil when no user has the token.
+	GetUserByFeedToken(token string) (*models.User, error)
+	SaveUser(user *models.User) error
+	GetAllUsers() ([]*models.User, error)
+	CountUsers() (int, error)
+
+	AreFriends(userID1, userID2 int64) (bool, error)
+	FriendRequestExists(requestorID, requesteeID int64) (bool, error)
+	AddFriendRequest(requestorID, requesteeID int64) error
+	AcceptFriendRequest(requestorID, requesteeID int64) error
+	GetPendingFriendRequests(userID int64) ([]int64, error)
+
+	// GetAccount returns nil when the platform identity has not been seen.
+	GetAccount(platform, externalID string) (*models.Account, error)
+	GetAccounts(chatID int64) ([]models.Account, error)
+	SaveAccount(account *models.Account) error
+	MergeUser(fromChatID, intoChatID int64) error
+
+	// GetRules returns a user's hide and rename rules in the order they were
+	// added.
+	GetRules(chatID int64) ([]models.Rule, error)
+	AddRule(rule *models.Rule) error
+	DeleteRule(chatID, ruleID int64) (bool, error)
+
+	// GetEvents returns a user's own events ordered by start time.
+	GetEvents(chatID int64) ([]models.Event, error)
+	AddEvent(event *models.Event) error
+	DeleteEvent(chatID, eventID int64) (bool, error)
+
+	// GetDeadlines returns a user's deadlines, soonest first.
+	GetDeadlines(chatID int64) ([]models.Deadline, error)
+	AddDeadline(deadline *models.Deadline) error
+	UpdateDeadline(deadline *models.Deadline) error
+	DeleteDeadline(chatID, deadlineID int64) (bool, error)
+
+	// GetReminderOffsets returns a user's reminder offsets grouped by module
+	// and session type, longest first within each group.
+	GetReminderOffsets(chatID int64) ([]models.ReminderOffset, error)
+	// SetReminderOffsets replaces a user's offsets for one session type and
+	// module, where "" means any.
+	SetReminderOffsets(chatID int64, sessionType, module string, minutes []int) error
+
+	// GetReminderAcks returns a user's answers to reminders for lectures
+	// starting from from until to, in the order they were given.
+	GetReminderAcks(chatID int64, from, to time.Time) ([]models.ReminderAck, error)
+	AddReminderAck(ack *models.ReminderAck) error
+
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+var _ Store = (*DB)(nil)
@@ -0,0 +1,529 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+)
+
+// backends opens an empty store of each kind. PostgreSQL is only tested
+// when DATABASE_URL points at a database the tests may wipe.
+var backends = []struct {
+	name string
+	open func(t *testing.T) Store
+}{
+	{"sqlite", func(t *testing.T) Store {
+		db, err := New(filepath.Join(t.TempDir(), "bot.db"), "")
+		if err != nil {
+			t.Fatalf("open sqlite: %v", err)
+		}
+		return db
+	}},
+	{"memory", func(t *testing.T) Store {
+		return NewMemory()
+	}},
+	{"postgres", func(t *testing.T) Store {
+		dsn := os.Getenv("DATABASE_URL")
+		if dsn == "" {
+			t.Skip("DATABASE_URL not set")
+		}
+		db, err := NewPostgres(dsn, "")
+		if err != nil {
+			t.Fatalf("open postgres: %v", err)
+		}
+		if _, err := db.exec(`TRUNCATE users, accounts, friend_requests, friends, lecture_rules, events, deadlines, reminder_offsets, reminder_acks CASCADE`); err != nil {
+			t.Fatalf("truncate: %v", err)
+		}
+		return db
+	}},
+}
+
+var storeCases = []struct {
+	name string
+	run  func(t *testing.T, s Store)
+}{
+	{"UserRoundTrip", testUserRoundTrip},
+	{"UserBlankFields", testUserBlankFields},
+	{"UserLookups", testUserLookups},
+	{"FriendRequests", testFriendRequests},
+	{"Accounts", testAccounts},
+	{"MergeUser", testMergeUser},
+	{"Rules", testRules},
+	{"Events", testEvents},
+	{"Deadlines", testDeadlines},
+	{"ReminderOffsets", testReminderOffsets},
+	{"ReminderAcks", testReminderAcks},
+}
+
+func TestStore(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			for _, tc := range storeCases {
+				t.Run(tc.name, func(t *testing.T) {
+					s := backend.open(t)
+					t.Cleanup(func() { s.Close() })
+					tc.run(t, s)
+				})
+			}
+		})
+	}
+}
+
+var day = time.Date(2024, time.November, 4, 9, 0, 0, 0, time.UTC)
+
+func saveUser(t *testing.T, s Store, chatID int64, username string) *models.User {
+	t.Helper()
+	user := &models.User{ChatID: chatID, Username: username, DailyChannel: models.ChannelTelegram}
+	if err := s.SaveUser(user); err != nil {
+		t.Fatalf("SaveUser(%d): %v", chatID, err)
+	}
+	return user
+}
+
+func mustGetUser(t *testing.T, s Store, chatID int64) *models.User {
+	t.Helper()
+	user, err := s.GetUser(chatID)
+	if err != nil {
+		t.Fatalf("GetUser(%d): %v", chatID, err)
+	}
+	return user
+}
+
+func testUserRoundTrip(t *testing.T, s Store) {
+	if user := mustGetUser(t, s, 1); user != nil {
+		t.Fatalf("GetUser of unknown chat = %+v, want nil", user)
+	}
+
+	until := day.Add(48 * time.Hour)
+	want := models.User{
+		ChatID:             1,
+		Username:           "alice",
+		WebCalURL:          "webcal://example.com/cal.ics",
+		DailyTime:          "07:30",
+		WeeklyTime:         "SUN 18:00",
+		Email:              "alice@ucl.ac.uk",
+		EmailVerified:      true,
+		DailyChannel:       models.ChannelBoth,
+		WeeklyChannel:      models.ChannelEmail,
+		ReminderChannel:    models.ChannelTelegram,
+		FeedToken:          "token",
+		MoodleURL:          "https://moodle.ucl.ac.uk/export",
+		Paused:             true,
+		PausedUntil:        &until,
+		DailyDisabled:      true,
+		WeeklyDisabled:     true,
+		RemindersDisabled:  true,
+		DailyWeekends:      true,
+		DailySkipEmpty:     true,
+		RemindersFirstOnly: true,
+		EveningTime:        "21:00",
+		CommuteMinutes:     25,
+		LeaveDisabled:      true,
+	}
+	user := want
+	if err := s.SaveUser(&user); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	got := mustGetUser(t, s, 1)
+	if got == nil || got.PausedUntil == nil || !got.PausedUntil.Equal(until) {
+		t.Fatalf("GetUser = %+v, want PausedUntil %v", got, until)
+	}
+	got.PausedUntil = want.PausedUntil
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("GetUser = %+v, want %+v", *got, want)
+	}
+
+	user.DailyTime = "08:00"
+	user.Paused = false
+	user.PausedUntil = nil
+	if err := s.SaveUser(&user); err != nil {
+		t.Fatalf("SaveUser update: %v", err)
+	}
+	got = mustGetUser(t, s, 1)
+	if got.DailyTime != "08:00" || got.Paused || got.PausedUntil != nil {
+		t.Errorf("GetUser after update = %+v", *got)
+	}
+	if n, err := s.CountUsers(); err != nil || n != 1 {
+		t.Errorf("CountUsers = %d, %v, want 1", n, err)
+	}
+}
+
+// testUserBlankFields checks a user with nothing set reads back as saved,
+// which fails if a text column can be NULL.
+func testUserBlankFields(t *testing.T, s Store) {
+	if err := s.SaveUser(&models.User{ChatID: 2}); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	got := mustGetUser(t, s, 2)
+	if got == nil || !reflect.DeepEqual(*got, models.User{ChatID: 2}) {
+		t.Errorf("GetUser = %+v, want a blank user", got)
+	}
+}
+
+func testUserLookups(t *testing.T, s Store) {
+	alice := saveUser(t, s, 1, "alice")
+	saveUser(t, s, 2, "bob")
+	alice.FeedToken = "secret"
+	if err := s.SaveUser(alice); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+
+	if got, err := s.GetTelegramUserByUsername("bob"); err != nil || got == nil || got.ChatID != 2 {
+		t.Errorf("GetTelegramUserByUsername(bob) = %+v, %v", got, err)
+	}
+	if got, err := s.GetTelegramUserByUsername("carol"); err != nil || got != nil {
+		t.Errorf("GetTelegramUserByUsername(carol) = %+v, %v, want nil", got, err)
+	}
+
+	// A Discord user with the same name sorts first but is never returned.
+	saveUser(t, s, models.SyntheticChatID(models.PlatformDiscord, "1"), "bob")
+	saveUser(t, s, models.SyntheticChatID(models.PlatformDiscord, "2"), "dave")
+	if got, err := s.GetTelegramUserByUsername("bob"); err != nil || got == nil || got.ChatID != 2 {
+		t.Errorf("GetTelegramUserByUsername(bob) with a Discord bob = %+v, %v, want the Telegram user", got, err)
+	}
+	if got, err := s.GetTelegramUserByUsername("dave"); err != nil || got != nil {
+		t.Errorf("GetTelegramUserByUsername(dave) = %+v, %v, want nil for a Discord user", got, err)
+	}
+	if got, err := s.GetUserByFeedToken("secret"); err != nil || got == nil || got.ChatID != 1 {
+		t.Errorf("GetUserByFeedToken(secret) = %+v, %v", got, err)
+	}
+	if got, err := s.GetUserByFeedToken(""); err != nil || got != nil {
+		t.Errorf("GetUserByFeedToken(\"\") = %+v, %v, want nil", got, err)
+	}
+
+	bob := mustGetUser(t, s, 2)
+	bob.FeedToken = "secret"
+	if err := s.SaveUser(bob); err == nil {
+		t.Error("SaveUser with another user's feed token succeeded, want an error")
+	}
+
+	users, err := s.GetAllUsers()
+	if err != nil || len(users) != 4 {
+		t.Errorf("GetAllUsers = %d users, %v, want 4", len(users), err)
+	}
+}
+
+func testFriendRequests(t *testing.T, s Store) {
+	saveUser(t, s, 1, "alice")
+	saveUser(t, s, 2, "bob")
+	saveUser(t, s, 3, "carol")
+
+	if err := s.AddFriendRequest(2, 1); err != nil {
+		t.Fatalf("AddFriendRequest: %v", err)
+	}
+	if err := s.AddFriendRequest(3, 1); err != nil {
+		t.Fatalf("AddFriendRequest: %v", err)
+	}
+	if err := s.AddFriendRequest(2, 1); err == nil {
+		t.Error("duplicate AddFriendRequest succeeded, want an error")
+	}
+	if err := s.AddFriendRequest(1, 1); err == nil {
+		t.Error("AddFriendRequest to self succeeded, want an error")
+	}
+	if ok, err := s.FriendRequestExists(2, 1); err != nil || !ok {
+		t.Errorf("FriendRequestExists(2, 1) = %v, %v, want true", ok, err)
+	}
+	if ok, err := s.FriendRequestExists(1, 2); err != nil || ok {
+		t.Errorf("FriendRequestExists(1, 2) = %v, %v, want false", ok, err)
+	}
+
+	pending, err := s.GetPendingFriendRequests(1)
+	if err != nil || len(pending) != 2 {
+		t.Fatalf("GetPendingFriendRequests = %v, %v, want two", pending, err)
+	}
+
+	if err := s.AcceptFriendRequest(2, 1); err != nil {
+		t.Fatalf("AcceptFriendRequest: %v", err)
+	}
+	for _, pair := range [][2]int64{{1, 2}, {2, 1}} {
+		if ok, err := s.AreFriends(pair[0], pair[1]); err != nil || !ok {
+			t.Errorf("AreFriends(%d, %d) = %v, %v, want true", pair[0], pair[1], ok, err)
+		}
+	}
+	if ok, err := s.AreFriends(1, 3); err != nil || ok {
+		t.Errorf("AreFriends(1, 3) = %v, %v, want false", ok, err)
+	}
+	if ok, _ := s.FriendRequestExists(2, 1); ok {
+		t.Error("friend request still exists after it was accepted")
+	}
+	if pending, _ := s.GetPendingFriendRequests(1); !reflect.DeepEqual(pending, []int64{3}) {
+		t.Errorf("GetPendingFriendRequests after accepting = %v, want [3]", pending)
+	}
+}
+
+func testAccounts(t *testing.T, s Store) {
+	if got, err := s.GetAccount(models.PlatformDiscord, "42"); err != nil || got != nil {
+		t.Fatalf("GetAccount of unknown identity = %+v, %v, want nil", got, err)
+	}
+
+	chatID := models.SyntheticChatID(models.PlatformDiscord, "42")
+	saveUser(t, s, chatID, "alice")
+	discord := models.Account{Platform: models.PlatformDiscord, ExternalID: "42", Address: "dm-1", ChatID: chatID}
+	matrix := models.Account{Platform: models.PlatformMatrix, ExternalID: "@alice:ucl", Address: "!room", ChatID: chatID}
+	for _, account := range []models.Account{matrix, discord} {
+		if err := s.SaveAccount(&account); err != nil {
+			t.Fatalf("SaveAccount: %v", err)
+		}
+	}
+
+	discord.Address = "dm-2"
+	if err := s.SaveAccount(&discord); err != nil {
+		t.Fatalf("SaveAccount update: %v", err)
+	}
+	if got, err := s.GetAccount(models.PlatformDiscord, "42"); err != nil || got == nil || *got != discord {
+		t.Errorf("GetAccount = %+v, %v, want %+v", got, err, discord)
+	}
+
+	accounts, err := s.GetAccounts(chatID)
+	if err != nil {
+		t.Fatalf("GetAccounts: %v", err)
+	}
+	if want := []models.Account{discord, matrix}; !reflect.DeepEqual(accounts, want) {
+		t.Errorf("GetAccounts = %+v, want %+v", accounts, want)
+	}
+}
+
+func testMergeUser(t *testing.T, s Store) {
+	from := models.SyntheticChatID(models.PlatformMatrix, "@alice:ucl")
+	saveUser(t, s, 1, "alice")
+	saveUser(t, s, 2, "bob")
+	saveUser(t, s, from, "alice")
+
+	account := models.Account{Platform: models.PlatformMatrix, ExternalID: "@alice:ucl", Address: "!room", ChatID: from}
+	if err := s.SaveAccount(&account); err != nil {
+		t.Fatalf("SaveAccount: %v", err)
+	}
+	if err := s.AddFriendRequest(from, 2); err != nil {
+		t.Fatalf("AddFriendRequest: %v", err)
+	}
+	if err := s.AddRule(&models.Rule{ChatID: from, Action: models.RuleHide, Match: models.MatchModule, Pattern: "COMP0001"}); err != nil {
+		t.Fatalf("AddRule: %v", err)
+	}
+	if err := s.AddEvent(&models.Event{ChatID: from, Title: "Gym", Start: day, End: day.Add(time.Hour)}); err != nil {
+		t.Fatalf("AddEvent: %v", err)
+	}
+	if err := s.AddDeadline(&models.Deadline{ChatID: from, Module: "COMP0001", Title: "CW1", Due: day}); err != nil {
+		t.Fatalf("AddDeadline: %v", err)
+	}
+	if err := s.SetReminderOffsets(from, "", "", []int{15}); err != nil {
+		t.Fatalf("SetReminderOffsets: %v", err)
+	}
+	if err := s.AddReminderAck(&models.ReminderAck{ChatID: from, LectureUID: "uid", Title: "Lecture", Start: day, Action: models.AckGotIt, At: day}); err != nil {
+		t.Fatalf("AddReminderAck: %v", err)
+	}
+
+	if err := s.MergeUser(from, 1); err != nil {
+		t.Fatalf("MergeUser: %v", err)
+	}
+
+	if user := mustGetUser(t, s, from); user != nil {
+		t.Errorf("merged user still exists: %+v", user)
+	}
+	if got, _ := s.GetAccount(models.PlatformMatrix, "@alice:ucl"); got == nil || got.ChatID != 1 {
+		t.Errorf("account after merge = %+v, want chat 1", got)
+	}
+	if ok, _ := s.FriendRequestExists(from, 2); ok {
+		t.Error("merged user's friend request was kept")
+	}
+	rules, _ := s.GetRules(from)
+	events, _ := s.GetEvents(from)
+	deadlines, _ := s.GetDeadlines(from)
+	offsets, _ := s.GetReminderOffsets(from)
+	acks, _ := s.GetReminderAcks(from, day.Add(-time.Hour), day.Add(time.Hour))
+	if len(rules)+len(events)+len(deadlines)+len(offsets)+len(acks) > 0 {
+		t.Errorf("merged user's rows were kept: %d rules, %d events, %d deadlines, %d offsets, %d acks",
+			len(rules), len(events), len(deadlines), len(offsets), len(acks))
+	}
+	if n, _ := s.CountUsers(); n != 2 {
+		t.Errorf("CountUsers after merge = %d, want 2", n)
+	}
+}
+
+func testRules(t *testing.T, s Store) {
+	saveUser(t, s, 1, "alice")
+	saveUser(t, s, 2, "bob")
+
+	hide := models.Rule{ChatID: 1, Action: models.RuleHide, Match: models.MatchModule, Pattern: "COMP0002"}
+	rename := models.Rule{ChatID: 1, Action: models.RuleRename, Match: models.MatchTitle, Pattern: "algo", Replacement: "Algorithms"}
+	for _, rule := range []*models.Rule{&hide, &rename} {
+		if err := s.AddRule(rule); err != nil {
+			t.Fatalf("AddRule: %v", err)
+		}
+		if rule.ID == 0 {
+			t.Fatal("AddRule did not set the ID")
+		}
+	}
+	if err := s.AddRule(&models.Rule{ChatID: 1, Action: "delete", Match: models.MatchModule, Pattern: "X"}); err == nil {
+		t.Error("AddRule with an unknown action succeeded, want an error")
+	}
+	if err := s.AddRule(&models.Rule{ChatID: 1, Action: models.RuleHide, Match: "room", Pattern: "X"}); err == nil {
+		t.Error("AddRule with an unknown match succeeded, want an error")
+	}
+
+	rules, err := s.GetRules(1)
+	if err != nil || !reflect.DeepEqual(rules, []models.Rule{hide, rename}) {
+		t.Errorf("GetRules = %+v, %v, want %+v", rules, err, []models.Rule{hide, rename})
+	}
+
+	if ok, err := s.DeleteRule(2, hide.ID); err != nil || ok {
+		t.Errorf("DeleteRule of another user's rule = %v, %v, want false", ok, err)
+	}
+	if ok, err := s.DeleteRule(1, hide.ID); err != nil || !ok {
+		t.Errorf("DeleteRule = %v, %v, want true", ok, err)
+	}
+	if rules, _ := s.GetRules(1); !reflect.DeepEqual(rules, []models.Rule{rename}) {
+		t.Errorf("GetRules after delete = %+v", rules)
+	}
+}
+
+func testEvents(t *testing.T, s Store) {
+	saveUser(t, s, 1, "alice")
+
+	later := models.Event{ChatID: 1, Title: "Gym", Location: "Bloomsbury Fitness", Start: day.Add(24 * time.Hour), End: day.Add(25 * time.Hour), Weekly: true}
+	sooner := models.Event{ChatID: 1, Title: "Society", Start: day, End: day.Add(2 * time.Hour)}
+	for _, event := range []*models.Event{&later, &sooner} {
+		if err := s.AddEvent(event); err != nil {
+			t.Fatalf("AddEvent: %v", err)
+		}
+	}
+	if err := s.AddEvent(&models.Event{ChatID: 1, Title: "Backwards", Start: day, End: day.Add(-time.Hour)}); err == nil {
+		t.Error("AddEvent ending before it starts succeeded, want an error")
+	}
+
+	events, err := s.GetEvents(1)
+	if err != nil || len(events) != 2 {
+		t.Fatalf("GetEvents = %+v, %v, want two", events, err)
+	}
+	for i, want := range []models.Event{sooner, later} {
+		got := events[i]
+		if got.ID != want.ID || got.Title != want.Title || got.Location != want.Location || got.Weekly != want.Weekly ||
+			!got.Start.Equal(want.Start) || !got.End.Equal(want.End) {
+			t.Errorf("GetEvents[%d] = %+v, want %+v", i, got, want)
+		}
+	}
+
+	if ok, err := s.DeleteEvent(1, sooner.ID); err != nil || !ok {
+		t.Errorf("DeleteEvent = %v, %v, want true", ok, err)
+	}
+	if ok, err := s.DeleteEvent(1, sooner.ID); err != nil || ok {
+		t.Errorf("second DeleteEvent = %v, %v, want false", ok, err)
+	}
+}
+
+func testDeadlines(t *testing.T, s Store) {
+	saveUser(t, s, 1, "alice")
+
+	cw := models.Deadline{ChatID: 1, Module: "COMP0001", Title: "CW1", Due: day.Add(72 * time.Hour)}
+	quiz := models.Deadline{ChatID: 1, Module: "COMP0002", Title: "Quiz", Due: day, UID: "moodle-1"}
+	for _, deadline := range []*models.Deadline{&cw, &quiz} {
+		if err := s.AddDeadline(deadline); err != nil {
+			t.Fatalf("AddDeadline: %v", err)
+		}
+	}
+	if err := s.AddDeadline(&models.Deadline{ChatID: 1, Module: "COMP0003", Title: "Essay", Due: day}); err != nil {
+		t.Errorf("second deadline without a UID: %v", err)
+	}
+	if err := s.AddDeadline(&models.Deadline{ChatID: 1, Module: "COMP0002", Title: "Quiz", Due: day, UID: "moodle-1"}); err == nil {
+		t.Error("AddDeadline with a duplicate UID succeeded, want an error")
+	}
+
+	cw.Due = day.Add(-time.Hour)
+	cw.Title = "CW1 (moved)"
+	if err := s.UpdateDeadline(&cw); err != nil {
+		t.Fatalf("UpdateDeadline: %v", err)
+	}
+
+	deadlines, err := s.GetDeadlines(1)
+	if err != nil || len(deadlines) != 3 {
+		t.Fatalf("GetDeadlines = %+v, %v, want three", deadlines, err)
+	}
+	if got := deadlines[0]; got.ID != cw.ID || got.Title != "CW1 (moved)" || !got.Due.Equal(cw.Due) {
+		t.Errorf("first deadline = %+v, want the moved %+v", got, cw)
+	}
+	if got := deadlines[1]; got.ID != quiz.ID || got.UID != "moodle-1" {
+		t.Errorf("second deadline = %+v, want %+v", got, quiz)
+	}
+
+	if ok, err := s.DeleteDeadline(1, quiz.ID); err != nil || !ok {
+		t.Errorf("DeleteDeadline = %v, %v, want true", ok, err)
+	}
+}
+
+func testReminderOffsets(t *testing.T, s Store) {
+	saveUser(t, s, 1, "alice")
+
+	if err := s.SetReminderOffsets(1, "", "", []int{10, 60}); err != nil {
+		t.Fatalf("SetReminderOffsets: %v", err)
+	}
+	if err := s.SetReminderOffsets(1, "lab", "COMP0001", []int{30}); err != nil {
+		t.Fatalf("SetReminderOffsets: %v", err)
+	}
+	if err := s.SetReminderOffsets(1, "lab", "", []int{45}); err != nil {
+		t.Fatalf("SetReminderOffsets: %v", err)
+	}
+	if err := s.SetReminderOffsets(1, "", "", []int{0}); err == nil {
+		t.Error("SetReminderOffsets with 0 minutes succeeded, want an error")
+	}
+	if err := s.SetReminderOffsets(1, "", "", []int{5, 5}); err == nil {
+		t.Error("SetReminderOffsets with a repeated offset succeeded, want an error")
+	}
+
+	offsets, err := s.GetReminderOffsets(1)
+	if err != nil {
+		t.Fatalf("GetReminderOffsets: %v", err)
+	}
+	type group struct {
+		minutes             int
+		sessionType, module string
+	}
+	var got []group
+	for _, offset := range offsets {
+		got = append(got, group{offset.Minutes, offset.SessionType, offset.Module})
+	}
+	want := []group{{60, "", ""}, {10, "", ""}, {45, "lab", ""}, {30, "lab", "COMP0001"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetReminderOffsets = %+v, want %+v (failed sets must not change anything)", got, want)
+	}
+
+	if err := s.SetReminderOffsets(1, "lab", "", nil); err != nil {
+		t.Fatalf("SetReminderOffsets off: %v", err)
+	}
+	if offsets, _ := s.GetReminderOffsets(1); len(offsets) != 3 {
+		t.Errorf("GetReminderOffsets after removing lab offsets = %+v, want three", offsets)
+	}
+}
+
+func testReminderAcks(t *testing.T, s Store) {
+	saveUser(t, s, 1, "alice")
+	saveUser(t, s, 2, "bob")
+
+	acks := []models.ReminderAck{
+		{ChatID: 1, LectureUID: "a", Module: "COMP0001", Title: "Lecture", Start: day, Action: models.AckSnooze, At: day.Add(-10 * time.Minute)},
+		{ChatID: 1, LectureUID: "a", Module: "COMP0001", Title: "Lecture", Start: day, Action: models.AckGotIt, At: day.Add(-5 * time.Minute)},
+		{ChatID: 1, LectureUID: "b", Title: "Tutorial", Start: day.Add(24 * time.Hour), Action: models.AckSkip, At: day},
+		{ChatID: 2, LectureUID: "a", Title: "Lecture", Start: day, Action: models.AckSkip, At: day},
+	}
+	for i := range acks {
+		if err := s.AddReminderAck(&acks[i]); err != nil {
+			t.Fatalf("AddReminderAck: %v", err)
+		}
+	}
+	if err := s.AddReminderAck(&models.ReminderAck{ChatID: 1, LectureUID: "a", Title: "Lecture", Start: day, Action: "maybe", At: day}); err == nil {
+		t.Error("AddReminderAck with an unknown action succeeded, want an error")
+	}
+
+	got, err := s.GetReminderAcks(1, day, day.Add(24*time.Hour))
+	if err != nil || len(got) != 2 {
+		t.Fatalf("GetReminderAcks = %+v, %v, want the two for the first day", got, err)
+	}
+	for i, want := range acks[:2] {
+		if got[i].ID != want.ID || got[i].Action != want.Action || got[i].Module != want.Module ||
+			!got[i].Start.Equal(want.Start) || !got[i].At.Equal(want.At) {
+			t.Errorf("GetReminderAcks[%d] = %+v, want %+v", i, got[i], want)
+		}
+	}
+}
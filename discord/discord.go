@@ -0,0 +1,184 @@
+// Package discord runs the bot on Discord. Users talk to it in direct
+// messages; commands start with / or !.
+package discord
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/frontend"
+	"github.com/artem-streltsov/ucl-timetable-bot/messenger"
+	"github.com/artem-streltsov/ucl-timetable-bot/utils"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// maxMessageLength is Discord's limit on message content.
+	maxMessageLength = 2000
+	// maxRows is the number of button rows a Discord message can carry.
+	maxRows = 5
+)
+
+var boldPattern = regexp.MustCompile(`\*([^*\n]+)\*`)
+
+// Discord is both the messenger.Platform for Discord and the front-end that
+// receives its events.
+type Discord struct {
+	session *discordgo.Session
+}
+
+func New(token string) (*Discord, error) {
+	session, err := discordgo.New("Bot " + token)
+	if err != nil {
+		return nil, err
+	}
+	session.Identify.Intents = discordgo.IntentsDirectMessages
+	return &Discord{session: session}, nil
+}
+
+var _ messenger.Platform = (*Discord)(nil)
+
+// Run connects to the gateway and passes direct messages and button presses
+// to dispatcher until ctx is canceled.
+func (d *Discord) Run(ctx context.Context, dispatcher *frontend.Dispatcher) error {
+	d.session.AddHandler(func(s *discordgo.Session, m *discordgo.MessageCreate) {
+		if m.Author == nil || m.Author.Bot || m.GuildID != "" {
+			return
+		}
+		dispatcher.Message(ctx, frontend.Sender{
+			ExternalID: m.Author.ID,
+			Address:    m.ChannelID,
+			Username:   m.Author.Username,
+		}, m.Content)
+	})
+	d.session.AddHandler(func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		if i.Type != discordgo.InteractionMessageComponent || i.User == nil || i.Message == nil {
+			return
+		}
+		dispatcher.Callback(ctx, frontend.Sender{
+			ExternalID: i.User.ID,
+			Address:    i.ChannelID,
+			Username:   i.User.Username,
+		}, i.ID+":"+i.Token, i.Message.ID, i.MessageComponentData().CustomID)
+	})
+
+	if err := d.session.Open(); err != nil {
+		return fmt.Errorf("failed to connect to Discord: %w", err)
+	}
+	slog.Info("discord front-end started", "username", d.session.State.User.Username)
+
+	<-ctx.Done()
+	return d.session.Close()
+}
+
+func (d *Discord) SendText(ctx context.Context, address string, text string) (string, error) {
+	return d.SendKeyboard(ctx, address, text, nil)
+}
+
+// SendKeyboard sends text, split across messages if it is too long, with the
+// buttons on the last one.
+func (d *Discord) SendKeyboard(ctx context.Context, address string, text string, keyboard messenger.Keyboard) (string, error) {
+	chunks := split(format(text))
+	var sent *discordgo.Message
+	for i, chunk := range chunks {
+		msg := &discordgo.MessageSend{Content: chunk}
+		if i == len(chunks)-1 {
+			msg.Components = components(keyboard)
+		}
+		var err error
+		sent, err = d.session.ChannelMessageSendComplex(address, msg, discordgo.WithContext(ctx))
+		if err != nil {
+			return "", err
+		}
+	}
+	return sent.ID, nil
+}
+
+func (d *Discord) EditMessage(ctx context.Context, address string, messageID string, text string, keyboard messenger.Keyboard) error {
+	content := format(text)
+	if len(content) > maxMessageLength {
+		content = content[:runeBoundary(content, maxMessageLength)]
+	}
+	rows := components(keyboard)
+	edit := discordgo.NewMessageEdit(address, messageID)
+	edit.Content = &content
+	edit.Components = &rows
+	_, err := d.session.ChannelMessageEditComplex(edit, discordgo.WithContext(ctx))
+	return err
+}
+
+// AnswerCallback responds to a component interaction. callbackID is the
+// interaction ID and token joined by a colon. With text, the answer is a
+// message only the presser can see.
+func (d *Discord) AnswerCallback(ctx context.Context, callbackID string, text string) error {
+	id, token, ok := strings.Cut(callbackID, ":")
+	if !ok {
+		return fmt.Errorf("invalid Discord callback ID %q", callbackID)
+	}
+	response := &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredMessageUpdate}
+	if text != "" {
+		response = &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: format(text),
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}
+	}
+	return d.session.InteractionRespond(&discordgo.Interaction{ID: id, Token: token}, response, discordgo.WithContext(ctx))
+}
+
+// format converts the bot's Telegram-style Markdown to Discord's.
+func format(text string) string {
+	return boldPattern.ReplaceAllString(utils.EscapeUnderscores(text), "**$1**")
+}
+
+// split breaks text into messages Discord accepts, preferring line breaks.
+func split(text string) []string {
+	var chunks []string
+	for len(text) > maxMessageLength {
+		cut := strings.LastIndex(text[:maxMessageLength], "\n")
+		if cut <= 0 {
+			cut = runeBoundary(text, maxMessageLength)
+		}
+		chunks = append(chunks, text[:cut])
+		text = strings.TrimPrefix(text[cut:], "\n")
+	}
+	return append(chunks, text)
+}
+
+// runeBoundary returns the largest index no greater than n that does not
+// split a UTF-8 sequence in text.
+func runeBoundary(text string, n int) int {
+	for n > 0 && !utf8.RuneStart(text[n]) {
+		n--
+	}
+	return n
+}
+
+// components converts a keyboard to action rows. Discord allows at most
+// maxRows rows; any beyond that are dropped.
+func components(keyboard messenger.Keyboard) []discordgo.MessageComponent {
+	rows := []discordgo.MessageComponent{}
+	for _, row := range keyboard {
+		if len(rows) == maxRows {
+			slog.Warn("keyboard has too many rows for Discord, dropping the rest", "rows", len(keyboard))
+			break
+		}
+		var buttons []discordgo.MessageComponent
+		for _, button := range row {
+			buttons = append(buttons, discordgo.Button{
+				Label:    button.Text,
+				Style:    discordgo.SecondaryButton,
+				CustomID: button.Data,
+			})
+		}
+		rows = append(rows, discordgo.ActionsRow{Components: buttons})
+	}
+	return rows
+}
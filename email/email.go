@@ -0,0 +1,161 @@
+// Package email delivers summaries and reminders over SMTP.
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+)
+
+// Message is an email with plain-text and HTML versions of the same content.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	// ImplicitTLS starts TLS as soon as the connection is made, as servers
+	// on port 465 expect, instead of upgrading with STARTTLS.
+	ImplicitTLS bool
+}
+
+// SMTP sends mail through a single SMTP server, either over implicit TLS or
+// upgrading to TLS when the server offers STARTTLS, and authenticating when a
+// username is set.
+type SMTP struct {
+	cfg Config
+	// rootCAs verifies the server's certificate; nil uses the system pool.
+	rootCAs *x509.CertPool
+}
+
+func NewSMTP(cfg Config) *SMTP {
+	return &SMTP{cfg: cfg}
+}
+
+var _ Sender = (*SMTP)(nil)
+
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	data, err := s.build(msg)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	if s.cfg.ImplicitTLS {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: s.tlsConfig()}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("could not connect to SMTP server: %v", err)
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(30 * time.Second)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && !s.cfg.ImplicitTLS {
+		if err := client.StartTLS(s.tlsConfig()); err != nil {
+			return fmt.Errorf("STARTTLS failed: %v", err)
+		}
+	}
+	if s.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %v", err)
+		}
+	}
+	if err := client.Mail(s.cfg.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func (s *SMTP) tlsConfig() *tls.Config {
+	return &tls.Config{ServerName: s.cfg.Host, RootCAs: s.rootCAs}
+}
+
+// build renders msg as a multipart/alternative MIME message.
+func (s *SMTP) build(msg Message) ([]byte, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", part.contentType)
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		pw, err := mw.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+		qw := quotedprintable.NewWriter(pw)
+		if _, err := qw.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", s.cfg.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", mw.Boundary())
+	buf.Write(body.Bytes())
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,211 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// session is what the fake SMTP server saw during one connection.
+type session struct {
+	commands []string
+	data     string
+	tls      bool
+}
+
+// fakeSMTP accepts one connection on a local listener, wrapped in TLS when
+// tlsConfig is set, and plays a minimal SMTP server.
+func fakeSMTP(t *testing.T, tlsConfig *tls.Config) (host string, port int, done <-chan session) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tlsConfig != nil {
+		ln = tls.NewListener(ln, tlsConfig)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	sessions := make(chan session, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		var s session
+		_, s.tls = conn.(*tls.Conn)
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		reply("220 fake ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				break
+			}
+			line = strings.TrimRight(line, "\r\n")
+			s.commands = append(s.commands, line)
+			switch verb := strings.ToUpper(strings.Fields(line + " x")[0]); verb {
+			case "EHLO":
+				reply("250-fake")
+				reply("250 AUTH PLAIN")
+			case "AUTH":
+				reply("235 authenticated")
+			case "MAIL", "RCPT":
+				reply("250 ok")
+			case "DATA":
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				s.data = data.String()
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				sessions <- s
+				return
+			default:
+				reply("502 unknown command")
+			}
+		}
+		sessions <- s
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, sessions
+}
+
+// selfSigned returns a server TLS config for 127.0.0.1 and a pool that
+// trusts it.
+func selfSigned(t *testing.T) (*tls.Config, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "fake smtp"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}, pool
+}
+
+var testMessage = Message{
+	To:      "student@ucl.ac.uk",
+	Subject: "Your timetable",
+	Text:    "No lectures today.",
+	HTML:    "<p>No lectures today.</p>",
+}
+
+func TestSMTPSend(t *testing.T) {
+	serverTLS, pool := selfSigned(t)
+	tests := []struct {
+		name        string
+		implicitTLS bool
+		username    string
+	}{
+		{"plain", false, ""},
+		{"plain with auth", false, "bot"},
+		{"implicit TLS", true, "bot"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var listenTLS *tls.Config
+			if tt.implicitTLS {
+				listenTLS = serverTLS
+			}
+			host, port, done := fakeSMTP(t, listenTLS)
+			s := NewSMTP(Config{
+				Host:        host,
+				Port:        port,
+				Username:    tt.username,
+				Password:    "secret",
+				From:        "bot@example.com",
+				ImplicitTLS: tt.implicitTLS,
+			})
+			s.rootCAs = pool
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := s.Send(ctx, testMessage); err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+
+			got := <-done
+			if got.tls != tt.implicitTLS {
+				t.Errorf("connection TLS = %v, want %v", got.tls, tt.implicitTLS)
+			}
+			joined := strings.Join(got.commands, "\n")
+			for _, want := range []string{"MAIL FROM:<bot@example.com>", "RCPT TO:<student@ucl.ac.uk>", "QUIT"} {
+				if !strings.Contains(joined, want) {
+					t.Errorf("commands %q do not include %q", got.commands, want)
+				}
+			}
+			if authed := strings.Contains(joined, "AUTH PLAIN"); authed != (tt.username != "") {
+				t.Errorf("authenticated = %v, want %v", authed, tt.username != "")
+			}
+			if !strings.Contains(got.data, "Subject: Your timetable") || !strings.Contains(got.data, "No lectures today.") {
+				t.Errorf("message data = %q, want the subject and body", got.data)
+			}
+		})
+	}
+}
+
+func TestSMTPSendImplicitTLSRejectsUntrustedServer(t *testing.T) {
+	serverTLS, _ := selfSigned(t)
+	host, port, _ := fakeSMTP(t, serverTLS)
+	s := NewSMTP(Config{Host: host, Port: port, From: "bot@example.com", ImplicitTLS: true})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Send(ctx, testMessage); err == nil {
+		t.Fatal("Send to a server with an untrusted certificate succeeded, want an error")
+	}
+}
+
+func TestSMTPSendUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	s := NewSMTP(Config{Host: "127.0.0.1", Port: port, From: "bot@example.com"})
+	err = s.Send(context.Background(), testMessage)
+	if err == nil || !strings.Contains(err.Error(), "could not connect") {
+		t.Fatalf("Send to a closed port = %v, want a connection error", err)
+	}
+}
@@ -0,0 +1,226 @@
+package email
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+	"github.com/artem-streltsov/ucl-timetable-bot/timetable"
+	"github.com/artem-streltsov/ucl-timetable-bot/utils"
+)
+
+type lectureData struct {
+	Title    string
+	Start    string
+	End      string
+	Location string
+}
+
+type dayData struct {
+	Heading  string
+	Lectures []lectureData
+}
+
+type deadlineData struct {
+	Module string
+	Title  string
+	Due    string
+}
+
+type summaryData struct {
+	Heading   string
+	Intro     string
+	Days      []dayData
+	Deadlines []deadlineData
+}
+
+const textSummary = `{{.Heading}}
+{{if .Intro}}
+{{.Intro}}
+{{end}}{{range .Days}}{{if .Heading}}
+{{.Heading}}
+{{end}}{{range .Lectures}}
+{{.Title}}
+{{.Start}} - {{.End}}
+{{.Location}}
+{{end}}{{end}}{{if .Deadlines}}
+Upcoming deadlines
+{{range .Deadlines}}
+{{.Module}} {{.Title}}
+Due {{.Due}}
+{{end}}{{end}}`
+
+const htmlSummary = `<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif;">
+<h2>{{.Heading}}</h2>
+{{if .Intro}}<p>{{.Intro}}</p>{{end}}
+{{range .Days}}{{if .Heading}}<h3>{{.Heading}}</h3>{{end}}
+<ul>
+{{range .Lectures}}<li><strong>{{.Title}}</strong><br>⏰ {{.Start}} - {{.End}}<br>📍 {{.Location}}</li>
+{{end}}</ul>
+{{end}}{{if .Deadlines}}<h3>Upcoming deadlines</h3>
+<ul>
+{{range .Deadlines}}<li><strong>{{.Module}}</strong> {{.Title}}<br>⏰ Due {{.Due}}</li>
+{{end}}</ul>
+{{end}}</body>
+</html>
+`
+
+var (
+	textTemplate = texttemplate.Must(texttemplate.New("summary").Parse(textSummary))
+	htmlTemplate = htmltemplate.Must(htmltemplate.New("summary").Parse(htmlSummary))
+)
+
+func lectureDataFrom(lectures []timetable.Lecture) []lectureData {
+	data := make([]lectureData, 0, len(lectures))
+	for _, lecture := range lectures {
+		data = append(data, lectureData{
+			Title:    timetable.CleanTitle(lecture.Title),
+			Start:    lecture.Start.Format("15:04"),
+			End:      lecture.End.Format("15:04"),
+			Location: lecture.Location,
+		})
+	}
+	return data
+}
+
+func deadlineDataFrom(deadlines []models.Deadline) []deadlineData {
+	data := make([]deadlineData, 0, len(deadlines))
+	for _, deadline := range deadlines {
+		data = append(data, deadlineData{
+			Module: deadline.Module,
+			Title:  deadline.Title,
+			Due:    deadline.Due.In(utils.Location()).Format("Mon 02 Jan 15:04"),
+		})
+	}
+	return data
+}
+
+func render(to, subject string, data summaryData) (Message, error) {
+	var text, html bytes.Buffer
+	if err := textTemplate.Execute(&text, data); err != nil {
+		return Message{}, err
+	}
+	if err := htmlTemplate.Execute(&html, data); err != nil {
+		return Message{}, err
+	}
+	return Message{
+		To:      to,
+		Subject: subject,
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
+
+func DailySummary(to string, day time.Time, lectures []timetable.Lecture) (Message, error) {
+	heading := day.Format("Mon, 02 Jan")
+	data := summaryData{Heading: heading}
+	if len(lectures) == 0 {
+		data.Intro = "No lectures today."
+	} else {
+		data.Days = []dayData{{Lectures: lectureDataFrom(lectures)}}
+	}
+	return render(to, "Your lectures for "+heading, data)
+}
+
+// EveningPreview lists the lectures on day, sent the evening before.
+func EveningPreview(to string, day time.Time, lectures []timetable.Lecture) (Message, error) {
+	heading := "Tomorrow, " + day.Format("Mon 02 Jan")
+	data := summaryData{Heading: heading}
+	if len(lectures) == 0 {
+		data.Intro = "No lectures tomorrow."
+	} else {
+		data.Days = []dayData{{Lectures: lectureDataFrom(lectures)}}
+	}
+	return render(to, "Your lectures for "+day.Format("Mon, 02 Jan"), data)
+}
+
+// WeeklySummary lists the week's lectures by day, followed by upcoming
+// deadlines.
+func WeeklySummary(to string, start, end time.Time, days []timetable.Day, deadlines []models.Deadline) (Message, error) {
+	heading := start.Format("Mon, 02 Jan") + " - " + end.Format("Mon, 02 Jan")
+	data := summaryData{Heading: heading, Deadlines: deadlineDataFrom(deadlines)}
+	for _, day := range days {
+		data.Days = append(data.Days, dayData{
+			Heading:  day.Date.Format("Mon 02 Jan"),
+			Lectures: lectureDataFrom(day.Lectures),
+		})
+	}
+	if len(data.Days) == 0 {
+		data.Intro = "No lectures this week."
+	}
+	return render(to, "Your lectures for "+heading, data)
+}
+
+func LectureReminder(to string, lecture timetable.Lecture, minutes int) (Message, error) {
+	lectures := lectureDataFrom([]timetable.Lecture{lecture})
+	data := summaryData{
+		Heading: lectures[0].Title,
+		Intro:   "Starts in " + pluralMinutes(minutes) + ".",
+		Days:    []dayData{{Lectures: lectures}},
+	}
+	return render(to, "Reminder: "+lectures[0].Title+" in "+pluralMinutes(minutes), data)
+}
+
+// LeaveReminder says it is time to set off for lecture, commute minutes
+// before it starts.
+func LeaveReminder(to string, lecture timetable.Lecture, commute int) (Message, error) {
+	lectures := lectureDataFrom([]timetable.Lecture{lecture})
+	data := summaryData{
+		Heading: "Leave now for " + lectures[0].Title,
+		Intro:   "It starts at " + lectures[0].Start + " and your commute is " + pluralMinutes(commute) + ".",
+		Days:    []dayData{{Lectures: lectures}},
+	}
+	return render(to, data.Heading, data)
+}
+
+// DeadlineReminder says deadline is due within left, such as "1 day".
+func DeadlineReminder(to string, deadline models.Deadline, left string) (Message, error) {
+	data := summaryData{
+		Heading:   deadline.Module + " " + deadline.Title,
+		Intro:     "Due in " + left + ".",
+		Deadlines: deadlineDataFrom([]models.Deadline{deadline}),
+	}
+	return render(to, "Deadline: "+data.Heading+" due in "+left, data)
+}
+
+// DeadlineChanges lists the deadlines a Moodle import added, moved or
+// removed.
+func DeadlineChanges(to string, changes timetable.DeadlineChanges) (Message, error) {
+	data := summaryData{
+		Heading: "Your Moodle deadlines changed",
+		Intro:   "These deadlines were added, moved or removed in Moodle.",
+	}
+	add := func(deadlines []models.Deadline, note string) {
+		for _, deadline := range deadlineDataFrom(deadlines) {
+			deadline.Title += note
+			data.Deadlines = append(data.Deadlines, deadline)
+		}
+	}
+	add(changes.Added, " (new)")
+	for _, move := range changes.Moved {
+		add([]models.Deadline{move.To}, " (moved from "+move.From.Due.In(utils.Location()).Format("Mon 02 Jan 15:04")+")")
+	}
+	add(changes.Removed, " (removed)")
+	return render(to, "Your Moodle deadlines changed", data)
+}
+
+func VerificationCode(to, code string) (Message, error) {
+	data := summaryData{
+		Heading: "Verify your email",
+		Intro:   "Send this code to the UCL Timetable Bot to confirm your email address: " + code,
+	}
+	return render(to, "Your UCL Timetable Bot verification code", data)
+}
+
+func pluralMinutes(minutes int) string {
+	if minutes == 1 {
+		return "1 minute"
+	}
+	return strconv.Itoa(minutes) + " minutes"
+}
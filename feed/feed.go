@@ -0,0 +1,112 @@
+// Package feed serves each user's timetable as an ICS calendar that calendar
+// apps can subscribe to. Feeds are addressed by a secret per-user token.
+package feed
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/database"
+	"github.com/artem-streltsov/ucl-timetable-bot/timetable"
+
+	ical "github.com/arran4/golang-ical"
+)
+
+// Pattern is the route the handler is registered on. The last segment is
+// the token followed by .ics.
+const Pattern = "GET /calendar/{file}"
+
+const calendarName = "UCL Timetable"
+
+// URL returns the feed address for token on the server at baseURL.
+func URL(baseURL, token string) string {
+	return strings.TrimSuffix(baseURL, "/") + "/calendar/" + token + ".ics"
+}
+
+// NewToken returns a random token for a feed URL.
+func NewToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+type Handler struct {
+	db database.Store
+}
+
+func NewHandler(db database.Store) *Handler {
+	return &Handler{db: db}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutSuffix(r.PathValue("file"), ".ics")
+	if !ok || token == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	user, err := h.db.GetUserByFeedToken(token)
+	if err != nil {
+		slog.Error("failed to look up feed token", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.NotFound(w, r)
+		return
+	}
+	logger := slog.With("chat_id", user.ChatID)
+
+	lectures, err := timetable.ForUser(h.db, user)
+	if err != nil {
+		logger.Error("failed to fetch calendar for feed", "error", err)
+		http.Error(w, "failed to fetch timetable", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	if err := Render(w, lectures); err != nil {
+		logger.Error("failed to write feed", "error", err)
+	}
+}
+
+// Render writes lectures as an ICS calendar with cleaned titles and the
+// module code as each event's category.
+func Render(w io.Writer, lectures []timetable.Lecture) error {
+	cal := ical.NewCalendarFor("ucl-timetable-bot")
+	cal.SetMethod(ical.MethodPublish)
+	cal.SetName(calendarName)
+	cal.SetXWRCalName(calendarName)
+	cal.SetRefreshInterval("PT1H")
+	cal.SetXPublishedTTL("PT1H")
+
+	now := time.Now().UTC()
+	for _, lecture := range lectures {
+		uid := lecture.UID
+		if uid == "" {
+			uid = lecture.Start.UTC().Format("20060102T150405Z") + "-" + timetable.CleanTitle(lecture.Title) + "@ucl-timetable-bot"
+		}
+		event := cal.AddEvent(uid)
+		event.SetDtStampTime(now)
+		event.SetStartAt(lecture.Start.UTC())
+		event.SetEndAt(lecture.End.UTC())
+		event.SetSummary(timetable.CleanTitle(lecture.Title))
+		if lecture.Location != "" {
+			event.SetLocation(lecture.Location)
+		}
+		if lecture.Module != "" {
+			event.AddProperty(ical.ComponentPropertyCategories, lecture.Module)
+			event.SetDescription("Module: " + lecture.Module)
+		}
+	}
+	// RFC 5545 lines end in CRLF; the library defaults to the OS newline.
+	return cal.SerializeTo(w, ical.WithNewLineWindows)
+}
@@ -0,0 +1,178 @@
+package feed
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/database"
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+	"github.com/artem-streltsov/ucl-timetable-bot/timetable"
+
+	ical "github.com/arran4/golang-ical"
+)
+
+const testToken = "0123456789abcdef"
+
+// servePortico serves an ICS calendar with one lecture, as Portico would.
+func servePortico(t *testing.T, start time.Time) string {
+	t.Helper()
+	const layout = "20060102T150405Z"
+	ics := fmt.Sprintf("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:portico\r\n"+
+		"BEGIN:VEVENT\r\nUID:portico-1\r\nDTSTAMP:%s\r\nDTSTART:%s\r\nDTEND:%s\r\n"+
+		"SUMMARY:COMP0010 Software Engineering\\, Lecture [Lecture Theatre] Level 5\r\n"+
+		"LOCATION:Roberts Building\\, G06\\; Ground Floor\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+		start.Format(layout), start.Format(layout), start.Add(time.Hour).Format(layout))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(ics))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL + "/calendar.ics"
+}
+
+// newTestServer serves the feed handler the way main.go mounts it, for a
+// user whose feed token is testToken.
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	db := database.NewMemory()
+	start := time.Now().UTC().Add(24 * time.Hour).Truncate(time.Hour)
+	user := &models.User{ChatID: 100, WebCalURL: servePortico(t, start), FeedToken: testToken}
+	if err := db.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(Pattern, NewHandler(db))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestServeHTTPStatus(t *testing.T) {
+	server := newTestServer(t)
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		want   int
+	}{
+		{"feed", http.MethodGet, "/calendar/" + testToken + ".ics", http.StatusOK},
+		{"unknown token", http.MethodGet, "/calendar/ffffffffffffffff.ics", http.StatusNotFound},
+		{"no .ics", http.MethodGet, "/calendar/" + testToken, http.StatusNotFound},
+		{"empty token", http.MethodGet, "/calendar/.ics", http.StatusNotFound},
+		{"post", http.MethodPost, "/calendar/" + testToken + ".ics", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("%s %s = %d, want %d", tt.method, tt.path, resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestServeHTTPFeed(t *testing.T) {
+	server := newTestServer(t)
+	resp, err := http.Get(URL(server.URL+"/", testToken))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/calendar") {
+		t.Errorf("Content-Type = %q, want text/calendar", ct)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The title is cleaned, and commas and semicolons are escaped.
+	for _, want := range []string{
+		"SUMMARY:COMP0010 Software Engineering\\, Lecture\r\n",
+		"LOCATION:Roberts Building\\, G06\\; Ground Floor\r\n",
+		"CATEGORIES:COMP0010\r\n",
+		"UID:portico-1\r\n",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("feed does not contain %q:\n%s", want, body)
+		}
+	}
+
+	cal, err := ical.ParseCalendar(strings.NewReader(string(body)))
+	if err != nil {
+		t.Fatalf("feed does not parse: %v", err)
+	}
+	events := cal.Events()
+	if len(events) != 1 {
+		t.Fatalf("feed has %d events, want 1", len(events))
+	}
+	if got := events[0].GetProperty(ical.ComponentPropertyLocation).Value; got != "Roberts Building, G06; Ground Floor" {
+		t.Errorf("location reads back as %q", got)
+	}
+}
+
+func TestRender(t *testing.T) {
+	start := time.Date(2025, time.October, 6, 9, 0, 0, 0, time.UTC)
+	lectures := []timetable.Lecture{
+		{Title: "Study group", Start: start, End: start.Add(time.Hour)},
+		{UID: "x@portico", Title: "COMP0002 Tutorial", Module: "COMP0002", Start: start, End: start.Add(time.Hour), Location: "Online"},
+	}
+	var sb strings.Builder
+	if err := Render(&sb, lectures); err != nil {
+		t.Fatal(err)
+	}
+	out := sb.String()
+	for _, want := range []string{
+		"X-WR-CALNAME:UCL Timetable\r\n",
+		// A lecture without a UID gets a stable one from its start and title.
+		"UID:20251006T090000Z-Study group@ucl-timetable-bot\r\n",
+		"UID:x@portico\r\n",
+		"CATEGORIES:COMP0002\r\n",
+		"DESCRIPTION:Module: COMP0002\r\n",
+		"DTSTART:20251006T090000Z\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Render output does not contain %q:\n%s", want, out)
+		}
+	}
+	if n := strings.Count(out, "CATEGORIES:"); n != 1 {
+		t.Errorf("%d CATEGORIES lines, want 1 for the one lecture with a module", n)
+	}
+}
+
+func TestServeHTTPOnlyEvents(t *testing.T) {
+	db := database.NewMemory()
+	if err := db.SaveUser(&models.User{ChatID: 100, FeedToken: testToken}); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+	if err := db.AddEvent(&models.Event{ChatID: 100, Title: "Study group", Start: start, End: start.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(Pattern, NewHandler(db))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := http.Get(URL(server.URL, testToken))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "SUMMARY:Study group\r\n") {
+		t.Errorf("feed without a calendar link = %d:\n%s\nwant the user's event", resp.StatusCode, body)
+	}
+}
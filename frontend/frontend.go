@@ -0,0 +1,157 @@
+// Package frontend connects chat platforms other than Telegram to the shared
+// handlers. Each platform adapter turns its events into Sender and text or
+// button presses; the Dispatcher maps the sender to a user and hands the
+// update on.
+package frontend
+
+import (
+	"context"
+	"strings"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/database"
+	"github.com/artem-streltsov/ucl-timetable-bot/logging"
+	"github.com/artem-streltsov/ucl-timetable-bot/messenger"
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+)
+
+// Handler is the part of handlers.Handler the dispatcher needs.
+type Handler interface {
+	HandleCommand(ctx context.Context, chatID int64, cmd string, args string, username string)
+	HandleMessage(ctx context.Context, chatID int64, text string, username string)
+	HandleCallback(ctx context.Context, callback messenger.Callback)
+	RateLimited(ctx context.Context, chatID int64, callback *messenger.Callback, warn bool)
+}
+
+// Sender identifies who an update came from.
+type Sender struct {
+	// ExternalID is the person's ID on the platform.
+	ExternalID string
+	// Address is where replies go: a DM channel or room ID.
+	Address  string
+	Username string
+}
+
+type Dispatcher struct {
+	platform string
+	handler  Handler
+	db       database.Store
+	allow    func(chatID int64) (ok, warn bool)
+}
+
+// NewDispatcher creates a dispatcher for platform. allow is asked before each
+// update is handled and may drop it, e.g. to rate limit, with warn set when
+// the user should be told; nil allows all.
+func NewDispatcher(platform string, handler Handler, db database.Store, allow func(chatID int64) (ok, warn bool)) *Dispatcher {
+	return &Dispatcher{platform: platform, handler: handler, db: db, allow: allow}
+}
+
+// Message handles text from sender. Text starting with / or ! is a command.
+func (d *Dispatcher) Message(ctx context.Context, from Sender, text string) {
+	chatID, username, ok := d.resolve(ctx, from)
+	if !ok {
+		return
+	}
+	ctx = d.context(ctx, chatID)
+	if !d.allowed(ctx, chatID, nil) {
+		return
+	}
+
+	text = strings.TrimSpace(text)
+	if cmd, args, ok := ParseCommand(text); ok {
+		d.handler.HandleCommand(ctx, chatID, cmd, args, username)
+		return
+	}
+	d.handler.HandleMessage(ctx, chatID, text, username)
+}
+
+// Callback handles a button press. callbackID and messageID are the
+// platform's own IDs.
+func (d *Dispatcher) Callback(ctx context.Context, from Sender, callbackID, messageID, data string) {
+	chatID, _, ok := d.resolve(ctx, from)
+	if !ok {
+		return
+	}
+	ctx = d.context(ctx, chatID)
+	callback := messenger.Callback{
+		ID:        messenger.QualifyID(d.platform, callbackID),
+		ChatID:    chatID,
+		MessageID: messenger.QualifyID(d.platform, messageID),
+		Data:      data,
+	}
+	if !d.allowed(ctx, chatID, &callback) {
+		return
+	}
+
+	d.handler.HandleCallback(ctx, callback)
+}
+
+func (d *Dispatcher) context(ctx context.Context, chatID int64) context.Context {
+	ctx = logging.With(logging.WithCorrelationID(ctx, chatID), "platform", d.platform)
+	return messenger.WithPlatform(ctx, d.platform)
+}
+
+// allowed asks allow about the update and replies to it when it is dropped.
+// callback is the button press being handled, or nil for a message.
+func (d *Dispatcher) allowed(ctx context.Context, chatID int64, callback *messenger.Callback) bool {
+	if d.allow == nil {
+		return true
+	}
+	ok, warn := d.allow(chatID)
+	if ok {
+		return true
+	}
+	logging.FromContext(ctx).Warn("rate limit exceeded, dropping update")
+	d.handler.RateLimited(ctx, chatID, callback, warn)
+	return false
+}
+
+// resolve returns the chat ID of the user behind from, creating an account
+// the first time they write. The username is only taken from the platform
+// the user was created on, so linked accounts don't fight over it.
+func (d *Dispatcher) resolve(ctx context.Context, from Sender) (int64, string, bool) {
+	logger := logging.FromContext(ctx).With("platform", d.platform, "external_id", from.ExternalID)
+
+	account, err := d.db.GetAccount(d.platform, from.ExternalID)
+	if err != nil {
+		logger.Error("failed to look up account", "error", err)
+		return 0, "", false
+	}
+	if account == nil || account.Address != from.Address {
+		if account == nil {
+			account = &models.Account{
+				Platform:   d.platform,
+				ExternalID: from.ExternalID,
+				ChatID:     models.SyntheticChatID(d.platform, from.ExternalID),
+			}
+		}
+		account.Address = from.Address
+		if err := d.db.SaveAccount(account); err != nil {
+			logger.Error("failed to save account", "error", err)
+			return 0, "", false
+		}
+	}
+
+	if account.ChatID == models.SyntheticChatID(d.platform, from.ExternalID) {
+		return account.ChatID, from.Username, true
+	}
+	user, err := d.db.GetUser(account.ChatID)
+	if err != nil {
+		logger.Error("failed to look up linked user", "error", err)
+		return 0, "", false
+	}
+	if user == nil {
+		return account.ChatID, "", true
+	}
+	return account.ChatID, user.Username, true
+}
+
+// ParseCommand splits "/cmd args" or "!cmd args" into its parts. A
+// "@botname" suffix on the command is dropped.
+func ParseCommand(text string) (cmd, args string, ok bool) {
+	if len(text) < 2 || (text[0] != '/' && text[0] != '!') {
+		return "", "", false
+	}
+	cmd, args, _ = strings.Cut(text[1:], " ")
+	cmd, _, _ = strings.Cut(cmd, "@")
+	return strings.ToLower(cmd), strings.TrimSpace(args), cmd != ""
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/academic"
+	"github.com/artem-streltsov/ucl-timetable-bot/logging"
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+	"github.com/artem-streltsov/ucl-timetable-bot/utils"
+)
+
+// attendanceDays is how far back /attendance looks outside term.
+const attendanceDays = 28
+
+// attendance handles /attendance, summarising the user's answers to lecture
+// reminders per module.
+func (h *Handler) attendance(ctx context.Context, user *models.User) {
+	now := time.Now().In(utils.Location())
+	from, period := attendancePeriod(academic.Current(), now)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, utils.Location())
+	acks, err := h.db.GetReminderAcks(user.ChatID, from, today.AddDate(0, 0, 1))
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to get reminder answers", "error", err)
+		h.sendMessage(ctx, user.ChatID, "Error fetching your attendance.")
+		return
+	}
+	h.sendMessage(ctx, user.ChatID, attendanceText(acks, period))
+}
+
+// attendancePeriod returns where /attendance starts counting and how to
+// describe it: the start of the current term, or the last four weeks
+// outside term.
+func attendancePeriod(cal *academic.Calendar, now time.Time) (time.Time, string) {
+	if term := cal.At(now).Term; term != nil {
+		start := time.Date(term.Start.Year(), term.Start.Month(), term.Start.Day(), 0, 0, 0, 0, now.Location())
+		return start, fmt.Sprintf("since %s started on %s", term.Name, start.Format("Mon 02 Jan"))
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return today.AddDate(0, 0, 1-attendanceDays), fmt.Sprintf("over the last %d weeks", attendanceDays/7)
+}
+
+// attendanceCounts tallies the final answer to each lecture of a module.
+type attendanceCounts struct {
+	gotIt, skipped, snoozed int
+}
+
+// attendanceText counts each lecture once, by the last answer given to its
+// reminders, so a lecture snoozed and then marked "Got it" counts as
+// attended. acks must be in the order they were given.
+func attendanceText(acks []models.ReminderAck, period string) string {
+	type lectureKey struct {
+		uid   string
+		start int64
+	}
+	final := make(map[lectureKey]models.ReminderAck)
+	for _, ack := range acks {
+		final[lectureKey{ack.LectureUID, ack.Start.Unix()}] = ack
+	}
+	if len(final) == 0 {
+		return "No answers to lecture reminders " + period + ".\nUse the buttons on your reminders to keep track of your attendance."
+	}
+
+	counts := make(map[string]*attendanceCounts)
+	for _, ack := range final {
+		module := ack.Module
+		if module == "" {
+			module = ack.Title
+		}
+		c := counts[module]
+		if c == nil {
+			c = &attendanceCounts{}
+			counts[module] = c
+		}
+		switch ack.Action {
+		case models.AckGotIt:
+			c.gotIt++
+		case models.AckSkip:
+			c.skipped++
+		case models.AckSnooze:
+			c.snoozed++
+		}
+	}
+	modules := make([]string, 0, len(counts))
+	for module := range counts {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+
+	var sb strings.Builder
+	sb.WriteString("📊 *Attendance* " + period + "\n\n")
+	for _, module := range modules {
+		c := counts[module]
+		fmt.Fprintf(&sb, "*%s*: ✅ %d attended, ⏭ %d skipped", module, c.gotIt, c.skipped)
+		if c.snoozed > 0 {
+			fmt.Fprintf(&sb, ", 💤 %d snoozed", c.snoozed)
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\nEach lecture counts by your last answer to its reminders.")
+	return sb.String()
+}
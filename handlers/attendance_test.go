@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/academic"
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+	"github.com/artem-streltsov/ucl-timetable-bot/utils"
+)
+
+func TestAttendanceText(t *testing.T) {
+	monday := time.Date(2025, time.October, 6, 9, 0, 0, 0, utils.Location())
+	ack := func(uid, module string, start time.Time, action string) models.ReminderAck {
+		return models.ReminderAck{LectureUID: uid, Module: module, Title: module + " Lecture", Start: start, Action: action}
+	}
+	acks := []models.ReminderAck{
+		ack("a", "COMP0002", monday, models.AckGotIt),
+		// Snoozed, then attended: counts once, as attended.
+		ack("b", "COMP0001", monday, models.AckSnooze),
+		ack("b", "COMP0001", monday, models.AckGotIt),
+		// The same lecture a week later is another lecture.
+		ack("b", "COMP0001", monday.AddDate(0, 0, 7), models.AckSkip),
+		ack("c", "COMP0001", monday.AddDate(0, 0, 8), models.AckSnooze),
+		{LectureUID: "d", Title: "Study group", Start: monday, Action: models.AckGotIt},
+	}
+
+	got := attendanceText(acks, "over the last 4 weeks")
+	want := "📊 *Attendance* over the last 4 weeks\n\n" +
+		"*COMP0001*: ✅ 1 attended, ⏭ 1 skipped, 💤 1 snoozed\n" +
+		"*COMP0002*: ✅ 1 attended, ⏭ 0 skipped\n" +
+		"*Study group*: ✅ 1 attended, ⏭ 0 skipped\n" +
+		"\nEach lecture counts by your last answer to its reminders."
+	if got != want {
+		t.Errorf("attendanceText =\n%s\nwant\n%s", got, want)
+	}
+
+	if got := attendanceText(nil, "over the last 4 weeks"); !strings.HasPrefix(got, "No answers to lecture reminders over the last 4 weeks.") {
+		t.Errorf("attendanceText without answers = %q", got)
+	}
+}
+
+func TestAttendancePeriod(t *testing.T) {
+	cal, err := academic.Parse([]byte(`
+years:
+  - name: "2025-26"
+    start: 2025-08-01
+    end: 2026-07-31
+    terms:
+      - name: Term 1
+        start: 2025-09-29
+        end: 2025-12-12
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		now        time.Time
+		wantFrom   time.Time
+		wantPeriod string
+	}{
+		{
+			time.Date(2025, time.October, 15, 12, 0, 0, 0, utils.Location()),
+			time.Date(2025, time.September, 29, 0, 0, 0, 0, utils.Location()),
+			"since Term 1 started on Mon 29 Sep",
+		},
+		{
+			time.Date(2025, time.December, 20, 12, 0, 0, 0, utils.Location()),
+			time.Date(2025, time.November, 23, 0, 0, 0, 0, utils.Location()),
+			"over the last 4 weeks",
+		},
+	}
+	for _, tt := range tests {
+		from, period := attendancePeriod(cal, tt.now)
+		if !from.Equal(tt.wantFrom) || period != tt.wantPeriod {
+			t.Errorf("attendancePeriod(%s) = %s, %q, want %s, %q", tt.now.Format(time.DateOnly), from, period, tt.wantFrom, tt.wantPeriod)
+		}
+	}
+}
+
+func TestAttendanceCommand(t *testing.T) {
+	h, rec, db := newTestHandler(t)
+	ctx := context.Background()
+
+	h.HandleCommand(ctx, alice, "attendance", "", "alice")
+	wantLast(t, rec, alice, "No answers to lecture reminders")
+
+	now := time.Now().In(utils.Location())
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, utils.Location())
+	for _, ack := range []models.ReminderAck{
+		{ChatID: alice, LectureUID: "a", Module: "COMP0001", Title: "COMP0001 Lecture", Start: today, Action: models.AckGotIt, At: now},
+		{ChatID: bob, LectureUID: "a", Module: "COMP0001", Title: "COMP0001 Lecture", Start: today, Action: models.AckSkip, At: now},
+	} {
+		if err := db.AddReminderAck(&ack); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h.HandleCommand(ctx, alice, "attendance", "", "alice")
+	wantLast(t, rec, alice, "*COMP0001*: ✅ 1 attended, ⏭ 0 skipped\n")
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/logging"
+	"github.com/artem-streltsov/ucl-timetable-bot/messenger"
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+	"github.com/artem-streltsov/ucl-timetable-bot/timetable"
+	"github.com/artem-streltsov/ucl-timetable-bot/utils"
+)
+
+const (
+	maxDeadlines = 100
+	// deadlinesShownAfter is how long a passed deadline stays in /deadlines.
+	deadlinesShownAfter = 7 * 24 * time.Hour
+	deadlineUsage       = "Usage: /deadline MODULE DAY [HH:MM] Title, e.g. /deadline COMP0010 2026-11-20 12:00 Coursework 1. DAY can also be a weekday, today or tomorrow; without a time the deadline is at 23:59."
+)
+
+// deadline handles /deadline, adding a deadline for one of the user's
+// modules.
+func (h *Handler) deadline(ctx context.Context, user *models.User, args string) {
+	logger := logging.FromContext(ctx)
+	if args == "" {
+		usage := deadlineUsage
+		if modules := h.userModules(ctx, user); len(modules) > 0 {
+			usage += "\nYour modules: " + strings.Join(modules, ", ")
+		}
+		h.sendMessage(ctx, user.ChatID, usage)
+		return
+	}
+	deadline, problem := parseDeadline(args, time.Now().In(utils.Location()))
+	if problem != "" {
+		h.sendMessage(ctx, user.ChatID, problem+"\n"+deadlineUsage)
+		return
+	}
+
+	deadlines, err := h.db.GetDeadlines(user.ChatID)
+	if err != nil {
+		logger.Error("failed to get deadlines", "error", err)
+		h.sendMessage(ctx, user.ChatID, "Error saving your deadline. Please try again later.")
+		return
+	}
+	if len(deadlines) >= maxDeadlines {
+		h.sendMessage(ctx, user.ChatID, fmt.Sprintf("You can have at most %d deadlines. Delete some with /deadlines first.", maxDeadlines))
+		return
+	}
+
+	deadline.ChatID = user.ChatID
+	if err := h.db.AddDeadline(&deadline); err != nil {
+		logger.Error("failed to add deadline", "error", err)
+		h.sendMessage(ctx, user.ChatID, "Error saving your deadline. Please try again later.")
+		return
+	}
+	h.scheduler.ScheduleUser(ctx, user.ChatID)
+
+	reply := "Deadline added:\n" + timetable.FormatDeadline(deadline, time.Now()) + "\nYou will be reminded 7 days, 1 day and 2 hours before."
+	if modules := h.userModules(ctx, user); len(modules) > 0 && !slices.Contains(modules, deadline.Module) {
+		reply += "\nNote: " + deadline.Module + " is not in your timetable."
+	}
+	h.sendMessage(ctx, user.ChatID, reply)
+}
+
+// userModules returns the module codes in the user's timetable, or nil when
+// it can't be fetched.
+func (h *Handler) userModules(ctx context.Context, user *models.User) []string {
+	lectures, err := timetable.ForUser(h.db, user)
+	if err != nil {
+		logging.FromContext(ctx).Warn("failed to fetch calendar for modules", "error", err)
+		return nil
+	}
+	return timetable.Modules(lectures)
+}
+
+// deadlines handles /deadlines, listing upcoming and recently passed
+// deadlines with countdowns and delete buttons.
+func (h *Handler) deadlines(ctx context.Context, user *models.User) {
+	deadlines, err := h.db.GetDeadlines(user.ChatID)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to get deadlines", "error", err)
+		h.sendMessage(ctx, user.ChatID, "Error fetching your deadlines.")
+		return
+	}
+	text, keyboard := deadlinesText(deadlines, time.Now())
+	h.sendKeyboard(ctx, user.ChatID, text, keyboard)
+}
+
+func (h *Handler) handleDeleteDeadlineCallback(ctx context.Context, callback messenger.Callback) {
+	logger := logging.FromContext(ctx)
+	deadlineID, err := strconv.ParseInt(strings.TrimPrefix(callback.Data, "deadline_del_"), 10, 64)
+	if err != nil {
+		h.answerCallback(ctx, callback, "Invalid callback data.")
+		return
+	}
+	deleted, err := h.db.DeleteDeadline(callback.ChatID, deadlineID)
+	if err != nil {
+		logger.Error("failed to delete deadline", "error", err)
+		h.answerCallback(ctx, callback, "Error deleting the deadline.")
+		return
+	}
+	if deleted {
+		h.scheduler.ScheduleUser(ctx, callback.ChatID)
+		h.answerCallback(ctx, callback, "Deadline deleted.")
+	} else {
+		h.answerCallback(ctx, callback, "That deadline was already deleted.")
+	}
+
+	deadlines, err := h.db.GetDeadlines(callback.ChatID)
+	if err != nil {
+		logger.Error("failed to get deadlines", "error", err)
+		return
+	}
+	text, keyboard := deadlinesText(deadlines, time.Now())
+	if err := h.messenger.EditMessage(ctx, callback.ChatID, callback.MessageID, text, keyboard); err != nil {
+		logger.Error("failed to update deadlines message", "error", err)
+	}
+}
+
+func deadlinesText(deadlines []models.Deadline, now time.Time) (string, messenger.Keyboard) {
+	deadlines = slices.DeleteFunc(deadlines, func(deadline models.Deadline) bool {
+		return deadline.Due.Before(now.Add(-deadlinesShownAfter))
+	})
+	if len(deadlines) == 0 {
+		return "You have no upcoming deadlines. Use /deadline to add one.", nil
+	}
+	var sb strings.Builder
+	sb.WriteString("Your deadlines:\n\n")
+	var keyboard messenger.Keyboard
+	imported := false
+	for i, deadline := range deadlines {
+		fmt.Fprintf(&sb, "%d. %s", i+1, timetable.FormatDeadline(deadline, now))
+		// Imported deadlines would come back on the next sync, so they are
+		// managed in Moodle instead.
+		if deadline.UID != "" {
+			sb.WriteString("From Moodle\n")
+			imported = true
+		} else {
+			keyboard = append(keyboard, []messenger.Button{{
+				Text: fmt.Sprintf("Delete %d", i+1),
+				Data: fmt.Sprintf("deadline_del_%d", deadline.ID),
+			}})
+		}
+		sb.WriteString("\n")
+	}
+	if imported {
+		sb.WriteString("Deadlines from Moodle update automatically. Use /set_moodle off to stop importing them.")
+	}
+	return sb.String(), keyboard
+}
+
+// parseDeadline reads "MODULE DAY [HH:MM] Title", with DAY as accepted by
+// /add_event. It returns a message for the user when the text can't be read.
+func parseDeadline(text string, now time.Time) (models.Deadline, string) {
+	fields := strings.Fields(text)
+	if len(fields) < 3 {
+		return models.Deadline{}, "Please give a module code, a day and a title."
+	}
+	var deadline models.Deadline
+	deadline.Module = strings.ToUpper(fields[0])
+	if timetable.ModuleCode(deadline.Module) != deadline.Module {
+		return models.Deadline{}, fmt.Sprintf("%q is not a module code like COMP0010.", fields[0])
+	}
+
+	day, weekday, problem := parseEventDay(fields[1], now)
+	if problem != "" {
+		return models.Deadline{}, problem
+	}
+	clock := time.Date(0, 1, 1, 23, 59, 0, 0, time.UTC)
+	titleFields := fields[2:]
+	if parsed, err := time.Parse("15:04", fields[2]); err == nil {
+		clock = parsed
+		titleFields = fields[3:]
+	}
+	deadline.Due = time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+	if weekday && !deadline.Due.After(now) {
+		deadline.Due = deadline.Due.AddDate(0, 0, 7)
+	}
+	if !deadline.Due.After(now) {
+		return models.Deadline{}, "That time has already passed."
+	}
+
+	deadline.Title = strings.Join(titleFields, " ")
+	if deadline.Title == "" {
+		return models.Deadline{}, "Please give the deadline a title."
+	}
+	if len(deadline.Title) > maxEventTitleLength {
+		return models.Deadline{}, fmt.Sprintf("The title must be at most %d characters.", maxEventTitleLength)
+	}
+	return deadline, ""
+}
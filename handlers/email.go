@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/email"
+	"github.com/artem-streltsov/ucl-timetable-bot/logging"
+	"github.com/artem-streltsov/ucl-timetable-bot/messenger"
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+)
+
+const (
+	emailCodeTTL         = 15 * time.Minute
+	maxEmailCodeAttempts = 5
+	// emailSendTimeout bounds sending a verification code.
+	emailSendTimeout = 30 * time.Second
+)
+
+type emailVerification struct {
+	address  string
+	code     string
+	expires  time.Time
+	attempts int
+}
+
+// deliveryTypes lists the notifications whose channel can be chosen, in the
+// order they are shown.
+var deliveryTypes = []struct {
+	key   string
+	label string
+	field func(user *models.User) *string
+}{
+	{"daily", "Daily summary", func(user *models.User) *string { return &user.DailyChannel }},
+	{"weekly", "Weekly summary", func(user *models.User) *string { return &user.WeeklyChannel }},
+	{"reminder", "Lecture reminders", func(user *models.User) *string { return &user.ReminderChannel }},
+}
+
+func (h *Handler) startSetEmail(ctx context.Context, chatID int64) {
+	if h.mailer == nil {
+		h.sendMessage(ctx, chatID, "Email delivery is not available.")
+		return
+	}
+	h.updateUserState(chatID, "set_email")
+	h.sendMessage(ctx, chatID, "Send your email address. Example: name@ucl.ac.uk")
+}
+
+func (h *Handler) handleSetEmail(ctx context.Context, user *models.User, text string) {
+	text = strings.TrimSpace(text)
+	addr, err := mail.ParseAddress(text)
+	if err != nil || addr.Address != text {
+		h.sendMessage(ctx, user.ChatID, "Invalid email address. Example: name@ucl.ac.uk")
+		return
+	}
+
+	code, err := newEmailCode()
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to generate verification code", "error", err)
+		h.sendMessage(ctx, user.ChatID, "Error sending verification email. Please try again later.")
+		return
+	}
+	msg, err := email.VerificationCode(addr.Address, code)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to build verification email", "error", err)
+		h.sendMessage(ctx, user.ChatID, "Error sending verification email. Please try again later.")
+		return
+	}
+
+	pending := &emailVerification{
+		address: addr.Address,
+		code:    code,
+		expires: time.Now().Add(emailCodeTTL),
+	}
+	h.mu.Lock()
+	h.emailVerifications[user.ChatID] = pending
+	h.mu.Unlock()
+	h.updateUserState(user.ChatID, "verify_email")
+	h.sendMessage(ctx, user.ChatID, fmt.Sprintf("Sending a 6-digit code to %s. Send it here to confirm your email.", addr.Address))
+
+	// A slow mail server must not hold up the update loop, so the code is
+	// sent in the background and the user is told if it fails.
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), emailSendTimeout)
+		defer cancel()
+		if err := h.mailer.Send(ctx, msg); err != nil {
+			logging.FromContext(ctx).Error("failed to send verification email", "error", err)
+			h.cancelEmailVerification(user.ChatID, pending)
+			h.sendMessage(ctx, user.ChatID, "Error sending verification email. Please check the address and use /set_email to try again.")
+		}
+	}()
+}
+
+// cancelEmailVerification forgets pending and leaves the verify_email state,
+// unless the user has started another verification since.
+func (h *Handler) cancelEmailVerification(chatID int64, pending *emailVerification) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.emailVerifications[chatID] != pending {
+		return
+	}
+	delete(h.emailVerifications, chatID)
+	if h.userStates[chatID] == "verify_email" {
+		delete(h.userStates, chatID)
+	}
+}
+
+func (h *Handler) handleVerifyEmail(ctx context.Context, user *models.User, text string) {
+	h.mu.Lock()
+	pending, ok := h.emailVerifications[user.ChatID]
+	expired := !ok || time.Now().After(pending.expires) || pending.attempts >= maxEmailCodeAttempts
+	matched := false
+	if !expired {
+		pending.attempts++
+		matched = strings.TrimSpace(text) == pending.code
+	}
+	if expired || matched {
+		delete(h.emailVerifications, user.ChatID)
+	}
+	h.mu.Unlock()
+
+	if expired {
+		h.clearUserState(user.ChatID)
+		h.sendMessage(ctx, user.ChatID, "Your verification code has expired. Use /set_email to get a new one.")
+		return
+	}
+	if !matched {
+		h.sendMessage(ctx, user.ChatID, "Incorrect code. Please try again.")
+		return
+	}
+
+	user.Email = pending.address
+	user.EmailVerified = true
+	if !h.saveUser(ctx, user) {
+		return
+	}
+	h.clearUserState(user.ChatID)
+	h.sendMessage(ctx, user.ChatID, "Email verified. Use /delivery to choose which notifications are sent by email.")
+}
+
+func (h *Handler) delivery(ctx context.Context, user *models.User) {
+	h.sendKeyboard(ctx, user.ChatID, deliveryText(user), deliveryKeyboard(user))
+}
+
+func (h *Handler) handleDeliveryCallback(ctx context.Context, callback messenger.Callback) {
+	key := strings.TrimPrefix(callback.Data, "delivery_")
+	user, err := h.db.GetUser(callback.ChatID)
+	if err != nil || user == nil {
+		if err != nil {
+			logging.FromContext(ctx).Error("failed to get user", "error", err)
+		}
+		h.answerCallback(ctx, callback, "Error fetching your data.")
+		return
+	}
+
+	for _, deliveryType := range deliveryTypes {
+		if deliveryType.key != key {
+			continue
+		}
+		channel := deliveryType.field(user)
+		next := nextChannel(*channel)
+		if models.WantsEmail(next) && (h.mailer == nil || !user.EmailVerified) {
+			h.answerCallback(ctx, callback, "Verify an email address with /set_email first.")
+			return
+		}
+		*channel = next
+		if err := h.db.SaveUser(user); err != nil {
+			logging.FromContext(ctx).Error("failed to save user", "error", err)
+			h.answerCallback(ctx, callback, "Error saving your settings.")
+			return
+		}
+		h.scheduler.ScheduleUser(ctx, user.ChatID)
+		h.answerCallback(ctx, callback, "")
+		if err := h.messenger.EditMessage(ctx, user.ChatID, callback.MessageID, deliveryText(user), deliveryKeyboard(user)); err != nil {
+			logging.FromContext(ctx).Error("failed to update delivery settings message", "error", err)
+		}
+		return
+	}
+	h.answerCallback(ctx, callback, "Invalid callback data.")
+}
+
+func deliveryText(user *models.User) string {
+	text := "Choose where each notification is sent. Tap a button to switch between chat, email and both."
+	if user.EmailVerified {
+		return text + "\nEmail: " + user.Email
+	}
+	return text + "\nUse /set_email to add an email address."
+}
+
+func deliveryKeyboard(user *models.User) messenger.Keyboard {
+	var keyboard messenger.Keyboard
+	for _, deliveryType := range deliveryTypes {
+		label := fmt.Sprintf("%s: %s", deliveryType.label, channelName(*deliveryType.field(user)))
+		keyboard = append(keyboard, []messenger.Button{{Text: label, Data: "delivery_" + deliveryType.key}})
+	}
+	return keyboard
+}
+
+func nextChannel(channel string) string {
+	switch channel {
+	case models.ChannelEmail:
+		return models.ChannelBoth
+	case models.ChannelBoth:
+		return models.ChannelTelegram
+	default:
+		return models.ChannelEmail
+	}
+}
+
+func channelName(channel string) string {
+	switch channel {
+	case models.ChannelEmail:
+		return "Email"
+	case models.ChannelBoth:
+		return "Chat + Email"
+	default:
+		return "Chat"
+	}
+}
+
+func newEmailCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/email"
+	"github.com/artem-streltsov/ucl-timetable-bot/messenger"
+)
+
+// blockingMailer hands each message to the test and waits for the test to
+// say whether sending succeeded.
+type blockingMailer struct {
+	sent   chan email.Message
+	result chan error
+}
+
+func (m *blockingMailer) Send(ctx context.Context, msg email.Message) error {
+	m.sent <- msg
+	select {
+	case err := <-m.result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// waitForLast waits for a message containing substr to be the last one sent
+// to chatID.
+func waitForLast(t *testing.T, rec *messenger.Recorder, chatID int64, substr string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if msg, ok := rec.Last(chatID); ok && strings.Contains(msg.Text, substr) {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	wantLast(t, rec, chatID, substr)
+}
+
+func TestSetEmailSendsCodeInBackground(t *testing.T) {
+	h, rec, db := newTestHandler(t)
+	mailer := &blockingMailer{sent: make(chan email.Message), result: make(chan error)}
+	h.mailer = mailer
+	ctx := context.Background()
+
+	h.HandleCommand(ctx, alice, "set_email", "", "alice")
+	// The handler must return while the mail server is still busy.
+	h.HandleMessage(ctx, alice, "alice@ucl.ac.uk", "alice")
+	wantLast(t, rec, alice, "Sending a 6-digit code to alice@ucl.ac.uk")
+
+	msg := <-mailer.sent
+	mailer.result <- nil
+	code := regexp.MustCompile(`\d{6}`).FindString(msg.Text)
+	if msg.To != "alice@ucl.ac.uk" || code == "" {
+		t.Fatalf("verification email = %+v, want a code to alice@ucl.ac.uk", msg)
+	}
+
+	h.HandleMessage(ctx, alice, code, "alice")
+	wantLast(t, rec, alice, "Email verified.")
+	if user, _ := db.GetUser(alice); !user.EmailVerified || user.Email != "alice@ucl.ac.uk" {
+		t.Errorf("user after verifying = %+v", user)
+	}
+}
+
+func TestSetEmailSendFailure(t *testing.T) {
+	h, rec, db := newTestHandler(t)
+	mailer := &blockingMailer{sent: make(chan email.Message), result: make(chan error)}
+	h.mailer = mailer
+	ctx := context.Background()
+
+	h.HandleCommand(ctx, alice, "set_email", "", "alice")
+	h.HandleMessage(ctx, alice, "alice@ucl.ac.uk", "alice")
+	msg := <-mailer.sent
+	mailer.result <- errors.New("connection refused")
+	waitForLast(t, rec, alice, "Error sending verification email.")
+
+	// The failed code is forgotten and the conversation is over.
+	code := regexp.MustCompile(`\d{6}`).FindString(msg.Text)
+	h.HandleMessage(ctx, alice, code, "alice")
+	wantLast(t, rec, alice, "Please use commands from the menu")
+	if user, _ := db.GetUser(alice); user.EmailVerified {
+		t.Error("email verified with the code of a failed send")
+	}
+}
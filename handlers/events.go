@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/logging"
+	"github.com/artem-streltsov/ucl-timetable-bot/messenger"
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+	"github.com/artem-streltsov/ucl-timetable-bot/utils"
+)
+
+const (
+	maxEvents           = 50
+	maxEventTitleLength = 100
+	addEventExamples    = "Examples:\nFri 14:00-15:00 Gym @ Bloomsbury Fitness\nevery Mon 09:00-10:00 Study group @ Library\n2026-11-20 12:00 Hand in coursework\ntomorrow 18:30 Call home"
+)
+
+// addEvent handles /add_event. The event can follow the command or come in
+// the next message.
+func (h *Handler) addEvent(ctx context.Context, user *models.User, args string) {
+	if args == "" {
+		h.updateUserState(user.ChatID, "add_event")
+		h.sendMessage(ctx, user.ChatID, "Send the event as: [every] DAY START[-END] Title [@ Location]\n"+addEventExamples)
+		return
+	}
+	h.handleAddEvent(ctx, user, args)
+}
+
+func (h *Handler) handleAddEvent(ctx context.Context, user *models.User, text string) {
+	logger := logging.FromContext(ctx)
+	event, problem := parseEvent(text, time.Now().In(utils.Location()))
+	if problem != "" {
+		h.sendMessage(ctx, user.ChatID, problem+"\n"+addEventExamples)
+		return
+	}
+
+	events, err := h.db.GetEvents(user.ChatID)
+	if err != nil {
+		logger.Error("failed to get events", "error", err)
+		h.sendMessage(ctx, user.ChatID, "Error saving your event. Please try again later.")
+		return
+	}
+	if len(events) >= maxEvents {
+		h.clearUserState(user.ChatID)
+		h.sendMessage(ctx, user.ChatID, fmt.Sprintf("You can have at most %d events. Delete some with /events first.", maxEvents))
+		return
+	}
+
+	event.ChatID = user.ChatID
+	if err := h.db.AddEvent(&event); err != nil {
+		logger.Error("failed to add event", "error", err)
+		h.sendMessage(ctx, user.ChatID, "Error saving your event. Please try again later.")
+		return
+	}
+	h.clearUserState(user.ChatID)
+	h.scheduler.ScheduleUser(ctx, user.ChatID)
+	h.sendMessage(ctx, user.ChatID, "Event added: "+describeEvent(event)+"\nIt will appear in your timetable and get reminders like a lecture. Use /events to see or delete your events.")
+}
+
+// events handles /events, listing the user's events with delete buttons.
+func (h *Handler) events(ctx context.Context, user *models.User) {
+	events, err := h.db.GetEvents(user.ChatID)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to get events", "error", err)
+		h.sendMessage(ctx, user.ChatID, "Error fetching your events.")
+		return
+	}
+	text, keyboard := eventsText(events)
+	h.sendKeyboard(ctx, user.ChatID, text, keyboard)
+}
+
+func (h *Handler) handleDeleteEventCallback(ctx context.Context, callback messenger.Callback) {
+	logger := logging.FromContext(ctx)
+	eventID, err := strconv.ParseInt(strings.TrimPrefix(callback.Data, "event_del_"), 10, 64)
+	if err != nil {
+		h.answerCallback(ctx, callback, "Invalid callback data.")
+		return
+	}
+	deleted, err := h.db.DeleteEvent(callback.ChatID, eventID)
+	if err != nil {
+		logger.Error("failed to delete event", "error", err)
+		h.answerCallback(ctx, callback, "Error deleting the event.")
+		return
+	}
+	if deleted {
+		h.scheduler.ScheduleUser(ctx, callback.ChatID)
+		h.answerCallback(ctx, callback, "Event deleted.")
+	} else {
+		h.answerCallback(ctx, callback, "That event was already deleted.")
+	}
+
+	events, err := h.db.GetEvents(callback.ChatID)
+	if err != nil {
+		logger.Error("failed to get events", "error", err)
+		return
+	}
+	text, keyboard := eventsText(events)
+	if err := h.messenger.EditMessage(ctx, callback.ChatID, callback.MessageID, text, keyboard); err != nil {
+		logger.Error("failed to update events message", "error", err)
+	}
+}
+
+func eventsText(events []models.Event) (string, messenger.Keyboard) {
+	if len(events) == 0 {
+		return "You have no events. Use /add_event to add one.", nil
+	}
+	var sb strings.Builder
+	sb.WriteString("Your events:")
+	var keyboard messenger.Keyboard
+	for i, event := range events {
+		fmt.Fprintf(&sb, "\n%d. %s", i+1, describeEvent(event))
+		keyboard = append(keyboard, []messenger.Button{{
+			Text: fmt.Sprintf("Delete %d", i+1),
+			Data: fmt.Sprintf("event_del_%d", event.ID),
+		}})
+	}
+	return sb.String(), keyboard
+}
+
+func describeEvent(event models.Event) string {
+	start := event.Start.In(utils.Location())
+	end := event.End.In(utils.Location())
+
+	var sb strings.Builder
+	if event.Weekly {
+		sb.WriteString("Every " + start.Format("Mon"))
+	} else {
+		sb.WriteString(start.Format("Mon 02 Jan"))
+	}
+	sb.WriteString(" " + start.Format("15:04"))
+	if end.After(start) {
+		sb.WriteString("-" + end.Format("15:04"))
+	}
+	sb.WriteString(" " + event.Title)
+	if event.Location != "" {
+		sb.WriteString(" @ " + event.Location)
+	}
+	return sb.String()
+}
+
+// parseEvent reads "[every] DAY START[-END] Title [@ Location]". DAY is a
+// weekday, today, tomorrow or a YYYY-MM-DD date. A weekday means its next
+// occurrence that has not started yet. It returns a message for the user when
+// the text can't be read.
+func parseEvent(text string, now time.Time) (models.Event, string) {
+	fields := strings.Fields(text)
+	var event models.Event
+	if len(fields) > 0 && (strings.EqualFold(fields[0], "every") || strings.EqualFold(fields[0], "weekly")) {
+		event.Weekly = true
+		fields = fields[1:]
+	}
+	if len(fields) < 3 {
+		return models.Event{}, "Please give a day, a time and a title."
+	}
+
+	startClock, endClock, hasEnd := strings.Cut(fields[1], "-")
+	startTime, err := time.Parse("15:04", startClock)
+	if err != nil {
+		return models.Event{}, fmt.Sprintf("Invalid time %q. Use HH:MM or HH:MM-HH:MM.", fields[1])
+	}
+	endTime := startTime
+	if hasEnd {
+		endTime, err = time.Parse("15:04", endClock)
+		if err != nil || !endTime.After(startTime) {
+			return models.Event{}, fmt.Sprintf("Invalid time %q. The end must be after the start on the same day.", fields[1])
+		}
+	}
+
+	day, weekday, problem := parseEventDay(fields[0], now)
+	if problem != "" {
+		return models.Event{}, problem
+	}
+	at := func(day time.Time, clock time.Time) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+	}
+	event.Start = at(day, startTime)
+	if weekday && event.Start.Before(now) {
+		day = day.AddDate(0, 0, 7)
+		event.Start = at(day, startTime)
+	}
+	if event.Start.Before(now) && !event.Weekly {
+		return models.Event{}, "That time has already passed."
+	}
+	event.End = at(day, endTime)
+
+	title, location, _ := strings.Cut(strings.Join(fields[2:], " "), "@")
+	event.Title = strings.TrimSpace(title)
+	event.Location = strings.TrimSpace(location)
+	if event.Title == "" {
+		return models.Event{}, "Please give the event a title."
+	}
+	if len(event.Title) > maxEventTitleLength || len(event.Location) > maxEventTitleLength {
+		return models.Event{}, fmt.Sprintf("The title and location must be at most %d characters each.", maxEventTitleLength)
+	}
+	return event, ""
+}
+
+// parseEventDay returns the date named by s on or after today's date, and
+// whether s was a weekday name.
+func parseEventDay(s string, now time.Time) (time.Time, bool, string) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch strings.ToLower(s) {
+	case "today":
+		return today, false, ""
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), false, ""
+	}
+	if weekday, ok := utils.ParseWeekday(s); ok {
+		return today.AddDate(0, 0, (int(weekday)-int(today.Weekday())+7)%7), true, ""
+	}
+	if date, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		return date, false, ""
+	}
+	return time.Time{}, false, fmt.Sprintf("Invalid day %q. Use a weekday, today, tomorrow or YYYY-MM-DD.", s)
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+	"github.com/artem-streltsov/ucl-timetable-bot/utils"
+)
+
+func TestParseEvent(t *testing.T) {
+	// A Wednesday lunchtime.
+	now := time.Date(2025, time.October, 15, 12, 0, 0, 0, utils.Location())
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2025, time.October, day, hour, minute, 0, 0, utils.Location())
+	}
+	tests := []struct {
+		text    string
+		want    models.Event
+		problem string // a substring of the message for invalid text
+	}{
+		{text: "Fri 14:00-15:00 Gym @ Bloomsbury Fitness",
+			want: models.Event{Title: "Gym", Location: "Bloomsbury Fitness", Start: at(17, 14, 0), End: at(17, 15, 0)}},
+		{text: "every Mon 09:00 Study group",
+			want: models.Event{Title: "Study group", Start: at(20, 9, 0), End: at(20, 9, 0), Weekly: true}},
+		{text: "WEEKLY friday 18:30-20:00 Choir @ Bloomsbury Theatre",
+			want: models.Event{Title: "Choir", Location: "Bloomsbury Theatre", Start: at(17, 18, 30), End: at(17, 20, 0), Weekly: true}},
+		// Today's weekday means today until the start has passed.
+		{text: "wed 13:00 Office hours",
+			want: models.Event{Title: "Office hours", Start: at(15, 13, 0), End: at(15, 13, 0)}},
+		{text: "wed 11:00 Office hours",
+			want: models.Event{Title: "Office hours", Start: at(22, 11, 0), End: at(22, 11, 0)}},
+		{text: "tomorrow 08:30 Breakfast @ Print Room Cafe",
+			want: models.Event{Title: "Breakfast", Location: "Print Room Cafe", Start: at(16, 8, 30), End: at(16, 8, 30)}},
+		{text: "2025-11-03 18:00-20:00 Society social @ Students' Union",
+			want: models.Event{Title: "Society social", Location: "Students' Union", Start: time.Date(2025, time.November, 3, 18, 0, 0, 0, utils.Location()), End: time.Date(2025, time.November, 3, 20, 0, 0, 0, utils.Location())}},
+
+		{text: "today 11:00 Missed it", problem: "already passed"},
+		{text: "2025-10-01 11:00 Missed it", problem: "already passed"},
+		{text: "Fri 15:00-14:00 Gym", problem: "end must be after the start"},
+		{text: "Fri 14:00-14:00 Gym", problem: "end must be after the start"},
+		{text: "Fri 14:00-1500 Gym", problem: "end must be after the start"},
+		{text: "Fri 25:00 Gym", problem: "Invalid time"},
+		{text: "Fri 2pm Gym", problem: "Invalid time"},
+		{text: "Someday 14:00 Gym", problem: "Invalid day"},
+		{text: "Fri 14:00", problem: "a day, a time and a title"},
+		{text: "every Fri 14:00", problem: "a day, a time and a title"},
+		{text: "", problem: "a day, a time and a title"},
+		{text: "Fri 14:00 @ Gym", problem: "give the event a title"},
+		{text: "Fri 14:00 " + strings.Repeat("x", maxEventTitleLength+1), problem: "at most"},
+	}
+	for _, tt := range tests {
+		got, problem := parseEvent(tt.text, now)
+		if tt.problem != "" {
+			if !strings.Contains(problem, tt.problem) {
+				t.Errorf("parseEvent(%q) problem = %q, want it to mention %q", tt.text, problem, tt.problem)
+			}
+			continue
+		}
+		if problem != "" {
+			t.Errorf("parseEvent(%q) problem = %q", tt.text, problem)
+			continue
+		}
+		if got.Title != tt.want.Title || got.Location != tt.want.Location || got.Weekly != tt.want.Weekly ||
+			!got.Start.Equal(tt.want.Start) || !got.End.Equal(tt.want.End) {
+			t.Errorf("parseEvent(%q) = %+v, want %+v", tt.text, got, tt.want)
+		}
+	}
+}
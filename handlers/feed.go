@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/feed"
+	"github.com/artem-streltsov/ucl-timetable-bot/logging"
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+	"github.com/artem-streltsov/ucl-timetable-bot/timetable"
+)
+
+// calendarFeed handles /feed, creating the user's feed token on first use.
+// "/feed reset" replaces the token so the old URL stops working.
+func (h *Handler) calendarFeed(ctx context.Context, user *models.User, args string) {
+	if h.cfg.PublicURL == "" {
+		h.sendMessage(ctx, user.ChatID, "The calendar feed is not available.")
+		return
+	}
+	reset := args == "reset"
+	if args != "" && !reset {
+		h.sendMessage(ctx, user.ChatID, "Usage: /feed, or /feed reset to get a new link and disable the old one.")
+		return
+	}
+
+	if user.FeedToken == "" || reset {
+		token, err := feed.NewToken()
+		if err != nil {
+			logging.FromContext(ctx).Error("failed to generate feed token", "error", err)
+			h.sendMessage(ctx, user.ChatID, "Error creating your calendar feed. Please try again later.")
+			return
+		}
+		user.FeedToken = token
+		if !h.saveUser(ctx, user) {
+			return
+		}
+	}
+
+	msg := "Subscribe to this link in Google Calendar, Apple Calendar or Outlook to see your timetable with tidied titles:\n" +
+		feed.URL(h.cfg.PublicURL, user.FeedToken) +
+		"\nKeep it private: anyone with the link can see your timetable. Use /feed reset to replace it."
+	if !timetable.HasTimetable(h.db, user) {
+		msg += "\nThe feed stays empty until you use /set_calendar."
+	}
+	h.sendMessage(ctx, user.ChatID, msg)
+}
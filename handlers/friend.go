@@ -1,101 +1,155 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"strings"
 
+	"github.com/artem-streltsov/ucl-timetable-bot/logging"
+	"github.com/artem-streltsov/ucl-timetable-bot/messenger"
 	"github.com/artem-streltsov/ucl-timetable-bot/models"
-
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
-func (h *Handler) handleAddFriend(user *models.User, text string) {
+func (h *Handler) handleAddFriend(ctx context.Context, user *models.User, text string) {
 	if !strings.HasPrefix(text, "@") || len(text) < 2 {
-		h.sendMessage(user.ChatID, "Invalid username format. Please provide a valid Telegram username (e.g., @username).")
+		h.sendMessage(ctx, user.ChatID, "Invalid username format. Please provide a valid username (e.g., @username).")
 		return
 	}
 
+	logger := logging.FromContext(ctx)
 	friendUsername := strings.TrimPrefix(text, "@")
-	friend, err := h.db.GetUserByUsername(friendUsername)
+	friend, err := h.db.GetTelegramUserByUsername(friendUsername)
 	if err != nil {
-		h.sendMessage(user.ChatID, "Error accessing the database. Please try again later.")
+		logger.Error("failed to look up user by username", "username", friendUsername, "error", err)
+		h.sendMessage(ctx, user.ChatID, "Error accessing the database. Please try again later.")
 		return
 	}
 	if friend == nil {
-		h.sendMessage(user.ChatID, "User not found. Please ensure the user has started the bot and their username is correct.")
+		h.sendMessage(ctx, user.ChatID, "User not found. Please ensure the user has started the bot and their username is correct.")
 		return
 	}
 	if friend.ChatID == user.ChatID {
-		h.sendMessage(user.ChatID, "You cannot add yourself as a friend.")
+		h.sendMessage(ctx, user.ChatID, "You cannot add yourself as a friend.")
 		return
 	}
 
 	areFriends, err := h.db.AreFriends(user.ChatID, friend.ChatID)
 	if err != nil {
-		h.sendMessage(user.ChatID, "Error checking friendship status.")
+		logger.Error("failed to check friendship", "friend_id", friend.ChatID, "error", err)
+		h.sendMessage(ctx, user.ChatID, "Error checking friendship status.")
 		return
 	}
 	if areFriends {
-		h.sendMessage(user.ChatID, "You are already friends with this user.")
+		h.sendMessage(ctx, user.ChatID, "You are already friends with this user.")
 		return
 	}
 
 	requestExists, err := h.db.FriendRequestExists(user.ChatID, friend.ChatID)
 	if err != nil {
-		h.sendMessage(user.ChatID, "Error checking existing friend requests.")
+		logger.Error("failed to check friend request", "friend_id", friend.ChatID, "error", err)
+		h.sendMessage(ctx, user.ChatID, "Error checking existing friend requests.")
 		return
 	}
 	if requestExists {
-		h.sendMessage(user.ChatID, "You have already sent a friend request to this user.")
+		h.sendMessage(ctx, user.ChatID, "You have already sent a friend request to this user.")
 		return
 	}
 
 	err = h.db.AddFriendRequest(user.ChatID, friend.ChatID)
 	if err != nil {
-		h.sendMessage(user.ChatID, "Error sending friend request.")
+		logger.Error("failed to add friend request", "friend_id", friend.ChatID, "error", err)
+		h.sendMessage(ctx, user.ChatID, "Error sending friend request.")
 		return
 	}
 
-	h.sendMessage(user.ChatID, "Request sent. Your friend now needs to add you with the /accept_friend command.")
+	h.sendMessage(ctx, user.ChatID, "Request sent. Your friend now needs to add you with the /accept_friend command.")
 	h.clearUserState(user.ChatID)
 
-	h.sendMessage(friend.ChatID, fmt.Sprintf("@%s has sent you a friend request. Use /accept_friend to accept.", user.Username))
+	h.sendMessage(ctx, friend.ChatID, fmt.Sprintf("@%s has sent you a friend request. Use /accept_friend to accept.", user.Username))
 }
 
-func (h *Handler) handleAcceptFriend(user *models.User) {
+func (h *Handler) handleAcceptFriend(ctx context.Context, user *models.User) {
+	logger := logging.FromContext(ctx)
 	requestorIDs, err := h.db.GetPendingFriendRequests(user.ChatID)
 	if err != nil {
-		h.sendMessage(user.ChatID, "Error fetching friend requests.")
+		logger.Error("failed to get pending friend requests", "error", err)
+		h.sendMessage(ctx, user.ChatID, "Error fetching friend requests.")
 		return
 	}
 
 	if len(requestorIDs) == 0 {
-		h.sendMessage(user.ChatID, "You have no pending friend requests.")
+		h.sendMessage(ctx, user.ChatID, "You have no pending friend requests.")
 		h.clearUserState(user.ChatID)
 		return
 	}
 
-	var buttons [][]tgbotapi.InlineKeyboardButton
+	var keyboard messenger.Keyboard
 	for _, requestorID := range requestorIDs {
 		requestor, err := h.db.GetUser(requestorID)
-		if err != nil || requestor == nil {
+		if err != nil {
+			logger.Error("failed to get requestor", "requestor_id", requestorID, "error", err)
+			continue
+		}
+		if requestor == nil {
 			continue
 		}
 		requestorUsername := requestor.Username
 
 		callbackData := fmt.Sprintf("accept_%d", requestorID)
-		button := tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("@%s", requestorUsername), callbackData)
-		buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(button))
+		button := messenger.Button{Text: fmt.Sprintf("@%s", requestorUsername), Data: callbackData}
+		keyboard = append(keyboard, []messenger.Button{button})
 	}
 
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(buttons...)
-	msg := tgbotapi.NewMessage(user.ChatID, "Pending Friend Requests:")
-	msg.ReplyMarkup = keyboard
+	h.sendKeyboard(ctx, user.ChatID, "Pending Friend Requests:", keyboard)
+
+	h.clearUserState(user.ChatID)
+}
+
+func (h *Handler) handleAcceptFriendCallback(ctx context.Context, callback messenger.Callback) {
+	data := callback.Data
+	chatID := callback.ChatID
+	logger := logging.FromContext(ctx)
 
-	if _, err := h.api.Send(msg); err != nil {
-		log.Printf("Error sending friend requests: %v", err)
+	h.answerCallback(ctx, callback, "")
+
+	parts := strings.Split(data, "_")
+	if len(parts) != 2 {
+		h.sendMessage(ctx, chatID, "Invalid callback data.")
+		return
+	}
+	var requestorID int64
+	_, err := fmt.Sscanf(parts[1], "%d", &requestorID)
+	if err != nil {
+		logger.Warn("invalid requestor ID in callback", "data", data, "error", err)
+		h.sendMessage(ctx, chatID, "Invalid requestor ID.")
+		return
 	}
 
-	h.clearUserState(user.ChatID)
+	requestor, err := h.db.GetUser(requestorID)
+	if err != nil {
+		logger.Error("failed to get requestor", "requestor_id", requestorID, "error", err)
+	}
+	if err != nil || requestor == nil {
+		h.sendMessage(ctx, chatID, "Requestor user not found.")
+		return
+	}
+
+	currentUser, err := h.db.GetUser(chatID)
+	if err != nil {
+		logger.Error("failed to get user", "error", err)
+	}
+	if err != nil || currentUser == nil {
+		h.sendMessage(ctx, chatID, "Error fetching your data.")
+		return
+	}
+
+	err = h.db.AcceptFriendRequest(requestorID, chatID)
+	if err != nil {
+		logger.Error("failed to accept friend request", "requestor_id", requestorID, "error", err)
+		h.sendMessage(ctx, chatID, "Error accepting friend request.")
+		return
+	}
+
+	h.sendMessage(ctx, currentUser.ChatID, fmt.Sprintf("You are now friends with @%s!", requestor.Username))
+	h.sendMessage(ctx, requestor.ChatID, fmt.Sprintf("@%s has accepted your friend request!", currentUser.Username))
 }
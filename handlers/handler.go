@@ -1,137 +1,258 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"strconv"
 	"strings"
 	"sync"
-	"time"
 
+	"github.com/artem-streltsov/ucl-timetable-bot/config"
 	"github.com/artem-streltsov/ucl-timetable-bot/database"
+	"github.com/artem-streltsov/ucl-timetable-bot/email"
+	"github.com/artem-streltsov/ucl-timetable-bot/logging"
+	"github.com/artem-streltsov/ucl-timetable-bot/messenger"
+	"github.com/artem-streltsov/ucl-timetable-bot/metrics"
 	"github.com/artem-streltsov/ucl-timetable-bot/models"
 	"github.com/artem-streltsov/ucl-timetable-bot/scheduler"
-	"github.com/artem-streltsov/ucl-timetable-bot/utils"
-
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-)
-
-var (
-	defaultDailyTime      = "07:00"
-	defaultWeeklyTime     = "SUN 18:00"
-	defaultReminderOffset = "15"
-	ukLocation, _         = time.LoadLocation("Europe/London")
 )
 
 type Handler struct {
-	api        *tgbotapi.BotAPI
-	db         *database.DB
-	scheduler  *scheduler.Scheduler
-	userStates map[int64]string
-	mu         sync.RWMutex
+	messenger          messenger.Messenger
+	mailer             email.Sender
+	db                 database.Store
+	scheduler          *scheduler.Scheduler
+	cfg                *config.Config
+	userStates         map[int64]string
+	emailVerifications map[int64]*emailVerification
+	linkCodes          map[string]*linkCode
+	mu                 sync.RWMutex
 }
 
-func NewHandler(api *tgbotapi.BotAPI, db *database.DB, scheduler *scheduler.Scheduler) *Handler {
+// NewHandler creates a handler. mailer may be nil when email delivery is not
+// configured.
+func NewHandler(m messenger.Messenger, mailer email.Sender, db database.Store, scheduler *scheduler.Scheduler, cfg *config.Config) *Handler {
 	return &Handler{
-		api:        api,
-		db:         db,
-		scheduler:  scheduler,
-		userStates: make(map[int64]string),
+		messenger:          m,
+		mailer:             mailer,
+		db:                 db,
+		scheduler:          scheduler,
+		cfg:                cfg,
+		userStates:         make(map[int64]string),
+		emailVerifications: make(map[int64]*emailVerification),
+		linkCodes:          make(map[string]*linkCode),
 	}
 }
 
-func (h *Handler) registerUser(chatID int64, username string) (*models.User, error) {
+func (h *Handler) registerUser(ctx context.Context, chatID int64, username string) (*models.User, error) {
 	user, err := h.db.GetUser(chatID)
 	if err != nil {
-		h.sendMessage(chatID, fmt.Sprintf("Error: %v", err))
 		return nil, err
 	}
 	if user == nil {
+		offset, err := strconv.Atoi(h.cfg.DefaultReminderOffset)
+		if err != nil {
+			return nil, fmt.Errorf("default reminder offset: %w", err)
+		}
 		user = &models.User{
-			ChatID:         chatID,
-			Username:       username,
-			DailyTime:      defaultDailyTime,
-			WeeklyTime:     defaultWeeklyTime,
-			ReminderOffset: defaultReminderOffset,
+			ChatID:          chatID,
+			Username:        username,
+			DailyTime:       h.cfg.DefaultDailyTime,
+			WeeklyTime:      h.cfg.DefaultWeeklyTime,
+			DailyChannel:    models.ChannelTelegram,
+			WeeklyChannel:   models.ChannelTelegram,
+			ReminderChannel: models.ChannelTelegram,
 		}
-		h.db.SaveUser(user)
+		if err := h.db.SaveUser(user); err != nil {
+			return nil, err
+		}
+		if err := h.db.SetReminderOffsets(chatID, "", "", []int{offset}); err != nil {
+			return nil, err
+		}
+		logging.FromContext(ctx).Info("registered new user")
 	} else if user.Username != username {
 		user.Username = username
-		h.db.SaveUser(user)
+		if err := h.db.SaveUser(user); err != nil {
+			return nil, err
+		}
 	}
 	return user, nil
 }
 
-func (h *Handler) HandleCommand(chatID int64, cmd string, username string) {
-	user, err := h.registerUser(chatID, username)
+// HandleCommand handles cmd, sent with the rest of its line as args.
+func (h *Handler) HandleCommand(ctx context.Context, chatID int64, cmd string, args string, username string) {
+	label := cmd
+	defer func() {
+		metrics.UpdatesProcessed.WithLabelValues(label).Inc()
+	}()
+
+	logging.FromContext(ctx).Debug("handling command", "command", cmd)
+
+	user, err := h.registerUser(ctx, chatID, username)
 	if err != nil {
-		h.sendMessage(chatID, "Error.")
+		logging.FromContext(ctx).Error("failed to register user", "error", err)
+		h.sendMessage(ctx, chatID, "Error.")
+		return
 	}
 
 	switch cmd {
 	case "start":
-		h.sendMessage(chatID, "Welcome! Use /set_calendar to set your Calendar link.")
+		h.sendMessage(ctx, chatID, "Welcome! Use /set_calendar to set your Calendar link.")
 	case "today":
-		h.today(user)
+		h.today(ctx, user)
 	case "tomorrow":
-		h.tomorrow(user)
+		h.tomorrow(ctx, user)
 	case "week":
-		h.week(user)
+		h.week(ctx, user)
 	case "settings":
-		h.settings(user)
+		h.settings(ctx, user)
 	case "add_friend":
 		h.updateUserState(chatID, "add_friend")
-		h.sendMessage(chatID, "Send your friend's username. Example: @username.")
+		h.sendMessage(ctx, chatID, "Send your friend's Telegram username. Example: @username.")
 	case "accept_friend":
-		h.handleAcceptFriend(user)
+		h.handleAcceptFriend(ctx, user)
 	case "set_daily_time":
 		h.updateUserState(chatID, "set_daily_time")
-		h.sendMessage(chatID, "Send your daily notification time. Example: 07:00.")
+		h.sendMessage(ctx, chatID, "Send your daily notification time. Example: 07:00.")
+	case "set_evening":
+		h.setEvening(ctx, user, args)
+	case "set_commute":
+		h.setCommute(ctx, user, args)
 	case "set_weekly_time":
 		h.updateUserState(chatID, "set_weekly_time")
-		h.sendMessage(chatID, "Send your weekly notification day and time. Example: SUN 18:00.")
+		h.sendMessage(ctx, chatID, "Send your weekly notification day and time. Example: SUN 18:00.")
 	case "set_reminder_offset":
 		h.updateUserState(chatID, "set_reminder_offset")
-		h.sendMessage(chatID, "Send your lectures reminder offset in minutes. Example: 15")
+		h.sendMessage(ctx, chatID, "Send how many minutes before each session to remind you. Example: 15, or 60 10 for two reminders.")
+	case "set_email":
+		h.startSetEmail(ctx, chatID)
+	case "delivery":
+		h.delivery(ctx, user)
 	case "set_calendar":
 		h.updateUserState(chatID, "set_calendar")
-		h.sendMessage(chatID, "Send your Calendar link.\nIt can be found in Portico -> My Studies -> Timetable -> Add to Calendar -> Copy Calendar Link.\nIt must start with webcal://")
+		h.sendMessage(ctx, chatID, "Send your Calendar link.\nIt can be found in Portico -> My Studies -> Timetable -> Add to Calendar -> Copy Calendar Link.\nIt must start with webcal://")
+	case "next":
+		h.next(ctx, user)
+	case "term":
+		h.term(ctx, user)
+	case "reminders":
+		h.reminders(ctx, user, args)
+	case "attendance":
+		h.attendance(ctx, user)
+	case "pause":
+		h.pause(ctx, user, args)
+	case "resume":
+		h.resume(ctx, user)
+	case "day":
+		h.day(ctx, user, args)
+	case "range":
+		h.dateRange(ctx, user, args)
+	case "hide":
+		h.hide(ctx, user, args)
+	case "rename":
+		h.rename(ctx, user, args)
+	case "add_event":
+		h.addEvent(ctx, user, args)
+	case "deadline":
+		h.deadline(ctx, user, args)
+	case "deadlines":
+		h.deadlines(ctx, user)
+	case "set_moodle":
+		h.setMoodle(ctx, user, args)
+	case "events":
+		h.events(ctx, user)
+	case "feed":
+		h.calendarFeed(ctx, user, args)
+	case "link":
+		h.link(ctx, user, args)
 	default:
-		h.sendMessage(chatID, "Unknown command. Use commands from the menu.")
+		label = "unknown"
+		h.sendMessage(ctx, chatID, "Unknown command. Use commands from the menu.")
 	}
 }
 
-func (h *Handler) HandleMessage(chatID int64, text string, username string) {
-	user, err := h.registerUser(chatID, username)
+func (h *Handler) HandleMessage(ctx context.Context, chatID int64, text string, username string) {
+	defer metrics.UpdatesProcessed.WithLabelValues("message").Inc()
+
+	user, err := h.registerUser(ctx, chatID, username)
 	if err != nil {
-		h.sendMessage(chatID, "Error.")
+		logging.FromContext(ctx).Error("failed to register user", "error", err)
+		h.sendMessage(ctx, chatID, "Error.")
+		return
 	}
 
 	state := h.getUserState(chatID)
+	logging.FromContext(ctx).Debug("handling message", "state", state)
 	switch state {
 	case "add_friend":
-		h.handleAddFriend(user, text)
+		h.handleAddFriend(ctx, user, text)
 	case "set_daily_time":
-		h.handleSetDailyTime(user, text)
+		h.handleSetDailyTime(ctx, user, text)
+	case "set_evening":
+		h.handleSetEvening(ctx, user, text)
+	case "set_commute":
+		h.handleSetCommute(ctx, user, text)
 	case "set_weekly_time":
-		h.handleSetWeeklyTime(user, text)
+		h.handleSetWeeklyTime(ctx, user, text)
 	case "set_reminder_offset":
-		h.handleSetReminderOffset(user, text)
+		h.handleSetReminderOffset(ctx, user, text)
 	case "set_calendar":
-		h.handleSetCalendar(user, text)
+		h.handleSetCalendar(ctx, user, text)
+	case "set_email":
+		h.handleSetEmail(ctx, user, text)
+	case "verify_email":
+		h.handleVerifyEmail(ctx, user, text)
+	case "add_event":
+		h.handleAddEvent(ctx, user, text)
+	case "set_moodle":
+		h.handleSetMoodle(ctx, user, text)
 	default:
-		h.sendMessage(chatID, "Please use commands from the menu to interact with the bot.")
+		h.sendMessage(ctx, chatID, "Please use commands from the menu to interact with the bot.")
 	}
 }
 
-func (h *Handler) sendMessage(chatID int64, text string) {
-	text = utils.EscapeUnderscores(text)
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ParseMode = "Markdown"
-	if _, err := h.api.Send(msg); err != nil {
-		log.Printf("Error sending message: %v", err)
+// RateLimited replies to an update dropped by the rate limit. A callback is
+// always answered so its button stops spinning; a message is only answered
+// when warn is set, once per run of dropped updates.
+func (h *Handler) RateLimited(ctx context.Context, chatID int64, callback *messenger.Callback, warn bool) {
+	metrics.UpdatesProcessed.WithLabelValues("rate_limited").Inc()
+	const text = "You're sending requests too quickly. Please wait a moment and try again."
+	if callback != nil {
+		h.answerCallback(ctx, *callback, text)
+		return
+	}
+	if warn {
+		h.sendMessage(ctx, chatID, text)
 	}
 }
 
+func (h *Handler) sendMessage(ctx context.Context, chatID int64, text string) {
+	_, err := h.messenger.SendText(ctx, chatID, text)
+	metrics.ObserveSend(err)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to send message", "recipient", chatID, "error", err)
+	}
+}
+
+func (h *Handler) sendKeyboard(ctx context.Context, chatID int64, text string, keyboard messenger.Keyboard) {
+	_, err := h.messenger.SendKeyboard(ctx, chatID, text, keyboard)
+	metrics.ObserveSend(err)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to send message", "recipient", chatID, "error", err)
+	}
+}
+
+// saveUser persists user, telling them when it fails. It reports whether the
+// save succeeded so callers can stop before confirming a change.
+func (h *Handler) saveUser(ctx context.Context, user *models.User) bool {
+	if err := h.db.SaveUser(user); err != nil {
+		logging.FromContext(ctx).Error("failed to save user", "error", err)
+		h.sendMessage(ctx, user.ChatID, "Error saving your settings. Please try again later.")
+		return false
+	}
+	return true
+}
+
 func (h *Handler) updateUserState(chatID int64, state string) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
@@ -150,47 +271,39 @@ func (h *Handler) clearUserState(chatID int64) {
 	delete(h.userStates, chatID)
 }
 
-func (h *Handler) HandleCallbackQuery(callback *tgbotapi.CallbackQuery) {
-	data := callback.Data
-	chatID := callback.Message.Chat.ID
-
-	ack := tgbotapi.NewCallback(callback.ID, "")
-	if _, err := h.api.Request(ack); err != nil {
-		log.Printf("Error acknowledging callback: %v", err)
-	}
-
-	if strings.HasPrefix(data, "accept_") {
-		parts := strings.Split(data, "_")
-		if len(parts) != 2 {
-			h.sendMessage(chatID, "Invalid callback data.")
-			return
-		}
-		var requestorID int64
-		_, err := fmt.Sscanf(parts[1], "%d", &requestorID)
-		if err != nil {
-			h.sendMessage(chatID, "Invalid requestor ID.")
-			return
-		}
-
-		requestor, err := h.db.GetUser(requestorID)
-		if err != nil || requestor == nil {
-			h.sendMessage(chatID, "Requestor user not found.")
-			return
-		}
+func (h *Handler) HandleCallback(ctx context.Context, callback messenger.Callback) {
+	defer metrics.UpdatesProcessed.WithLabelValues("callback").Inc()
 
-		currentUser, err := h.db.GetUser(chatID)
-		if err != nil || currentUser == nil {
-			h.sendMessage(chatID, "Error fetching your data.")
-			return
-		}
+	logging.FromContext(ctx).Debug("handling callback", "data", callback.Data)
 
-		err = h.db.AcceptFriendRequest(requestorID, chatID)
-		if err != nil {
-			h.sendMessage(chatID, "Error accepting friend request.")
-			return
-		}
+	switch {
+	case strings.HasPrefix(callback.Data, "accept_"):
+		h.handleAcceptFriendCallback(ctx, callback)
+	case strings.HasPrefix(callback.Data, "settings_"):
+		h.handleSettingsCallback(ctx, callback)
+	case strings.HasPrefix(callback.Data, "delivery_"):
+		h.handleDeliveryCallback(ctx, callback)
+	case strings.HasPrefix(callback.Data, "rule_del_"):
+		h.handleDeleteRuleCallback(ctx, callback)
+	case strings.HasPrefix(callback.Data, "event_del_"):
+		h.handleDeleteEventCallback(ctx, callback)
+	case strings.HasPrefix(callback.Data, "deadline_del_"):
+		h.handleDeleteDeadlineCallback(ctx, callback)
+	case strings.HasPrefix(callback.Data, "reminder_"):
+		h.handleReminderCallback(ctx, callback)
+	case callback.Data == "next_refresh":
+		h.handleNextRefreshCallback(ctx, callback)
+	case strings.HasPrefix(callback.Data, "week_"):
+		h.handleWeekCallback(ctx, callback)
+	default:
+		h.answerCallback(ctx, callback, "")
+	}
+}
 
-		h.sendMessage(currentUser.ChatID, fmt.Sprintf("You are now friends with @%s!", requestor.Username))
-		h.sendMessage(requestor.ChatID, fmt.Sprintf("@%s has accepted your friend request!", currentUser.Username))
+// answerCallback acknowledges a button press. Every callback must be
+// answered exactly once, or the button keeps showing a spinner.
+func (h *Handler) answerCallback(ctx context.Context, callback messenger.Callback, text string) {
+	if err := h.messenger.AnswerCallback(ctx, callback.ID, text); err != nil {
+		logging.FromContext(ctx).Error("failed to acknowledge callback", "error", err)
 	}
 }
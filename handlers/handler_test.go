@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/config"
+	"github.com/artem-streltsov/ucl-timetable-bot/database"
+	"github.com/artem-streltsov/ucl-timetable-bot/messenger"
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+	"github.com/artem-streltsov/ucl-timetable-bot/scheduler"
+	"github.com/artem-streltsov/ucl-timetable-bot/utils"
+)
+
+const (
+	alice int64 = 100
+	bob   int64 = 200
+)
+
+// unreachableCalendar is a calendar link whose fetch fails straight away, so
+// scheduling a user does not touch the network.
+const unreachableCalendar = "webcal://127.0.0.1:1/calendar.ics"
+
+// newTestHandler returns a handler backed by an in-memory store, recording
+// everything it sends.
+func newTestHandler(t *testing.T) (*Handler, *messenger.Recorder, *database.Memory) {
+	t.Helper()
+	rec := messenger.NewRecorder()
+	db := database.NewMemory()
+	sched := scheduler.NewScheduler(rec, nil, db)
+	t.Cleanup(sched.StopAll)
+	cfg := &config.Config{
+		DefaultDailyTime:      "07:00",
+		DefaultWeeklyTime:     "SUN 18:00",
+		DefaultReminderOffset: "15",
+	}
+	return NewHandler(rec, nil, db, sched, cfg), rec, db
+}
+
+func lastText(t *testing.T, rec *messenger.Recorder, chatID int64) string {
+	t.Helper()
+	msg, ok := rec.Last(chatID)
+	if !ok {
+		t.Fatalf("nothing was sent to %d", chatID)
+	}
+	return msg.Text
+}
+
+func wantLast(t *testing.T, rec *messenger.Recorder, chatID int64, substr string) {
+	t.Helper()
+	if text := lastText(t, rec, chatID); !strings.Contains(text, substr) {
+		t.Errorf("last message to %d = %q, want it to contain %q", chatID, text, substr)
+	}
+}
+
+// findButton returns the button with data in the last keyboard sent to
+// chatID and the ID of the message it is on.
+func findButton(t *testing.T, rec *messenger.Recorder, chatID int64, data string) (messenger.Button, string) {
+	t.Helper()
+	messages := rec.Messages(chatID)
+	for i := len(messages) - 1; i >= 0; i-- {
+		for _, row := range messages[i].Keyboard {
+			for _, button := range row {
+				if button.Data == data {
+					return button, messages[i].ID
+				}
+			}
+		}
+	}
+	t.Fatalf("no button %q sent to %d", data, chatID)
+	return messenger.Button{}, ""
+}
+
+func TestStartRegistersUser(t *testing.T) {
+	h, rec, db := newTestHandler(t)
+	ctx := context.Background()
+
+	h.HandleCommand(ctx, alice, "start", "", "alice")
+
+	wantLast(t, rec, alice, "Welcome!")
+	user, err := db.GetUser(alice)
+	if err != nil || user == nil {
+		t.Fatalf("GetUser = %v, %v, want the new user", user, err)
+	}
+	if user.Username != "alice" || user.DailyTime != "07:00" || user.WeeklyTime != "SUN 18:00" {
+		t.Errorf("new user = %+v, want the configured defaults", user)
+	}
+	offsets, err := db.GetReminderOffsets(alice)
+	if err != nil || len(offsets) != 1 || offsets[0].Minutes != 15 {
+		t.Errorf("GetReminderOffsets = %+v, %v, want the default 15 minutes", offsets, err)
+	}
+
+	h.HandleCommand(ctx, alice, "start", "", "alice_renamed")
+	if user, _ := db.GetUser(alice); user.Username != "alice_renamed" {
+		t.Errorf("Username after a new one is seen = %q, want alice_renamed", user.Username)
+	}
+	if n, _ := db.CountUsers(); n != 1 {
+		t.Errorf("CountUsers = %d, want 1", n)
+	}
+}
+
+func TestSetCalendarConversation(t *testing.T) {
+	h, rec, db := newTestHandler(t)
+	ctx := context.Background()
+
+	h.HandleCommand(ctx, alice, "set_calendar", "", "alice")
+	wantLast(t, rec, alice, "Send your Calendar link.")
+
+	h.HandleMessage(ctx, alice, "https://example.com/calendar.ics", "alice")
+	wantLast(t, rec, alice, "must start with webcal://")
+	if user, _ := db.GetUser(alice); user.WebCalURL != "" {
+		t.Errorf("WebCalURL after an invalid link = %q, want it unset", user.WebCalURL)
+	}
+
+	// The conversation carries on until a valid link arrives.
+	h.HandleMessage(ctx, alice, unreachableCalendar, "alice")
+	wantLast(t, rec, alice, "Calendar link saved.")
+	if user, _ := db.GetUser(alice); user.WebCalURL != unreachableCalendar {
+		t.Errorf("WebCalURL = %q, want %q", user.WebCalURL, unreachableCalendar)
+	}
+
+	h.HandleMessage(ctx, alice, unreachableCalendar, "alice")
+	wantLast(t, rec, alice, "Please use commands from the menu")
+}
+
+func TestAddAndAcceptFriend(t *testing.T) {
+	h, rec, db := newTestHandler(t)
+	ctx := context.Background()
+	h.HandleCommand(ctx, bob, "start", "", "bob")
+
+	h.HandleCommand(ctx, alice, "add_friend", "", "alice")
+	for _, tc := range []struct {
+		text string
+		want string
+	}{
+		{"bob", "Invalid username format."},
+		{"@nobody", "User not found."},
+		{"@alice", "You cannot add yourself"},
+	} {
+		h.HandleMessage(ctx, alice, tc.text, "alice")
+		wantLast(t, rec, alice, tc.want)
+	}
+
+	h.HandleMessage(ctx, alice, "@bob", "alice")
+	wantLast(t, rec, alice, "Request sent.")
+	wantLast(t, rec, bob, "@alice has sent you a friend request.")
+	if ok, _ := db.FriendRequestExists(alice, bob); !ok {
+		t.Fatal("friend request was not stored")
+	}
+
+	h.HandleCommand(ctx, bob, "accept_friend", "", "bob")
+	data := fmt.Sprintf("accept_%d", alice)
+	button, messageID := findButton(t, rec, bob, data)
+	if button.Text != "@alice" {
+		t.Errorf("accept button = %q, want @alice", button.Text)
+	}
+
+	callback := messenger.Callback{ID: "cb1", ChatID: bob, MessageID: messageID, Data: data}
+	h.HandleCallback(ctx, callback)
+	if !slices.Contains(rec.Answered(), "cb1") {
+		t.Error("accept callback was not answered")
+	}
+	wantLast(t, rec, bob, "You are now friends with @alice!")
+	wantLast(t, rec, alice, "@bob has accepted your friend request!")
+	if ok, _ := db.AreFriends(alice, bob); !ok {
+		t.Error("AreFriends = false after accepting")
+	}
+
+	h.HandleCommand(ctx, alice, "add_friend", "", "alice")
+	h.HandleMessage(ctx, alice, "@bob", "alice")
+	wantLast(t, rec, alice, "already friends")
+}
+
+func TestSettingsToggles(t *testing.T) {
+	h, rec, db := newTestHandler(t)
+	ctx := context.Background()
+
+	h.HandleCommand(ctx, alice, "settings", "", "alice")
+	wantLast(t, rec, alice, "Your Calendar link is not set.")
+
+	for i, toggle := range settingToggles {
+		data := "settings_" + toggle.key
+		button, messageID := findButton(t, rec, alice, data)
+		if !strings.HasSuffix(button.Text, ": on") && !strings.HasSuffix(button.Text, ": off") {
+			t.Fatalf("button %q does not show on or off", button.Text)
+		}
+		before := strings.HasSuffix(button.Text, ": on")
+
+		id := fmt.Sprintf("cb%d", i)
+		h.HandleCallback(ctx, messenger.Callback{ID: id, ChatID: alice, MessageID: messageID, Data: data})
+		if !slices.Contains(rec.Answered(), id) {
+			t.Errorf("%s: callback was not answered", data)
+		}
+
+		user, _ := db.GetUser(alice)
+		if on := *toggle.field(user) != toggle.inverted; on == before {
+			t.Errorf("%s: setting on = %v after toggling, want %v", data, on, !before)
+		}
+		button, _ = findButton(t, rec, alice, data)
+		if on := strings.HasSuffix(button.Text, ": on"); on == before {
+			t.Errorf("%s: button %q was not updated", data, button.Text)
+		}
+	}
+
+	if messages := rec.Messages(alice); !messages[0].Edited {
+		t.Error("settings message was not edited in place")
+	}
+
+	h.HandleCallback(ctx, messenger.Callback{ID: "bogus", ChatID: alice, Data: "settings_bogus"})
+	if !slices.Contains(rec.Answered(), "bogus") {
+		t.Error("unknown settings callback was not answered")
+	}
+}
+
+func TestTimetableWithOnlyEvents(t *testing.T) {
+	h, rec, db := newTestHandler(t)
+	ctx := context.Background()
+
+	h.HandleCommand(ctx, alice, "today", "", "alice")
+	wantLast(t, rec, alice, "Please set your calendar link")
+
+	now := time.Now().In(utils.Location())
+	start := time.Date(now.Year(), now.Month(), now.Day(), 23, 0, 0, 0, utils.Location())
+	event := &models.Event{ChatID: alice, Title: "Study group", Location: "Main Library", Start: start, End: start.Add(30 * time.Minute)}
+	if err := db.AddEvent(event); err != nil {
+		t.Fatal(err)
+	}
+	h.HandleCommand(ctx, alice, "today", "", "alice")
+	wantLast(t, rec, alice, "Study group")
+}
+
+func TestWeekCallbackStartsOnMonday(t *testing.T) {
+	h, rec, db := newTestHandler(t)
+	ctx := context.Background()
+	h.HandleCommand(ctx, alice, "start", "", "alice")
+
+	// A Monday two weeks ahead and the Saturday of that week.
+	monday := defaultWeek(time.Now().In(utils.Location())).AddDate(0, 0, 14).Add(10 * time.Hour)
+	for _, event := range []*models.Event{
+		{ChatID: alice, Title: "Study group", Start: monday, End: monday.Add(time.Hour)},
+		{ChatID: alice, Title: "Weekend hike", Start: monday.AddDate(0, 0, 5), End: monday.AddDate(0, 0, 5).Add(time.Hour)},
+	} {
+		if err := db.AddEvent(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h.HandleCommand(ctx, alice, "week", "", "alice")
+	_, messageID := findButton(t, rec, alice, "week_today")
+
+	// A Wednesday shows the week from its Monday.
+	wednesday := monday.AddDate(0, 0, 2).Format(time.DateOnly)
+	h.HandleCallback(ctx, messenger.Callback{ID: "cb1", ChatID: alice, MessageID: messageID, Data: "week_" + wednesday})
+	msg, _ := rec.Last(alice)
+	if !msg.Edited || !strings.Contains(msg.Text, "Study group") || strings.Contains(msg.Text, "Weekend hike") {
+		t.Errorf("week message = %q, want the week of %s", msg.Text, monday.Format("Mon, 02 Jan"))
+	}
+	findButton(t, rec, alice, "week_"+monday.AddDate(0, 0, -7).Format(time.DateOnly))
+	findButton(t, rec, alice, "week_"+monday.AddDate(0, 0, 7).Format(time.DateOnly))
+}
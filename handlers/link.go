@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/logging"
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+)
+
+const (
+	linkCodeTTL    = 10 * time.Minute
+	linkCodeLength = 8
+	// linkCodeAlphabet leaves out characters that are easy to misread.
+	linkCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+type linkCode struct {
+	chatID  int64
+	expires time.Time
+}
+
+// link handles /link. Without a code it issues one; with a code it joins the
+// account the code was issued to with this one.
+func (h *Handler) link(ctx context.Context, user *models.User, code string) {
+	if code == "" {
+		h.issueLinkCode(ctx, user)
+		return
+	}
+	h.redeemLinkCode(ctx, user, strings.ToUpper(code))
+}
+
+func (h *Handler) issueLinkCode(ctx context.Context, user *models.User) {
+	code, err := newLinkCode()
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to generate link code", "error", err)
+		h.sendMessage(ctx, user.ChatID, "Error creating a link code. Please try again later.")
+		return
+	}
+
+	h.mu.Lock()
+	for existing, issued := range h.linkCodes {
+		if issued.chatID == user.ChatID || time.Now().After(issued.expires) {
+			delete(h.linkCodes, existing)
+		}
+	}
+	h.linkCodes[code] = &linkCode{chatID: user.ChatID, expires: time.Now().Add(linkCodeTTL)}
+	h.mu.Unlock()
+
+	h.sendMessage(ctx, user.ChatID, "Your link code is *"+code+"*. Send /link "+code+" to the bot from your account on the other platform within 10 minutes.")
+}
+
+func (h *Handler) redeemLinkCode(ctx context.Context, user *models.User, code string) {
+	logger := logging.FromContext(ctx)
+
+	h.mu.Lock()
+	issued, ok := h.linkCodes[code]
+	if ok {
+		delete(h.linkCodes, code)
+	}
+	h.mu.Unlock()
+	if !ok || time.Now().After(issued.expires) {
+		h.sendMessage(ctx, user.ChatID, "Invalid or expired link code. Send /link on your other account to get a new one.")
+		return
+	}
+	if issued.chatID == user.ChatID {
+		h.sendMessage(ctx, user.ChatID, "These accounts are already linked.")
+		return
+	}
+
+	// A Telegram chat ID can't move to another user, so the Telegram side
+	// always keeps its settings. Otherwise the account that issued the code
+	// does.
+	into, from := issued.chatID, user.ChatID
+	switch {
+	case !models.IsSyntheticChatID(into) && !models.IsSyntheticChatID(from):
+		h.sendMessage(ctx, user.ChatID, "Two Telegram chats can't be linked. Link codes join Telegram with Discord or Matrix accounts.")
+		return
+	case !models.IsSyntheticChatID(from):
+		into, from = from, into
+	}
+
+	if err := h.db.MergeUser(from, into); err != nil {
+		logger.Error("failed to link accounts", "from", from, "into", into, "error", err)
+		h.sendMessage(ctx, user.ChatID, "Error linking accounts. Please try again later.")
+		return
+	}
+	h.scheduler.CancelUser(from)
+	h.mu.Lock()
+	delete(h.userStates, from)
+	delete(h.emailVerifications, from)
+	h.mu.Unlock()
+
+	logger.Info("linked accounts", "from", from, "into", into)
+	msg := "Accounts linked. Both now share one timetable, settings and friends list."
+	if into != user.ChatID {
+		msg += " The settings of your other account are kept."
+	}
+	h.sendMessage(ctx, into, msg)
+}
+
+func newLinkCode() (string, error) {
+	var sb strings.Builder
+	for i := 0; i < linkCodeLength; i++ {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(linkCodeAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(linkCodeAlphabet[n.Int64()])
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/logging"
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+	"github.com/artem-streltsov/ucl-timetable-bot/timetable"
+)
+
+// setMoodle handles /set_moodle. The link can follow the command or come in
+// the next message.
+func (h *Handler) setMoodle(ctx context.Context, user *models.User, args string) {
+	if args == "" {
+		h.updateUserState(user.ChatID, "set_moodle")
+		h.sendMessage(ctx, user.ChatID, "Send your Moodle calendar link to import your deadlines, or \"off\" to stop importing.\nIt can be found in Moodle -> Calendar -> Import or export calendars -> Export calendar: choose All courses and Recent and next 60 days, then Get calendar URL.")
+		return
+	}
+	h.handleSetMoodle(ctx, user, args)
+}
+
+func (h *Handler) handleSetMoodle(ctx context.Context, user *models.User, text string) {
+	logger := logging.FromContext(ctx)
+	text = strings.TrimSpace(text)
+	if strings.EqualFold(text, "off") {
+		h.stopMoodle(ctx, user)
+		return
+	}
+	lower := strings.ToLower(text)
+	if !strings.HasPrefix(lower, "https://") && !strings.HasPrefix(lower, "webcal://") {
+		h.sendMessage(ctx, user.ChatID, "Moodle link must start with https:// or webcal://")
+		return
+	}
+
+	if _, err := timetable.FetchCalendar(text); err != nil {
+		logger.Warn("failed to read moodle calendar", "error", err)
+		h.sendMessage(ctx, user.ChatID, "Couldn't read a calendar from that link. Please check it and try again.")
+		return
+	}
+	// Save the link before importing, so imported deadlines always belong
+	// to a link that later syncs and "/set_moodle off" can clean up.
+	user.MoodleURL = text
+	if !h.saveUser(ctx, user) {
+		return
+	}
+	h.clearUserState(user.ChatID)
+	changes, err := h.scheduler.SyncMoodle(user)
+	h.scheduler.ScheduleUser(ctx, user.ChatID)
+	if err != nil {
+		logger.Error("failed to import moodle deadlines", "error", err)
+		h.sendMessage(ctx, user.ChatID, "Moodle link saved, but importing your deadlines failed. It will be tried again later.")
+		return
+	}
+	h.sendMessage(ctx, user.ChatID, fmt.Sprintf("Moodle link saved. Imported %d new deadlines; new, moved and removed deadlines will be announced from now on. Use /deadlines to see them.", len(changes.Added)))
+}
+
+// stopMoodle forgets the user's Moodle link along with the deadlines
+// imported from it.
+func (h *Handler) stopMoodle(ctx context.Context, user *models.User) {
+	logger := logging.FromContext(ctx)
+	user.MoodleURL = ""
+	if !h.saveUser(ctx, user) {
+		return
+	}
+	h.clearUserState(user.ChatID)
+
+	deadlines, err := h.db.GetDeadlines(user.ChatID)
+	if err != nil {
+		logger.Error("failed to get deadlines", "error", err)
+	}
+	for _, deadline := range deadlines {
+		if deadline.UID == "" {
+			continue
+		}
+		if _, err := h.db.DeleteDeadline(user.ChatID, deadline.ID); err != nil {
+			logger.Error("failed to delete imported deadline", "error", err)
+		}
+	}
+	h.scheduler.ScheduleUser(ctx, user.ChatID)
+	h.sendMessage(ctx, user.ChatID, "Stopped importing from Moodle and removed the imported deadlines.")
+}
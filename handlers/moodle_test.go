@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/database"
+	"github.com/artem-streltsov/ucl-timetable-bot/messenger"
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+	"github.com/artem-streltsov/ucl-timetable-bot/scheduler"
+)
+
+// serveMoodle serves a Moodle calendar export with one assignment due next
+// week over HTTPS, and makes the default client trust it.
+func serveMoodle(t *testing.T) string {
+	t.Helper()
+	due := time.Now().Add(7 * 24 * time.Hour).UTC().Format("20060102T150405Z")
+	ics := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:moodle\r\n" +
+		"BEGIN:VEVENT\r\nUID:1@moodle\r\n" +
+		fmt.Sprintf("DTSTAMP:%s\r\nDTSTART:%s\r\nDTEND:%s\r\n", due, due, due) +
+		"SUMMARY:Coursework 1 is due\r\nCATEGORIES:COMP0010_25-26\r\n" +
+		"END:VEVENT\r\nEND:VCALENDAR\r\n"
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(ics))
+	}))
+	t.Cleanup(server.Close)
+
+	transport := http.DefaultTransport
+	http.DefaultTransport = server.Client().Transport
+	t.Cleanup(func() { http.DefaultTransport = transport })
+	return server.URL + "/export.ics"
+}
+
+func TestSetMoodleImportsDeadlines(t *testing.T) {
+	h, rec, db := newTestHandler(t)
+	ctx := context.Background()
+	h.HandleCommand(ctx, alice, "start", "", "alice")
+	link := serveMoodle(t)
+
+	h.HandleCommand(ctx, alice, "set_moodle", link, "alice")
+	wantLast(t, rec, alice, "Imported 1 new deadlines")
+	user, _ := db.GetUser(alice)
+	if user.MoodleURL != link {
+		t.Errorf("MoodleURL = %q, want %q", user.MoodleURL, link)
+	}
+	deadlines, _ := db.GetDeadlines(alice)
+	if len(deadlines) != 1 || deadlines[0].Module != "COMP0010" || deadlines[0].Title != "Coursework 1" {
+		t.Errorf("deadlines = %+v, want Coursework 1 for COMP0010", deadlines)
+	}
+}
+
+func TestSetMoodleBadLinkSavesNothing(t *testing.T) {
+	h, rec, db := newTestHandler(t)
+	ctx := context.Background()
+	h.HandleCommand(ctx, alice, "start", "", "alice")
+
+	h.HandleCommand(ctx, alice, "set_moodle", "https://127.0.0.1:1/export.ics", "alice")
+	wantLast(t, rec, alice, "Couldn't read a calendar")
+	if user, _ := db.GetUser(alice); user.MoodleURL != "" {
+		t.Errorf("MoodleURL = %q after a bad link, want it unset", user.MoodleURL)
+	}
+}
+
+// failingSaves is a store whose user saves fail.
+type failingSaves struct {
+	*database.Memory
+}
+
+func (failingSaves) SaveUser(*models.User) error {
+	return errors.New("disk full")
+}
+
+func TestSetMoodleSaveFailureImportsNothing(t *testing.T) {
+	rec := messenger.NewRecorder()
+	mem := database.NewMemory()
+	if err := mem.SaveUser(&models.User{ChatID: alice}); err != nil {
+		t.Fatal(err)
+	}
+	db := failingSaves{mem}
+	sched := scheduler.NewScheduler(rec, nil, db)
+	t.Cleanup(sched.StopAll)
+	h := NewHandler(rec, nil, db, sched, nil)
+	ctx := context.Background()
+	link := serveMoodle(t)
+
+	user, _ := mem.GetUser(alice)
+	h.handleSetMoodle(ctx, user, link)
+	wantLast(t, rec, alice, "Error saving your settings")
+	if deadlines, _ := mem.GetDeadlines(alice); len(deadlines) != 0 {
+		t.Errorf("deadlines = %+v after a failed save, want none", deadlines)
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/academic"
+	"github.com/artem-streltsov/ucl-timetable-bot/logging"
+	"github.com/artem-streltsov/ucl-timetable-bot/messenger"
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+	"github.com/artem-streltsov/ucl-timetable-bot/timetable"
+	"github.com/artem-streltsov/ucl-timetable-bot/utils"
+)
+
+var nextKeyboard = messenger.Keyboard{{{Text: "🔄 Refresh", Data: "next_refresh"}}}
+
+// next handles /next, showing the next lecture with a countdown and a
+// button that refreshes it in place.
+func (h *Handler) next(ctx context.Context, user *models.User) {
+	if !timetable.HasTimetable(h.db, user) {
+		h.sendMessage(ctx, user.ChatID, "Please set your calendar link using /set_calendar")
+		return
+	}
+	text, ok := h.nextText(ctx, user)
+	if !ok {
+		h.sendMessage(ctx, user.ChatID, text)
+		return
+	}
+	h.sendKeyboard(ctx, user.ChatID, text, nextKeyboard)
+}
+
+func (h *Handler) handleNextRefreshCallback(ctx context.Context, callback messenger.Callback) {
+	user, err := h.db.GetUser(callback.ChatID)
+	if err != nil || user == nil {
+		if err != nil {
+			logging.FromContext(ctx).Error("failed to get user", "error", err)
+		}
+		h.answerCallback(ctx, callback, "Error fetching your data.")
+		return
+	}
+	text, ok := h.nextText(ctx, user)
+	if !ok {
+		h.answerCallback(ctx, callback, text)
+		return
+	}
+	// An edit that leaves the message as it was is not an error; the
+	// messenger reports it as success.
+	if err := h.messenger.EditMessage(ctx, callback.ChatID, callback.MessageID, text, nextKeyboard); err != nil {
+		logging.FromContext(ctx).Error("failed to update next lecture message", "error", err)
+		h.answerCallback(ctx, callback, "Error updating the message.")
+		return
+	}
+	h.answerCallback(ctx, callback, "Updated.")
+}
+
+// nextText fetches the user's timetable and describes their next lecture.
+// It reports false with an error message when the timetable can't be
+// fetched.
+func (h *Handler) nextText(ctx context.Context, user *models.User) (string, bool) {
+	lectures, err := timetable.ForUser(h.db, user)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to fetch calendar", "error", err)
+		return "Error fetching calendar", false
+	}
+	now := time.Now()
+	return formatNext(nextTeaching(academic.Current(), lectures, now), now), true
+}
+
+// nextTeaching is timetable.NextOnDay over the lectures that fall on
+// teaching days, so custom events repeating through a weekend or a closure
+// are skipped the same way the Portico timetable skips them.
+func nextTeaching(cal *academic.Calendar, lectures []timetable.Lecture, now time.Time) []timetable.Lecture {
+	var teaching []timetable.Lecture
+	for _, lecture := range lectures {
+		if cal.TeachingDay(lecture.Start.In(utils.Location())) {
+			teaching = append(teaching, lecture)
+		}
+	}
+	return timetable.NextOnDay(teaching, now)
+}
+
+// formatNext describes the first of day, the next lecture, and lists what
+// follows it that day.
+func formatNext(day []timetable.Lecture, now time.Time) string {
+	if len(day) == 0 {
+		return "No upcoming lectures."
+	}
+	next := day[0]
+	start := next.Start.In(utils.Location())
+
+	var sb strings.Builder
+	sb.WriteString("⏭ *" + timetable.CleanTitle(next.Title) + "*\n")
+	sb.WriteString("⏳ in " + utils.FormatCountdown(next.Start.Sub(now)) + "\n")
+	sb.WriteString("⏰ " + start.Format("Mon 02 Jan 15:04") + " - " + next.End.In(utils.Location()).Format("15:04") + "\n")
+	if next.Location != "" {
+		sb.WriteString("📍 " + next.Location + "\n")
+	}
+	if len(day) > 1 {
+		sb.WriteString("\nThen:\n")
+		for _, lecture := range day[1:] {
+			sb.WriteString("• " + lecture.Start.In(utils.Location()).Format("15:04") + " " + timetable.CleanTitle(lecture.Title))
+			if lecture.Location != "" {
+				sb.WriteString(" @ " + lecture.Location)
+			}
+			sb.WriteString("\n")
+		}
+	}
+	sb.WriteString("\nUpdated " + now.In(utils.Location()).Format("15:04"))
+	return sb.String()
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/academic"
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+	"github.com/artem-streltsov/ucl-timetable-bot/timetable"
+	"github.com/artem-streltsov/ucl-timetable-bot/utils"
+)
+
+func TestNextTeachingSkipsWeekendsAndClosures(t *testing.T) {
+	cal, err := academic.Parse([]byte(`
+years:
+  - name: "2025-26"
+    start: 2025-08-01
+    end: 2026-07-31
+    terms:
+      - name: Term 1
+        start: 2025-09-29
+        end: 2025-12-12
+    closures:
+      - name: Staff day
+        start: 2025-10-10
+        end: 2025-10-10
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(day, hour int) time.Time {
+		return time.Date(2025, time.October, day, hour, 0, 0, 0, utils.Location())
+	}
+	// A weekly custom event on Fridays, and a one-off on Saturday.
+	events := []models.Event{
+		{ID: 1, Title: "Society meeting", Start: at(3, 18), End: at(3, 19), Weekly: true},
+		{ID: 2, Title: "Study group", Start: at(11, 10), End: at(11, 12)},
+	}
+	lectures := timetable.EventLectures(events, at(1, 0), at(31, 0))
+	lectures = append(lectures, timetable.Lecture{Title: "COMP0001 Lecture", Start: at(13, 9), End: at(13, 10)})
+	sort.Slice(lectures, func(i, j int) bool { return lectures[i].Start.Before(lectures[j].Start) })
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{"teaching day", at(2, 12), at(3, 18)},
+		{"closure", at(9, 12), at(13, 9)},
+		{"weekend", at(11, 0), at(13, 9)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nextTeaching(cal, lectures, tt.now)
+			if len(got) == 0 || !got[0].Start.Equal(tt.want) {
+				t.Errorf("next after %s = %v, want the session at %s", tt.now, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/academic"
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+	"github.com/artem-streltsov/ucl-timetable-bot/utils"
+)
+
+const pauseUsage = "Usage: /pause to pause until /resume, or /pause until DATE, e.g. /pause until 5 Jan or /pause until next term."
+
+// pause handles /pause, stopping summaries and reminders until /resume or,
+// with "until DATE", until the start of that day.
+func (h *Handler) pause(ctx context.Context, user *models.User, args string) {
+	now := time.Now().In(utils.Location())
+	var until *time.Time
+	if args != "" {
+		date, problem := parsePauseUntil(args, academic.Current(), now)
+		if problem != "" {
+			h.sendMessage(ctx, user.ChatID, problem+"\n"+pauseUsage)
+			return
+		}
+		until = &date
+	}
+
+	user.Paused = true
+	user.PausedUntil = until
+	if !h.saveUser(ctx, user) {
+		return
+	}
+	h.scheduler.ScheduleUser(ctx, user.ChatID)
+	if until == nil {
+		h.sendMessage(ctx, user.ChatID, "⏸ Notifications paused. Use /resume to turn them back on.")
+		return
+	}
+	h.sendMessage(ctx, user.ChatID, fmt.Sprintf("⏸ Notifications paused until %s. Use /resume to turn them back on sooner.", until.Format("Mon 02 Jan 2006")))
+}
+
+// resume handles /resume, ending a pause early.
+func (h *Handler) resume(ctx context.Context, user *models.User) {
+	if !user.Paused {
+		h.sendMessage(ctx, user.ChatID, "Your notifications are not paused.")
+		return
+	}
+	user.Paused = false
+	user.PausedUntil = nil
+	if !h.saveUser(ctx, user) {
+		return
+	}
+	h.scheduler.ScheduleUser(ctx, user.ChatID)
+	h.sendMessage(ctx, user.ChatID, "▶️ Notifications resumed.")
+}
+
+// parsePauseUntil reads the day a pause ends, as accepted by utils.ParseDate
+// with an optional "until" in front, or "next term" for the start of the
+// next term. The day must be after today. It returns a message for the user
+// when the text can't be read.
+func parsePauseUntil(text string, cal *academic.Calendar, now time.Time) (time.Time, string) {
+	text = strings.TrimSpace(text)
+	if rest, ok := strings.CutPrefix(strings.ToLower(text), "until "); ok {
+		text = strings.TrimSpace(rest)
+	}
+
+	var date time.Time
+	switch strings.ToLower(text) {
+	case "term", "next term":
+		term := cal.NextTerm(now)
+		if term == nil {
+			return time.Time{}, "The academic calendar has no next term yet."
+		}
+		date = time.Date(term.Start.Year(), term.Start.Month(), term.Start.Day(), 0, 0, 0, 0, now.Location())
+	default:
+		var ok bool
+		date, ok = utils.ParseDate(text, now)
+		if !ok {
+			return time.Time{}, fmt.Sprintf("Couldn't read %q as a date.", text)
+		}
+	}
+	if !date.After(now) {
+		return time.Time{}, "The pause must end after today."
+	}
+	return date, ""
+}
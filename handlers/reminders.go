@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/logging"
+	"github.com/artem-streltsov/ucl-timetable-bot/messenger"
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+	"github.com/artem-streltsov/ucl-timetable-bot/scheduler"
+	"github.com/artem-streltsov/ucl-timetable-bot/timetable"
+	"github.com/artem-streltsov/ucl-timetable-bot/utils"
+)
+
+// reminderActions maps the buttons on a lecture reminder to the answer they
+// record.
+var reminderActions = map[string]string{
+	"snooze": models.AckSnooze,
+	"skip":   models.AckSkip,
+	"ack":    models.AckGotIt,
+}
+
+const (
+	// maxReminderOffsets limits the offsets for one session type and module.
+	maxReminderOffsets = 5
+	remindersUsage     = "Usage: /reminders [TYPE] [MODULE] MINUTES..., e.g. /reminders 60 10 for two reminders before everything, /reminders lab 30 for labs, /reminders COMP0010 30 5 for one module or /reminders COMP0010 lab 45 for its labs. Use off instead of the minutes to remove offsets.\nTypes: "
+)
+
+// reminders handles /reminders, listing the user's reminder offsets or
+// replacing those for one session type and module.
+func (h *Handler) reminders(ctx context.Context, user *models.User, args string) {
+	logger := logging.FromContext(ctx)
+	usage := remindersUsage + strings.Join(timetable.SessionTypes, ", ")
+	if args == "" {
+		offsets, err := h.db.GetReminderOffsets(user.ChatID)
+		if err != nil {
+			logger.Error("failed to get reminder offsets", "error", err)
+			h.sendMessage(ctx, user.ChatID, "Error fetching your reminders.")
+			return
+		}
+		h.sendMessage(ctx, user.ChatID, remindersText(offsets, user.RemindersFirstOnly)+"\n\n"+usage)
+		return
+	}
+
+	sessionType, module, minutes, problem := parseReminderOffsets(args)
+	if problem != "" {
+		h.sendMessage(ctx, user.ChatID, problem+"\n"+usage)
+		return
+	}
+	h.setReminderOffsets(ctx, user, sessionType, module, minutes)
+}
+
+// handleSetReminderOffset replaces the user's general offsets, which remind
+// them of every session without a more specific offset.
+func (h *Handler) handleSetReminderOffset(ctx context.Context, user *models.User, text string) {
+	sessionType, module, minutes, problem := parseReminderOffsets(text)
+	if problem == "" && (sessionType != "" || module != "") {
+		problem = "Use /reminders to set offsets for a session type or module."
+	}
+	if problem != "" {
+		h.sendMessage(ctx, user.ChatID, problem+" Send the minutes, e.g. 15, or 60 10 for two reminders.")
+		return
+	}
+	h.clearUserState(user.ChatID)
+	h.setReminderOffsets(ctx, user, "", "", minutes)
+}
+
+func (h *Handler) setReminderOffsets(ctx context.Context, user *models.User, sessionType, module string, minutes []int) {
+	logger := logging.FromContext(ctx)
+	if err := h.db.SetReminderOffsets(user.ChatID, sessionType, module, minutes); err != nil {
+		logger.Error("failed to save reminder offsets", "error", err)
+		h.sendMessage(ctx, user.ChatID, "Error saving your reminders. Please try again later.")
+		return
+	}
+	h.scheduler.ScheduleUser(ctx, user.ChatID)
+
+	offsets, err := h.db.GetReminderOffsets(user.ChatID)
+	if err != nil {
+		logger.Error("failed to get reminder offsets", "error", err)
+		h.sendMessage(ctx, user.ChatID, "Reminders saved.")
+		return
+	}
+	h.sendMessage(ctx, user.ChatID, "Reminders saved.\n\n"+remindersText(offsets, user.RemindersFirstOnly))
+}
+
+// handleReminderCallback handles the buttons on a lecture reminder. It
+// records the answer, reschedules the day's reminders for a snooze or skip
+// and replaces the buttons with what was chosen.
+func (h *Handler) handleReminderCallback(ctx context.Context, callback messenger.Callback) {
+	logger := logging.FromContext(ctx)
+	button, key, _ := strings.Cut(strings.TrimPrefix(callback.Data, "reminder_"), "_")
+	action, ok := reminderActions[button]
+	if !ok {
+		h.answerCallback(ctx, callback, "Invalid callback data.")
+		return
+	}
+	user, err := h.db.GetUser(callback.ChatID)
+	if err != nil || user == nil {
+		if err != nil {
+			logger.Error("failed to get user", "error", err)
+		}
+		h.answerCallback(ctx, callback, "Error fetching your data.")
+		return
+	}
+	lectures, err := timetable.ForUser(h.db, user)
+	if err != nil {
+		logger.Error("failed to fetch calendar for reminder answer", "error", err)
+		h.answerCallback(ctx, callback, "Error fetching your timetable.")
+		return
+	}
+	lecture, found := timetable.FindLecture(lectures, key)
+	if !found {
+		h.answerCallback(ctx, callback, "That lecture is no longer in your timetable.")
+		return
+	}
+	now := time.Now()
+	if action == models.AckSnooze && !lecture.Start.After(now) {
+		h.answerCallback(ctx, callback, "That lecture has already started.")
+		return
+	}
+
+	title := timetable.CleanTitle(lecture.Title)
+	ack := models.ReminderAck{
+		ChatID:     user.ChatID,
+		LectureUID: lecture.UID,
+		Module:     lecture.Module,
+		Title:      title,
+		Start:      lecture.Start,
+		Action:     action,
+		At:         now,
+	}
+	if err := h.db.AddReminderAck(&ack); err != nil {
+		logger.Error("failed to save reminder answer", "error", err)
+		h.answerCallback(ctx, callback, "Error saving your answer.")
+		return
+	}
+
+	var answer, status string
+	switch action {
+	case models.AckSnooze:
+		minutes := int(scheduler.SnoozeFor.Minutes())
+		answer = fmt.Sprintf("Snoozed for %d minutes.", minutes)
+		status = fmt.Sprintf("💤 Snoozed for %d minutes", minutes)
+	case models.AckSkip:
+		skipped := title
+		if lecture.Module != "" {
+			skipped = lecture.Module
+		}
+		answer = "No more reminders for " + skipped + " today."
+		status = "⏭ Skipped today"
+	default:
+		status = "✅ Got it"
+	}
+	if action != models.AckGotIt {
+		h.scheduler.ScheduleReminders(ctx, user.ChatID)
+	}
+	h.answerCallback(ctx, callback, answer)
+
+	text := fmt.Sprintf("%s\n📚 %s at %s\n📍 %s", status, title, lecture.Start.Format("15:04"), lecture.Location)
+	if err := h.messenger.EditMessage(ctx, callback.ChatID, callback.MessageID, text, nil); err != nil {
+		logger.Error("failed to update reminder message", "error", err)
+	}
+}
+
+// parseReminderOffsets reads "[TYPE] [MODULE] MINUTES...", in any order, or
+// "off" in place of the minutes. It returns a message for the user when the
+// text can't be read.
+func parseReminderOffsets(text string) (sessionType, module string, minutes []int, problem string) {
+	off := false
+	for _, field := range strings.Fields(text) {
+		switch upper := strings.ToUpper(field); {
+		case strings.EqualFold(field, "off"):
+			off = true
+		case timetable.ModuleCode(upper) == upper:
+			if module != "" {
+				return "", "", nil, "Please give at most one module."
+			}
+			module = upper
+		case timetable.SessionType(field) != "":
+			if sessionType != "" {
+				return "", "", nil, "Please give at most one session type."
+			}
+			sessionType = timetable.SessionType(field)
+		default:
+			n, err := strconv.Atoi(field)
+			if err != nil {
+				return "", "", nil, fmt.Sprintf("%q is not a number of minutes, a session type or a module code.", field)
+			}
+			if n < 1 || n > utils.MaxReminderOffset {
+				return "", "", nil, fmt.Sprintf("Reminders must be 1-%d minutes before.", utils.MaxReminderOffset)
+			}
+			if !slices.Contains(minutes, n) {
+				minutes = append(minutes, n)
+			}
+		}
+	}
+	switch {
+	case off && len(minutes) > 0:
+		return "", "", nil, "Give either the minutes or off, not both."
+	case !off && len(minutes) == 0:
+		return "", "", nil, "Please give the minutes before the session to remind you."
+	case len(minutes) > maxReminderOffsets:
+		return "", "", nil, fmt.Sprintf("Please give at most %d reminders.", maxReminderOffsets)
+	}
+	return sessionType, module, minutes, ""
+}
+
+// remindersText lists offsets in the order they are returned by the store,
+// one line per session type and module.
+func remindersText(offsets []models.ReminderOffset, firstOnly bool) string {
+	var sb strings.Builder
+	sb.WriteString("Your reminders:")
+	if len(offsets) == 0 {
+		sb.WriteString("\nNone. Use /reminders 15 to be reminded 15 minutes before each session.")
+	}
+	specific := slices.ContainsFunc(offsets, func(offset models.ReminderOffset) bool {
+		return offset.Module != "" || offset.SessionType != ""
+	})
+	for i := 0; i < len(offsets); {
+		group := offsets[i]
+		var minutes []int
+		for ; i < len(offsets) && offsets[i].Module == group.Module && offsets[i].SessionType == group.SessionType; i++ {
+			minutes = append(minutes, offsets[i].Minutes)
+		}
+		fmt.Fprintf(&sb, "\n%s: %s before", reminderGroupName(group, specific), describeMinutes(minutes))
+	}
+	if firstOnly {
+		sb.WriteString("\nOnly the first session of each day is reminded. Turn this off in /settings.")
+	}
+	return sb.String()
+}
+
+// reminderGroupName names the sessions offset applies to. The general
+// offsets are for "Other sessions" when there are more specific ones.
+func reminderGroupName(offset models.ReminderOffset, specific bool) string {
+	switch {
+	case offset.Module != "" && offset.SessionType != "":
+		return offset.Module + " " + offset.SessionType + "s"
+	case offset.Module != "":
+		return offset.Module
+	case offset.SessionType != "":
+		return strings.ToUpper(offset.SessionType[:1]) + offset.SessionType[1:] + "s"
+	}
+	if specific {
+		return "Other sessions"
+	}
+	return "All sessions"
+}
+
+// describeMinutes writes minutes as "60, 30 and 10 minutes".
+func describeMinutes(minutes []int) string {
+	parts := make([]string, len(minutes))
+	for i, n := range minutes {
+		parts[i] = strconv.Itoa(n)
+	}
+	unit := " minutes"
+	if len(minutes) == 1 && minutes[0] == 1 {
+		unit = " minute"
+	}
+	if len(parts) < 2 {
+		return strings.Join(parts, "") + unit
+	}
+	return strings.Join(parts[:len(parts)-1], ", ") + " and " + parts[len(parts)-1] + unit
+}
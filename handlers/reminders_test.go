@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseReminderOffsets(t *testing.T) {
+	tests := []struct {
+		text        string
+		sessionType string
+		module      string
+		minutes     []int
+		problem     string // a substring of the message, or "" when valid
+	}{
+		{text: "15", minutes: []int{15}},
+		{text: "60 10", minutes: []int{60, 10}},
+		{text: "1 240", minutes: []int{1, 240}},
+		{text: "10 10", minutes: []int{10}},
+		{text: "lab 30", sessionType: "lab", minutes: []int{30}},
+		{text: "comp0010 30 5", module: "COMP0010", minutes: []int{30, 5}},
+		{text: "45 COMP0010 labs", sessionType: "lab", module: "COMP0010", minutes: []int{45}},
+		{text: "lab off", sessionType: "lab"},
+		{text: "0", problem: "1-240 minutes"},
+		{text: "241", problem: "1-240 minutes"},
+		{text: "-5", problem: "1-240 minutes"},
+		{text: "1 2 3 4 5", minutes: []int{1, 2, 3, 4, 5}},
+		{text: "1 2 3 4 5 6", problem: "at most 5 reminders"},
+		{text: "soon", problem: "is not a number"},
+		{text: "lab", problem: "give the minutes"},
+		{text: "", problem: "give the minutes"},
+		{text: "30 off", problem: "not both"},
+		{text: "COMP0010 COMP0002 30", problem: "at most one module"},
+		{text: "lab seminar 30", problem: "at most one session type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			sessionType, module, minutes, problem := parseReminderOffsets(tt.text)
+			if tt.problem != "" {
+				if !strings.Contains(problem, tt.problem) {
+					t.Errorf("problem = %q, want it to contain %q", problem, tt.problem)
+				}
+				return
+			}
+			if problem != "" {
+				t.Fatalf("unexpected problem %q", problem)
+			}
+			if sessionType != tt.sessionType || module != tt.module || !slices.Equal(minutes, tt.minutes) {
+				t.Errorf("got (%q, %q, %v), want (%q, %q, %v)", sessionType, module, minutes, tt.sessionType, tt.module, tt.minutes)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/logging"
+	"github.com/artem-streltsov/ucl-timetable-bot/messenger"
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+	"github.com/artem-streltsov/ucl-timetable-bot/timetable"
+)
+
+const (
+	maxRules         = 30
+	maxPatternLength = 100
+	maxTitleLength   = 100
+)
+
+// hide handles /hide. With an argument it adds a rule hiding the matching
+// lectures; without one it lists the user's rules.
+func (h *Handler) hide(ctx context.Context, user *models.User, args string) {
+	if args == "" {
+		h.sendRules(ctx, user, "Usage: /hide COMP0010 hides a module; /hide drop-in hides lectures whose title matches a regular expression.")
+		return
+	}
+	rule, problem := parseRuleTarget(args)
+	if problem != "" {
+		h.sendMessage(ctx, user.ChatID, problem)
+		return
+	}
+	rule.Action = models.RuleHide
+	h.addRule(ctx, user, rule)
+}
+
+// rename handles /rename PATTERN = TITLE.
+func (h *Handler) rename(ctx context.Context, user *models.User, args string) {
+	target, title, ok := strings.Cut(args, "=")
+	target, title = strings.TrimSpace(target), strings.TrimSpace(title)
+	if !ok || target == "" || title == "" {
+		h.sendRules(ctx, user, "Usage: /rename COMP0010 = Software Engineering, or /rename a title regular expression = New title.")
+		return
+	}
+	if len(title) > maxTitleLength {
+		h.sendMessage(ctx, user.ChatID, fmt.Sprintf("The new title must be at most %d characters.", maxTitleLength))
+		return
+	}
+	rule, problem := parseRuleTarget(target)
+	if problem != "" {
+		h.sendMessage(ctx, user.ChatID, problem)
+		return
+	}
+	rule.Action = models.RuleRename
+	rule.Replacement = title
+	h.addRule(ctx, user, rule)
+}
+
+// parseRuleTarget reads a module code or title pattern. It returns a
+// message for the user when the target is not valid.
+func parseRuleTarget(target string) (models.Rule, string) {
+	if code := strings.ToUpper(target); timetable.ModuleCode(code) == code {
+		return models.Rule{Match: models.MatchModule, Pattern: code}, ""
+	}
+	if len(target) > maxPatternLength {
+		return models.Rule{}, fmt.Sprintf("The pattern must be at most %d characters.", maxPatternLength)
+	}
+	if _, err := timetable.CompileTitlePattern(target); err != nil {
+		var syntaxErr *syntax.Error
+		if errors.As(err, &syntaxErr) {
+			return models.Rule{}, "Invalid regular expression: " + syntaxErr.Code.String() + "."
+		}
+		return models.Rule{}, "Invalid regular expression."
+	}
+	return models.Rule{Match: models.MatchTitle, Pattern: target}, ""
+}
+
+func (h *Handler) addRule(ctx context.Context, user *models.User, rule models.Rule) {
+	logger := logging.FromContext(ctx)
+	rules, err := h.db.GetRules(user.ChatID)
+	if err != nil {
+		logger.Error("failed to get rules", "error", err)
+		h.sendMessage(ctx, user.ChatID, "Error saving your rule. Please try again later.")
+		return
+	}
+	if len(rules) >= maxRules {
+		h.sendMessage(ctx, user.ChatID, fmt.Sprintf("You can have at most %d rules. Remove one with /hide first.", maxRules))
+		return
+	}
+
+	rule.ChatID = user.ChatID
+	if err := h.db.AddRule(&rule); err != nil {
+		logger.Error("failed to add rule", "error", err)
+		h.sendMessage(ctx, user.ChatID, "Error saving your rule. Please try again later.")
+		return
+	}
+	h.scheduler.ScheduleUser(ctx, user.ChatID)
+	h.sendMessage(ctx, user.ChatID, "Saved: "+describeRule(rule)+".\nUse /hide to see or remove your rules.")
+}
+
+// sendRules sends intro followed by the user's rules, each with a button to
+// remove it.
+func (h *Handler) sendRules(ctx context.Context, user *models.User, intro string) {
+	rules, err := h.db.GetRules(user.ChatID)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to get rules", "error", err)
+		h.sendMessage(ctx, user.ChatID, "Error fetching your rules.")
+		return
+	}
+	text, keyboard := rulesText(rules)
+	h.sendKeyboard(ctx, user.ChatID, intro+"\n\n"+text, keyboard)
+}
+
+func (h *Handler) handleDeleteRuleCallback(ctx context.Context, callback messenger.Callback) {
+	logger := logging.FromContext(ctx)
+	ruleID, err := strconv.ParseInt(strings.TrimPrefix(callback.Data, "rule_del_"), 10, 64)
+	if err != nil {
+		h.answerCallback(ctx, callback, "Invalid callback data.")
+		return
+	}
+	deleted, err := h.db.DeleteRule(callback.ChatID, ruleID)
+	if err != nil {
+		logger.Error("failed to delete rule", "error", err)
+		h.answerCallback(ctx, callback, "Error removing the rule.")
+		return
+	}
+	if deleted {
+		h.scheduler.ScheduleUser(ctx, callback.ChatID)
+		h.answerCallback(ctx, callback, "Rule removed.")
+	} else {
+		h.answerCallback(ctx, callback, "That rule was already removed.")
+	}
+
+	rules, err := h.db.GetRules(callback.ChatID)
+	if err != nil {
+		logger.Error("failed to get rules", "error", err)
+		return
+	}
+	text, keyboard := rulesText(rules)
+	if err := h.messenger.EditMessage(ctx, callback.ChatID, callback.MessageID, text, keyboard); err != nil {
+		logger.Error("failed to update rules message", "error", err)
+	}
+}
+
+func rulesText(rules []models.Rule) (string, messenger.Keyboard) {
+	if len(rules) == 0 {
+		return "You have no hide or rename rules.", nil
+	}
+	var sb strings.Builder
+	sb.WriteString("Your rules:")
+	var keyboard messenger.Keyboard
+	for i, rule := range rules {
+		fmt.Fprintf(&sb, "\n%d. %s", i+1, describeRule(rule))
+		keyboard = append(keyboard, []messenger.Button{{
+			Text: fmt.Sprintf("Remove %d", i+1),
+			Data: fmt.Sprintf("rule_del_%d", rule.ID),
+		}})
+	}
+	return sb.String(), keyboard
+}
+
+func describeRule(rule models.Rule) string {
+	target := rule.Pattern
+	if rule.Match == models.MatchTitle {
+		target = fmt.Sprintf("titles matching %q", rule.Pattern)
+	}
+	if rule.Action == models.RuleHide {
+		return "Hide " + target
+	}
+	return fmt.Sprintf("Rename %s to %q", target, rule.Replacement)
+}
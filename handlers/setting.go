@@ -1,65 +1,254 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
+	"strconv"
 	"strings"
 
+	"github.com/artem-streltsov/ucl-timetable-bot/logging"
+	"github.com/artem-streltsov/ucl-timetable-bot/messenger"
 	"github.com/artem-streltsov/ucl-timetable-bot/models"
 	"github.com/artem-streltsov/ucl-timetable-bot/utils"
 )
 
-func (h *Handler) settings(user *models.User) {
-	h.sendMessage(user.ChatID, fmt.Sprintf("Your settings:\nDaily notification time: %v\nWeekly notification day and time: %v\nReminder offset: %v minutes", user.DailyTime, user.WeeklyTime, user.ReminderOffset))
+// settingToggles lists the notification switches shown under /settings, in
+// order. Switches stored as "disabled" are inverted so every button reads
+// "on" when the notification is sent.
+var settingToggles = []struct {
+	key      string
+	label    string
+	field    func(user *models.User) *bool
+	inverted bool
+}{
+	{"daily", "Daily summary", func(user *models.User) *bool { return &user.DailyDisabled }, true},
+	{"weekly", "Weekly summary", func(user *models.User) *bool { return &user.WeeklyDisabled }, true},
+	{"reminders", "Lecture reminders", func(user *models.User) *bool { return &user.RemindersDisabled }, true},
+	{"leave", "Reminder to leave", func(user *models.User) *bool { return &user.LeaveDisabled }, true},
+	{"weekends", "Daily summary at weekends", func(user *models.User) *bool { return &user.DailyWeekends }, false},
+	{"skip_empty", "Skip days without lectures", func(user *models.User) *bool { return &user.DailySkipEmpty }, false},
+	{"first_only", "Remind of first session only", func(user *models.User) *bool { return &user.RemindersFirstOnly }, false},
+}
+
+func (h *Handler) settings(ctx context.Context, user *models.User) {
+	offsets, err := h.db.GetReminderOffsets(user.ChatID)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to get reminder offsets", "error", err)
+		h.sendMessage(ctx, user.ChatID, "Error fetching your settings.")
+		return
+	}
+	h.sendKeyboard(ctx, user.ChatID, settingsText(user, offsets), settingsKeyboard(user))
 	if user.WebCalURL == "" {
-		h.sendMessage(user.ChatID, "Your Calendar link is not set. Use /set_calendar to set it.")
+		h.sendMessage(ctx, user.ChatID, "Your Calendar link is not set. Use /set_calendar to set it.")
+	}
+}
+
+func (h *Handler) handleSettingsCallback(ctx context.Context, callback messenger.Callback) {
+	key := strings.TrimPrefix(callback.Data, "settings_")
+	user, err := h.db.GetUser(callback.ChatID)
+	if err != nil || user == nil {
+		if err != nil {
+			logging.FromContext(ctx).Error("failed to get user", "error", err)
+		}
+		h.answerCallback(ctx, callback, "Error fetching your data.")
+		return
+	}
+
+	for _, toggle := range settingToggles {
+		if toggle.key != key {
+			continue
+		}
+		field := toggle.field(user)
+		*field = !*field
+		if err := h.db.SaveUser(user); err != nil {
+			logging.FromContext(ctx).Error("failed to save user", "error", err)
+			h.answerCallback(ctx, callback, "Error saving your settings.")
+			return
+		}
+		h.scheduler.ScheduleUser(ctx, user.ChatID)
+		h.answerCallback(ctx, callback, "")
+		offsets, err := h.db.GetReminderOffsets(user.ChatID)
+		if err != nil {
+			logging.FromContext(ctx).Error("failed to get reminder offsets", "error", err)
+			return
+		}
+		if err := h.messenger.EditMessage(ctx, user.ChatID, callback.MessageID, settingsText(user, offsets), settingsKeyboard(user)); err != nil {
+			logging.FromContext(ctx).Error("failed to update settings message", "error", err)
+		}
+		return
+	}
+	h.answerCallback(ctx, callback, "Invalid callback data.")
+}
+
+func settingsText(user *models.User, offsets []models.ReminderOffset) string {
+	emailStatus := "not set"
+	if user.EmailVerified {
+		emailStatus = user.Email
+	}
+	moodleStatus := "off"
+	if user.MoodleURL != "" {
+		moodleStatus = "on"
+	}
+	pauseStatus := "on"
+	if user.Paused {
+		pauseStatus = "paused, use /resume to turn them back on"
+		if user.PausedUntil != nil {
+			pauseStatus = "paused until " + user.PausedUntil.In(utils.Location()).Format("Mon 02 Jan 2006")
+		}
+	}
+	var general []int
+	specific := false
+	for _, offset := range offsets {
+		if offset.Module == "" && offset.SessionType == "" {
+			general = append(general, offset.Minutes)
+		} else {
+			specific = true
+		}
+	}
+	reminderStatus := "none"
+	if len(general) > 0 {
+		reminderStatus = describeMinutes(general) + " before"
+	}
+	if specific {
+		reminderStatus += ", others set by /reminders"
+	}
+	eveningStatus := "off"
+	if user.EveningTime != "" {
+		eveningStatus = user.EveningTime
+	}
+	commuteStatus := "off"
+	if user.CommuteMinutes > 0 {
+		commuteStatus = fmt.Sprintf("%d minutes", user.CommuteMinutes)
+	}
+	return fmt.Sprintf("Your settings:\nNotifications: %v\nDaily notification time: %v (%v)\nEvening preview: %v\nCommute: %v\nWeekly notification day and time: %v (%v)\nReminders: %v (%v)\nEmail: %v\nMoodle deadline import: %v\n\nTap a button to turn a notification on or off.",
+		pauseStatus,
+		user.DailyTime, channelName(user.DailyChannel),
+		eveningStatus, commuteStatus,
+		user.WeeklyTime, channelName(user.WeeklyChannel),
+		reminderStatus, channelName(user.ReminderChannel),
+		emailStatus, moodleStatus)
+}
+
+func settingsKeyboard(user *models.User) messenger.Keyboard {
+	var keyboard messenger.Keyboard
+	for _, toggle := range settingToggles {
+		status := "off"
+		if *toggle.field(user) != toggle.inverted {
+			status = "on"
+		}
+		label := fmt.Sprintf("%s: %s", toggle.label, status)
+		keyboard = append(keyboard, []messenger.Button{{Text: label, Data: "settings_" + toggle.key}})
 	}
+	return keyboard
 }
 
-func (h *Handler) handleSetCalendar(user *models.User, text string) {
+func (h *Handler) handleSetCalendar(ctx context.Context, user *models.User, text string) {
 	if !strings.HasPrefix(strings.ToLower(text), "webcal://") {
-		h.sendMessage(user.ChatID, "Calendar link must start with webcal://")
+		h.sendMessage(ctx, user.ChatID, "Calendar link must start with webcal://")
 		return
 	}
 	user.WebCalURL = text
-	h.db.SaveUser(user)
-	h.scheduler.ScheduleUser(user.ChatID)
-	h.sendMessage(user.ChatID, "Calendar link saved.")
+	if !h.saveUser(ctx, user) {
+		return
+	}
+	h.scheduler.ScheduleUser(ctx, user.ChatID)
+	h.sendMessage(ctx, user.ChatID, "Calendar link saved.")
 	h.clearUserState(user.ChatID)
 }
 
-func (h *Handler) handleSetDailyTime(user *models.User, text string) {
+func (h *Handler) handleSetDailyTime(ctx context.Context, user *models.User, text string) {
 	if !utils.IsValidTime(text) {
-		h.sendMessage(user.ChatID, "Invalid format. Use HH:MM format.")
+		h.sendMessage(ctx, user.ChatID, "Invalid format. Use HH:MM format.")
 		return
 	}
 	user.DailyTime = text
-	h.db.SaveUser(user)
-	h.scheduler.ScheduleUser(user.ChatID)
-	h.sendMessage(user.ChatID, "Daily notification time saved.")
+	if !h.saveUser(ctx, user) {
+		return
+	}
+	h.scheduler.ScheduleUser(ctx, user.ChatID)
+	h.sendMessage(ctx, user.ChatID, "Daily notification time saved.")
 	h.clearUserState(user.ChatID)
 }
 
-func (h *Handler) handleSetWeeklyTime(user *models.User, text string) {
+func (h *Handler) handleSetWeeklyTime(ctx context.Context, user *models.User, text string) {
 	parts := strings.SplitN(text, " ", 2)
 	if len(parts) != 2 || !utils.IsValidDay(parts[0]) || !utils.IsValidTime(parts[1]) {
-		h.sendMessage(user.ChatID, "Invalid format. Use DAY HH:MM.")
+		h.sendMessage(ctx, user.ChatID, "Invalid format. Use DAY HH:MM.")
 		return
 	}
 	user.WeeklyTime = text
-	h.db.SaveUser(user)
-	h.scheduler.ScheduleUser(user.ChatID)
-	h.sendMessage(user.ChatID, "Weekly notification time saved.")
+	if !h.saveUser(ctx, user) {
+		return
+	}
+	h.scheduler.ScheduleUser(ctx, user.ChatID)
+	h.sendMessage(ctx, user.ChatID, "Weekly notification time saved.")
 	h.clearUserState(user.ChatID)
 }
 
-func (h *Handler) handleSetReminderOffset(user *models.User, text string) {
-	if !utils.IsValidOffset(text) {
-		h.sendMessage(user.ChatID, "Invalid format. Use MM format.")
+// maxCommuteMinutes is the longest commute /set_commute accepts.
+const maxCommuteMinutes = 180
+
+// setEvening handles /set_evening. The time can follow the command or come in
+// the next message.
+func (h *Handler) setEvening(ctx context.Context, user *models.User, args string) {
+	if args == "" {
+		h.updateUserState(user.ChatID, "set_evening")
+		h.sendMessage(ctx, user.ChatID, "Send the time to preview tomorrow's lectures the evening before. Example: 21:00, or off to stop.")
+		return
+	}
+	h.handleSetEvening(ctx, user, args)
+}
+
+func (h *Handler) handleSetEvening(ctx context.Context, user *models.User, text string) {
+	text = strings.TrimSpace(text)
+	reply := "Evening preview turned off."
+	switch {
+	case strings.EqualFold(text, "off"):
+		user.EveningTime = ""
+	case utils.IsValidTime(text):
+		user.EveningTime = text
+		reply = "Evening preview time saved. Tomorrow's lectures will be sent at " + text + " the evening before."
+	default:
+		h.sendMessage(ctx, user.ChatID, "Invalid format. Use HH:MM format, or off.")
+		return
+	}
+	if !h.saveUser(ctx, user) {
+		return
+	}
+	h.scheduler.ScheduleUser(ctx, user.ChatID)
+	h.sendMessage(ctx, user.ChatID, reply)
+	h.clearUserState(user.ChatID)
+}
+
+// setCommute handles /set_commute. The minutes can follow the command or
+// come in the next message.
+func (h *Handler) setCommute(ctx context.Context, user *models.User, args string) {
+	if args == "" {
+		h.updateUserState(user.ChatID, "set_commute")
+		h.sendMessage(ctx, user.ChatID, "Send how many minutes it takes you to get to your first lecture, to be told when to leave. Example: 30, or off to stop.")
+		return
+	}
+	h.handleSetCommute(ctx, user, args)
+}
+
+func (h *Handler) handleSetCommute(ctx context.Context, user *models.User, text string) {
+	text = strings.TrimSpace(text)
+	reply := "Leave reminder turned off."
+	if strings.EqualFold(text, "off") {
+		user.CommuteMinutes = 0
+	} else {
+		minutes, err := strconv.Atoi(text)
+		if err != nil || minutes < 1 || minutes > maxCommuteMinutes {
+			h.sendMessage(ctx, user.ChatID, fmt.Sprintf("Invalid format. Send 1-%d minutes, or off.", maxCommuteMinutes))
+			return
+		}
+		user.CommuteMinutes = minutes
+		reply = fmt.Sprintf("Commute saved. You will be told to leave %d minutes before your first lecture each day.", minutes)
+	}
+	if !h.saveUser(ctx, user) {
 		return
 	}
-	user.ReminderOffset = text
-	h.db.SaveUser(user)
-	h.scheduler.ScheduleUser(user.ChatID)
-	h.sendMessage(user.ChatID, "Reminder offset saved.")
+	h.scheduler.ScheduleUser(ctx, user.ChatID)
+	h.sendMessage(ctx, user.ChatID, reply)
 	h.clearUserState(user.ChatID)
 }
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/academic"
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+	"github.com/artem-streltsov/ucl-timetable-bot/utils"
+)
+
+// term handles /term, showing where today falls in the academic year.
+func (h *Handler) term(ctx context.Context, user *models.User) {
+	h.sendMessage(ctx, user.ChatID, termText(academic.Current(), time.Now().In(utils.Location())))
+}
+
+func termText(cal *academic.Calendar, now time.Time) string {
+	const dateFormat = "Mon 02 Jan"
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	daysUntil := func(date academic.Date) string {
+		days := int(date.Sub(today).Hours() / 24)
+		if days == 1 {
+			return "tomorrow"
+		}
+		return fmt.Sprintf("in %d days", days)
+	}
+
+	pos := cal.At(now)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "📅 *%s* (UCL week %d)\n", now.Format("Mon 02 Jan 2006"), cal.UCLWeek(now))
+	switch {
+	case !pos.Known():
+		sb.WriteString("The academic calendar has no dates for today.\n")
+		return sb.String()
+	case pos.Closure != nil:
+		fmt.Fprintf(&sb, "UCL is closed today: %s, until %s.\n", pos.Closure.Name, pos.Closure.End.Format(dateFormat))
+	case pos.Term != nil:
+		fmt.Fprintf(&sb, "%s, academic year %s.\n", pos.TermWeek(), pos.Year.Name)
+	default:
+		fmt.Fprintf(&sb, "Outside term, academic year %s.\n", pos.Year.Name)
+	}
+
+	if pos.Term != nil {
+		for _, monday := range pos.Term.ReadingWeeks {
+			if monday.After(today) {
+				fmt.Fprintf(&sb, "Reading week starts %s (%s).\n", monday.Format(dateFormat), daysUntil(monday))
+			}
+		}
+		end := pos.Term.End
+		if end.Equal(today) {
+			fmt.Fprintf(&sb, "%s ends today.\n", pos.Term.Name)
+		} else {
+			fmt.Fprintf(&sb, "%s ends %s (%s).\n", pos.Term.Name, end.Format(dateFormat), daysUntil(end))
+		}
+	}
+	if next := cal.NextTerm(now); next != nil {
+		fmt.Fprintf(&sb, "%s starts %s (%s).\n", next.Name, next.Start.Format(dateFormat), daysUntil(next.Start))
+	}
+	if closure := cal.NextClosure(now); closure != nil {
+		fmt.Fprintf(&sb, "Next closure: %s, %s", closure.Name, closure.Start.Format(dateFormat))
+		if !closure.End.Equal(closure.Start.Time) {
+			sb.WriteString(" - " + closure.End.Format(dateFormat))
+		}
+		sb.WriteString(".\n")
+	}
+	return sb.String()
+}
@@ -1,95 +1,184 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/artem-streltsov/ucl-timetable-bot/academic"
+	"github.com/artem-streltsov/ucl-timetable-bot/logging"
+	"github.com/artem-streltsov/ucl-timetable-bot/messenger"
 	"github.com/artem-streltsov/ucl-timetable-bot/models"
 	"github.com/artem-streltsov/ucl-timetable-bot/timetable"
+	"github.com/artem-streltsov/ucl-timetable-bot/utils"
 )
 
-func (h *Handler) today(user *models.User) {
-	h.sendTimetable(user, time.Now().In(ukLocation), time.Now(), "today")
+// maxRangeDays bounds /range so the reply fits in one message.
+const maxRangeDays = 14
+
+func (h *Handler) today(ctx context.Context, user *models.User) {
+	today := time.Now().In(utils.Location())
+	h.sendTimetable(ctx, user, today, today, "today")
 }
 
-func (h *Handler) tomorrow(user *models.User) {
-	tomorrow := time.Now().In(ukLocation).AddDate(0, 0, 1)
-	h.sendTimetable(user, tomorrow, tomorrow, "tomorrow")
+func (h *Handler) tomorrow(ctx context.Context, user *models.User) {
+	tomorrow := time.Now().In(utils.Location()).AddDate(0, 0, 1)
+	h.sendTimetable(ctx, user, tomorrow, tomorrow, "tomorrow")
 }
 
-func (h *Handler) week(user *models.User) {
-	now := time.Now().In(ukLocation)
-	weekday := now.Weekday()
+// day handles /day, showing the lectures on a date such as "3 Nov" or
+// "next tue".
+func (h *Handler) day(ctx context.Context, user *models.User, args string) {
+	date, ok := utils.ParseDate(args, time.Now().In(utils.Location()))
+	if !ok {
+		h.sendMessage(ctx, user.ChatID, "Usage: /day DATE. DATE can be 2026-11-03, 3 Nov, tue, next tue, tomorrow or in 3 days.")
+		return
+	}
+	h.sendTimetable(ctx, user, date, date, "on "+date.Format("Mon, 02 Jan"))
+}
 
-	var weekStart time.Time
-	var weekEnd time.Time
-	var period string
+// dateRange handles /range, showing the lectures between two dates.
+func (h *Handler) dateRange(ctx context.Context, user *models.User, args string) {
+	from, to, ok := utils.ParseDateRange(args, time.Now().In(utils.Location()))
+	if !ok {
+		h.sendMessage(ctx, user.ChatID, "Usage: /range FROM to TO, e.g. /range 3 Nov to 14 Nov or /range today next fri.")
+		return
+	}
+	if to.Before(from) {
+		from, to = to, from
+	}
+	if to.After(from.AddDate(0, 0, maxRangeDays-1)) {
+		h.sendMessage(ctx, user.ChatID, fmt.Sprintf("Ranges can be at most %d days long.", maxRangeDays))
+		return
+	}
+	h.sendTimetable(ctx, user, from, to, "between "+from.Format("Mon, 02 Jan")+" and "+to.Format("Mon, 02 Jan"))
+}
 
-	if weekday == time.Saturday || weekday == time.Sunday {
-		daysUntilMonday := (time.Monday + 7 - weekday) % 7
-		weekStart = now.AddDate(0, 0, int(daysUntilMonday))
-		period = "next week"
-	} else {
-		daysSinceMonday := (weekday - time.Monday + 7) % 7
-		weekStart = now.AddDate(0, 0, -int(daysSinceMonday))
-		period = "this week"
+// week handles /week, showing this week on weekdays and next week at the
+// weekend, with buttons to page through weeks.
+func (h *Handler) week(ctx context.Context, user *models.User) {
+	if !timetable.HasTimetable(h.db, user) {
+		h.sendMessage(ctx, user.ChatID, "Please set your calendar link using /set_calendar")
+		return
+	}
+	monday := defaultWeek(time.Now().In(utils.Location()))
+	text, ok := h.weekText(ctx, user, monday)
+	if !ok {
+		h.sendMessage(ctx, user.ChatID, text)
+		return
 	}
+	h.sendKeyboard(ctx, user.ChatID, text, weekKeyboard(monday))
+}
 
-	weekEnd = weekStart.AddDate(0, 0, 4) // Friday
+func (h *Handler) handleWeekCallback(ctx context.Context, callback messenger.Callback) {
+	monday := defaultWeek(time.Now().In(utils.Location()))
+	if date := strings.TrimPrefix(callback.Data, "week_"); date != "today" {
+		parsed, err := time.ParseInLocation(time.DateOnly, date, utils.Location())
+		if err != nil {
+			h.answerCallback(ctx, callback, "Invalid callback data.")
+			return
+		}
+		// Buttons always send a Monday, but a stale or hand-made callback
+		// may not, and the week must still run Monday to Friday.
+		monday = parsed.AddDate(0, 0, -(int(parsed.Weekday())+6)%7)
+	}
+	user, err := h.db.GetUser(callback.ChatID)
+	if err != nil || user == nil {
+		if err != nil {
+			logging.FromContext(ctx).Error("failed to get user", "error", err)
+		}
+		h.answerCallback(ctx, callback, "Error fetching your data.")
+		return
+	}
+	text, ok := h.weekText(ctx, user, monday)
+	if !ok {
+		h.answerCallback(ctx, callback, text)
+		return
+	}
+	h.answerCallback(ctx, callback, "")
+	if err := h.messenger.EditMessage(ctx, callback.ChatID, callback.MessageID, text, weekKeyboard(monday)); err != nil {
+		logging.FromContext(ctx).Error("failed to update week message", "error", err)
+	}
+}
+
+// weekText renders the Monday to Friday starting at monday, headed with the
+// term week and UCL week number. It reports false with an error message when the
+// timetable can't be fetched.
+func (h *Handler) weekText(ctx context.Context, user *models.User, monday time.Time) (string, bool) {
+	all, err := timetable.ForUser(h.db, user)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to fetch calendar", "error", err)
+		return "Error fetching calendar", false
+	}
+	cal := academic.Current()
+	header := fmt.Sprintf("📅 *UCL week %d*\n", cal.UCLWeek(monday))
+	if label := cal.WeekLabel(monday); label != "" {
+		header = fmt.Sprintf("📅 *%s* (UCL week %d)\n", label, cal.UCLWeek(monday))
+	}
+	period := "in the week of " + monday.Format("Mon, 02 Jan")
+	return header + timetableText(all, monday, monday.AddDate(0, 0, 4), period), true
+}
 
-	h.sendTimetable(user, weekStart, weekEnd, period)
+// defaultWeek returns the Monday /week starts on: this week's on weekdays
+// and next week's at the weekend.
+func defaultWeek(now time.Time) time.Time {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch weekday := today.Weekday(); weekday {
+	case time.Saturday, time.Sunday:
+		return today.AddDate(0, 0, int((time.Monday+7-weekday)%7))
+	default:
+		return today.AddDate(0, 0, -int(weekday-time.Monday))
+	}
+}
+
+func weekKeyboard(monday time.Time) messenger.Keyboard {
+	return messenger.Keyboard{{
+		{Text: "◀️", Data: "week_" + monday.AddDate(0, 0, -7).Format(time.DateOnly)},
+		{Text: "Today", Data: "week_today"},
+		{Text: "▶️", Data: "week_" + monday.AddDate(0, 0, 7).Format(time.DateOnly)},
+	}}
 }
 
-func (h *Handler) sendTimetable(user *models.User, startDate, endDate time.Time, period string) {
-	if user.WebCalURL == "" {
-		h.sendMessage(user.ChatID, "Please set your calendar link using /set_calendar")
+func (h *Handler) sendTimetable(ctx context.Context, user *models.User, startDate, endDate time.Time, period string) {
+	if !timetable.HasTimetable(h.db, user) {
+		h.sendMessage(ctx, user.ChatID, "Please set your calendar link using /set_calendar")
 		return
 	}
-	cal, err := timetable.FetchCalendar(user.WebCalURL)
+	all, err := timetable.ForUser(h.db, user)
 	if err != nil {
-		h.sendMessage(user.ChatID, "Error fetching calendar")
+		logging.FromContext(ctx).Error("failed to fetch calendar", "error", err)
+		h.sendMessage(ctx, user.ChatID, "Error fetching calendar")
 		return
 	}
+	h.sendMessage(ctx, user.ChatID, timetableText(all, startDate, endDate, period))
+}
 
-	if startDate.Day() == endDate.Day() {
-		lectures, err := timetable.GetLectures(cal, startDate)
-		if err != nil {
-			h.sendMessage(user.ChatID, "Error processing calendar")
-			return
-		}
+// timetableText renders the lectures from startDate to endDate, or says
+// there are none in period.
+func timetableText(all []timetable.Lecture, startDate, endDate time.Time, period string) string {
+	if startDate.Format(time.DateOnly) == endDate.Format(time.DateOnly) {
+		lectures := timetable.OnDay(all, startDate)
 		if len(lectures) == 0 {
-			h.sendMessage(user.ChatID, fmt.Sprintf("No lectures %s.", period))
-			return
+			return fmt.Sprintf("No lectures %s.", period)
 		}
 		dateStr := startDate.Format("Mon, 02 Jan")
-		message := fmt.Sprintf("*%s:*\n\n", dateStr) + timetable.FormatLectures(lectures)
-		h.sendMessage(user.ChatID, message)
-	} else {
-		lecturesMap, err := timetable.GetLecturesInRange(cal, startDate, endDate)
-		if err != nil {
-			h.sendMessage(user.ChatID, "Error processing calendar: "+err.Error())
-			return
-		}
-		if len(lecturesMap) == 0 {
-			h.sendMessage(user.ChatID, fmt.Sprintf("No lectures %s.", period))
-			return
-		}
-		startDateStr := startDate.Format("Mon, 02 Jan")
-		endDateStr := endDate.Format("Fri, 02 Jan")
-		dateRangeStr := fmt.Sprintf("*%s - %s:*\n\n", startDateStr, endDateStr)
-
-		var sb strings.Builder
-		sb.WriteString(dateRangeStr)
-		for day := startDate; !day.After(endDate); day = day.AddDate(0, 0, 1) {
-			dayKey := day.Format("Monday")
-			lectures, ok := lecturesMap[dayKey]
-			if ok {
-				sb.WriteString("\n" + "*" + dayKey + "*" + "\n")
-				message := timetable.FormatLectures(lectures)
-				sb.WriteString(message)
-			}
-		}
-		h.sendMessage(user.ChatID, sb.String())
+		return fmt.Sprintf("*%s:*\n\n", dateStr) + timetable.FormatLectures(lectures)
+	}
+
+	days := timetable.GroupByDay(all, startDate, endDate)
+	if len(days) == 0 {
+		return fmt.Sprintf("No lectures %s.", period)
+	}
+	startDateStr := startDate.Format("Mon, 02 Jan")
+	endDateStr := endDate.Format("Mon, 02 Jan")
+	dateRangeStr := fmt.Sprintf("*%s - %s:*\n\n", startDateStr, endDateStr)
+
+	var sb strings.Builder
+	sb.WriteString(dateRangeStr)
+	for _, day := range days {
+		sb.WriteString("\n" + "*" + day.Date.Format("Mon 02 Jan") + "*" + "\n")
+		sb.WriteString(timetable.FormatLectures(day.Lectures))
 	}
+	return sb.String()
 }
@@ -0,0 +1,59 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+type contextKey struct{}
+
+// New builds a logger writing to w. Format is "text" or "json"; level is one
+// of debug, info, warn or error.
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch strings.ToLower(format) {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q", format)
+	}
+}
+
+// FromContext returns the logger attached to ctx, or the default logger.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// With returns a context whose logger carries the given attributes.
+func With(ctx context.Context, args ...any) context.Context {
+	return context.WithValue(ctx, contextKey{}, FromContext(ctx).With(args...))
+}
+
+// WithCorrelationID starts a new unit of work, such as a Telegram update or a
+// scheduled job, tagging its logs with a fresh correlation ID and the chat ID.
+func WithCorrelationID(ctx context.Context, chatID int64) context.Context {
+	return With(ctx, "correlation_id", newCorrelationID(), "chat_id", chatID)
+}
+
+func newCorrelationID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
@@ -2,47 +2,110 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"flag"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/artem-streltsov/ucl-timetable-bot/academic"
 	"github.com/artem-streltsov/ucl-timetable-bot/bot"
 	"github.com/artem-streltsov/ucl-timetable-bot/config"
 	"github.com/artem-streltsov/ucl-timetable-bot/database"
+	"github.com/artem-streltsov/ucl-timetable-bot/feed"
+	"github.com/artem-streltsov/ucl-timetable-bot/logging"
+	"github.com/artem-streltsov/ucl-timetable-bot/metrics"
+	"github.com/artem-streltsov/ucl-timetable-bot/server"
+	"github.com/artem-streltsov/ucl-timetable-bot/timetable"
+	"github.com/artem-streltsov/ucl-timetable-bot/utils"
 )
 
 func main() {
-	cfg, err := config.Load()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return
+			}
+			if errors.Is(err, errMigrateUsage) {
+				os.Exit(2)
+			}
+			fatal("migration command failed", err)
+		}
+		return
+	}
+
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fatal("failed to load configuration", err)
+	}
+
+	logger, err := logging.New(os.Stderr, cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		fatal("failed to configure logging", err)
+	}
+	slog.SetDefault(logger)
+
+	location, err := time.LoadLocation(cfg.Timezone)
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		fatal("failed to load timezone", err)
+	}
+	utils.SetLocation(location)
+	timetable.SetCacheTTL(cfg.CacheTTL)
+	if err := academic.Load(cfg.AcademicCalendarPath); err != nil {
+		fatal("failed to load academic calendar", err)
 	}
 
-	db, err := database.New(cfg.DBPath)
+	db, err := database.Open(cfg.DBDriver, cfg.DSN(), cfg.MigrationsPath)
 	if err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+		fatal("failed to initialize database", err)
 	}
 	defer db.Close()
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	botInstance, err := bot.NewBot(cfg.TelegramBotToken, db)
+	botInstance, err := bot.NewBot(ctx, cfg, db)
 	if err != nil {
-		log.Fatalf("Failed to initialize bot: %v", err)
+		fatal("failed to initialize bot", err)
+	}
+
+	if cfg.HTTPListenAddr != "" {
+		metrics.RegisterGauges(botInstance.ActiveTimers, db.CountUsers)
+		srv := server.New(cfg.HTTPListenAddr,
+			server.Check{Name: "database", Run: db.Ping},
+			server.Check{Name: "telegram", Run: botInstance.Ping},
+		)
+		if cfg.PublicURL != "" {
+			srv.Handle(feed.Pattern, feed.NewHandler(db))
+		}
+		go func() {
+			if err := srv.Run(ctx); err != nil {
+				slog.Error("HTTP server stopped", "error", err)
+			}
+		}()
 	}
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
 	go func() {
-		<-sigChan
-		log.Println("Received shutdown signal")
+		sig := <-sigChan
+		slog.Info("received shutdown signal", "signal", sig.String())
 		cancel()
 	}()
 
 	if err := botInstance.Run(ctx); err != nil {
-		log.Fatalf("Bot stopped with error: %v", err)
+		fatal("bot stopped with error", err)
 	}
 
-	log.Println("Bot has been shut down")
+	slog.Info("bot has been shut down")
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,372 @@
+// Package matrix runs the bot on Matrix through the client-server API. The bot
+// joins rooms it is invited to as a direct chat and answers messages there;
+// commands start with / or !.
+//
+// Matrix has no buttons, so keyboards are rendered as a numbered list and a
+// reply of ! and one of the numbers, such as !2, acts as pressing that
+// button. A bare number is always a message, so answers like "30" to a
+// question are never mistaken for a choice from a menu sent since.
+package matrix
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/frontend"
+	"github.com/artem-streltsov/ucl-timetable-bot/messenger"
+)
+
+const (
+	syncTimeout  = 30 * time.Second
+	retryBackoff = 5 * time.Second
+)
+
+var boldPattern = regexp.MustCompile(`\*([^*\n]+)\*`)
+
+// menu is the keyboard of the latest message in a room that has one.
+type menu struct {
+	messageID string
+	buttons   []messenger.Button
+}
+
+// Matrix is both the messenger.Platform for Matrix and the front-end that
+// receives its events.
+type Matrix struct {
+	homeserver  string
+	accessToken string
+	client      *http.Client
+	userID      string
+	txnPrefix   string
+	txnCounter  atomic.Int64
+	menus       map[string]menu
+	mu          sync.Mutex
+}
+
+func New(homeserver, accessToken string) *Matrix {
+	return &Matrix{
+		homeserver:  strings.TrimSuffix(homeserver, "/"),
+		accessToken: accessToken,
+		client:      &http.Client{Timeout: syncTimeout + 30*time.Second},
+		txnPrefix:   strconv.FormatInt(time.Now().UnixNano(), 36),
+		menus:       make(map[string]menu),
+	}
+}
+
+var _ messenger.Platform = (*Matrix)(nil)
+
+type event struct {
+	Type     string          `json:"type"`
+	Sender   string          `json:"sender"`
+	EventID  string          `json:"event_id"`
+	StateKey *string         `json:"state_key"`
+	Content  json.RawMessage `json:"content"`
+}
+
+type syncResponse struct {
+	NextBatch string `json:"next_batch"`
+	Rooms     struct {
+		Join map[string]struct {
+			Timeline struct {
+				Events []event `json:"events"`
+			} `json:"timeline"`
+		} `json:"join"`
+		Invite map[string]struct {
+			InviteState struct {
+				Events []event `json:"events"`
+			} `json:"invite_state"`
+		} `json:"invite"`
+	} `json:"rooms"`
+}
+
+// Run syncs with the homeserver and passes messages to dispatcher until ctx
+// is canceled. Messages sent while the bot was offline are skipped.
+func (m *Matrix) Run(ctx context.Context, dispatcher *frontend.Dispatcher) error {
+	var whoami struct {
+		UserID string `json:"user_id"`
+	}
+	if err := m.do(ctx, http.MethodGet, "/account/whoami", nil, nil, &whoami); err != nil {
+		return fmt.Errorf("failed to connect to Matrix: %w", err)
+	}
+	m.userID = whoami.UserID
+	slog.Info("matrix front-end started", "user_id", m.userID)
+
+	since := ""
+	for {
+		resp, err := m.sync(ctx, since)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			slog.Warn("matrix sync failed", "error", err)
+			select {
+			case <-time.After(retryBackoff):
+				continue
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+		for roomID, room := range resp.Rooms.Invite {
+			m.handleInvite(ctx, roomID, room.InviteState.Events)
+		}
+		if since != "" {
+			for roomID, room := range resp.Rooms.Join {
+				for _, ev := range room.Timeline.Events {
+					m.handleEvent(ctx, dispatcher, roomID, ev)
+				}
+			}
+		}
+		since = resp.NextBatch
+	}
+}
+
+func (m *Matrix) sync(ctx context.Context, since string) (*syncResponse, error) {
+	query := url.Values{}
+	if since != "" {
+		query.Set("since", since)
+		query.Set("timeout", strconv.FormatInt(syncTimeout.Milliseconds(), 10))
+	}
+	var resp syncResponse
+	if err := m.do(ctx, http.MethodGet, "/sync", query, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// handleInvite joins rooms the bot is invited to as a direct chat and
+// declines the rest, so it never answers one user where others can read it.
+func (m *Matrix) handleInvite(ctx context.Context, roomID string, events []event) {
+	direct := false
+	for _, ev := range events {
+		if ev.Type != "m.room.member" || ev.StateKey == nil || *ev.StateKey != m.userID {
+			continue
+		}
+		var content struct {
+			IsDirect bool `json:"is_direct"`
+		}
+		if err := json.Unmarshal(ev.Content, &content); err == nil {
+			direct = content.IsDirect
+		}
+	}
+
+	action := "leave"
+	if direct {
+		action = "join"
+	}
+	if err := m.do(ctx, http.MethodPost, "/rooms/"+url.PathEscape(roomID)+"/"+action, nil, struct{}{}, nil); err != nil {
+		slog.Warn("failed to answer matrix invite", "room_id", roomID, "action", action, "error", err)
+	}
+}
+
+func (m *Matrix) handleEvent(ctx context.Context, dispatcher *frontend.Dispatcher, roomID string, ev event) {
+	if ev.Type != "m.room.message" || ev.Sender == m.userID {
+		return
+	}
+	var content struct {
+		MsgType   string `json:"msgtype"`
+		Body      string `json:"body"`
+		RelatesTo *struct {
+			RelType string `json:"rel_type"`
+		} `json:"m.relates_to"`
+	}
+	if err := json.Unmarshal(ev.Content, &content); err != nil || content.MsgType != "m.text" {
+		return
+	}
+	if content.RelatesTo != nil && content.RelatesTo.RelType == "m.replace" {
+		return
+	}
+
+	from := frontend.Sender{
+		ExternalID: ev.Sender,
+		Address:    roomID,
+		Username:   strings.TrimPrefix(ev.Sender, "@"),
+	}
+	body := strings.TrimSpace(content.Body)
+	if button, messageID, ok := m.choose(roomID, body); ok {
+		dispatcher.Callback(ctx, from, roomID, messageID, button.Data)
+		return
+	}
+	dispatcher.Message(ctx, from, body)
+}
+
+// choose returns the button picked by a reply such as !2 to the room's menu.
+func (m *Matrix) choose(roomID, body string) (messenger.Button, string, bool) {
+	digits, ok := strings.CutPrefix(body, "!")
+	if !ok {
+		return messenger.Button{}, "", false
+	}
+	n, err := strconv.Atoi(digits)
+	if err != nil {
+		return messenger.Button{}, "", false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	current, ok := m.menus[roomID]
+	if !ok || n < 1 || n > len(current.buttons) {
+		return messenger.Button{}, "", false
+	}
+	return current.buttons[n-1], current.messageID, true
+}
+
+func (m *Matrix) setMenu(roomID, messageID string, keyboard messenger.Keyboard) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(keyboard) == 0 {
+		if m.menus[roomID].messageID == messageID || messageID == "" {
+			delete(m.menus, roomID)
+		}
+		return
+	}
+	var buttons []messenger.Button
+	for _, row := range keyboard {
+		buttons = append(buttons, row...)
+	}
+	m.menus[roomID] = menu{messageID: messageID, buttons: buttons}
+}
+
+// SendText sends text to the room. Any menu shown earlier stops accepting
+// numbered replies, since the number may now be an answer to this message.
+func (m *Matrix) SendText(ctx context.Context, address string, text string) (string, error) {
+	id, err := m.send(ctx, address, messageContent("m.text", text))
+	if err == nil {
+		m.setMenu(address, "", nil)
+	}
+	return id, err
+}
+
+func (m *Matrix) SendKeyboard(ctx context.Context, address string, text string, keyboard messenger.Keyboard) (string, error) {
+	id, err := m.send(ctx, address, messageContent("m.text", withMenu(text, keyboard)))
+	if err == nil {
+		m.setMenu(address, id, keyboard)
+	}
+	return id, err
+}
+
+func (m *Matrix) EditMessage(ctx context.Context, address string, messageID string, text string, keyboard messenger.Keyboard) error {
+	newContent := messageContent("m.text", withMenu(text, keyboard))
+	content := messageContent("m.text", "* "+withMenu(text, keyboard))
+	content["m.new_content"] = newContent
+	content["m.relates_to"] = map[string]string{"rel_type": "m.replace", "event_id": messageID}
+	if _, err := m.send(ctx, address, content); err != nil {
+		return err
+	}
+	m.setMenu(address, messageID, keyboard)
+	return nil
+}
+
+// AnswerCallback posts text as a notice in the room. callbackID is the room
+// ID; a button press with nothing to say needs no answer on Matrix.
+func (m *Matrix) AnswerCallback(ctx context.Context, callbackID string, text string) error {
+	if text == "" {
+		return nil
+	}
+	_, err := m.send(ctx, callbackID, messageContent("m.notice", text))
+	return err
+}
+
+func (m *Matrix) send(ctx context.Context, roomID string, content map[string]any) (string, error) {
+	txnID := m.txnPrefix + "-" + strconv.FormatInt(m.txnCounter.Add(1), 10)
+	path := "/rooms/" + url.PathEscape(roomID) + "/send/m.room.message/" + url.PathEscape(txnID)
+	var resp struct {
+		EventID string `json:"event_id"`
+	}
+	if err := m.do(ctx, http.MethodPut, path, nil, content, &resp); err != nil {
+		return "", err
+	}
+	return resp.EventID, nil
+}
+
+// do calls the client-server API endpoint path, sending body and decoding
+// the response into out when they are not nil.
+func (m *Matrix) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	endpoint := m.homeserver + "/_matrix/client/v3" + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+m.accessToken)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			ErrCode string `json:"errcode"`
+			Error   string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.ErrCode != "" {
+			return fmt.Errorf("%s %s: %s: %s", method, path, apiErr.ErrCode, apiErr.Error)
+		}
+		return fmt.Errorf("%s %s: unexpected status %s", method, path, resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// messageContent builds a message event with a plain body and an HTML body
+// converted from the bot's Telegram-style Markdown.
+func messageContent(msgType, text string) map[string]any {
+	formatted := boldPattern.ReplaceAllString(html.EscapeString(text), "<b>$1</b>")
+	return map[string]any{
+		"msgtype":        msgType,
+		"body":           boldPattern.ReplaceAllString(text, "$1"),
+		"format":         "org.matrix.custom.html",
+		"formatted_body": strings.ReplaceAll(formatted, "\n", "<br>"),
+	}
+}
+
+// withMenu appends the keyboard's buttons to text as a numbered list.
+func withMenu(text string, keyboard messenger.Keyboard) string {
+	if len(keyboard) == 0 {
+		return text
+	}
+	var sb strings.Builder
+	sb.WriteString(text)
+	sb.WriteString("\n")
+	n := 0
+	for _, row := range keyboard {
+		for _, button := range row {
+			n++
+			fmt.Fprintf(&sb, "\n%d. %s", n, button.Text)
+		}
+	}
+	sb.WriteString("\n\nReply with ! and a number, e.g. !1, to choose.")
+	return sb.String()
+}
@@ -0,0 +1,49 @@
+package matrix
+
+import (
+	"testing"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/messenger"
+)
+
+func TestChoose(t *testing.T) {
+	m := New("https://matrix.example.org", "token")
+	m.setMenu("!room", "$menu", messenger.Keyboard{
+		{{Text: "Snooze", Data: "reminder_snooze_1"}, {Text: "Skip", Data: "reminder_skip_1"}},
+		{{Text: "Got it", Data: "reminder_ack_1"}},
+	})
+
+	tests := []struct {
+		room string
+		body string
+		want string
+	}{
+		{"!room", "!1", "reminder_snooze_1"},
+		{"!room", "!3", "reminder_ack_1"},
+		// A bare number answers a question, such as /set_commute's.
+		{"!room", "30", ""},
+		{"!room", "2", ""},
+		{"!room", "!4", ""},
+		{"!room", "!0", ""},
+		{"!room", "!help", ""},
+		{"!other", "!1", ""},
+	}
+	for _, tt := range tests {
+		button, messageID, ok := m.choose(tt.room, tt.body)
+		if got := button.Data; got != tt.want || ok != (tt.want != "") {
+			t.Errorf("choose(%q, %q) = %q, %v, want %q", tt.room, tt.body, got, ok, tt.want)
+		}
+		if ok && messageID != "$menu" {
+			t.Errorf("choose(%q, %q) message = %q, want $menu", tt.room, tt.body, messageID)
+		}
+	}
+}
+
+func TestTextClearsMenu(t *testing.T) {
+	m := New("https://matrix.example.org", "token")
+	m.setMenu("!room", "$menu", messenger.Keyboard{{{Text: "Refresh", Data: "next_refresh"}}})
+	m.setMenu("!room", "", nil)
+	if _, _, ok := m.choose("!room", "!1"); ok {
+		t.Error("menu still answers after a plain message was sent")
+	}
+}
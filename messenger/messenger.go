@@ -0,0 +1,37 @@
+// Package messenger decouples the bot's logic from the chat platform it talks
+// to. Message text uses Telegram-style Markdown (*bold*); adapters convert it
+// as their platform requires.
+package messenger
+
+import "context"
+
+// Button is an inline button that reports Data back as a Callback when
+// pressed.
+type Button struct {
+	Text string
+	Data string
+}
+
+// Keyboard is a grid of buttons, one slice per row.
+type Keyboard [][]Button
+
+// Callback is a button press.
+type Callback struct {
+	ID        string
+	ChatID    int64
+	MessageID string
+	Data      string
+}
+
+type Messenger interface {
+	// SendText sends text to chatID and returns the new message's ID.
+	SendText(ctx context.Context, chatID int64, text string) (string, error)
+	// SendKeyboard sends text with buttons attached below it.
+	SendKeyboard(ctx context.Context, chatID int64, text string, keyboard Keyboard) (string, error)
+	// EditMessage replaces the text and buttons of a message sent earlier.
+	// A nil keyboard removes the buttons. Leaving the message unchanged is
+	// not an error.
+	EditMessage(ctx context.Context, chatID int64, messageID string, text string, keyboard Keyboard) error
+	// AnswerCallback acknowledges a button press, optionally showing text.
+	AnswerCallback(ctx context.Context, callbackID string, text string) error
+}
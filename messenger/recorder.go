@@ -0,0 +1,107 @@
+package messenger
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// Message is a message captured by Recorder.
+type Message struct {
+	ID       string
+	ChatID   int64
+	Text     string
+	Keyboard Keyboard
+	Edited   bool
+}
+
+// Recorder is a Messenger that keeps everything sent through it, for tests
+// that exercise conversations without Telegram.
+type Recorder struct {
+	messages []Message
+	answered []string
+	nextID   int
+	mu       sync.Mutex
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+var _ Messenger = (*Recorder)(nil)
+
+func (r *Recorder) SendText(ctx context.Context, chatID int64, text string) (string, error) {
+	return r.record(chatID, text, nil), nil
+}
+
+func (r *Recorder) SendKeyboard(ctx context.Context, chatID int64, text string, keyboard Keyboard) (string, error) {
+	return r.record(chatID, text, keyboard), nil
+}
+
+func (r *Recorder) EditMessage(ctx context.Context, chatID int64, messageID string, text string, keyboard Keyboard) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.messages {
+		if r.messages[i].ID == messageID && r.messages[i].ChatID == chatID {
+			r.messages[i].Text = text
+			r.messages[i].Keyboard = keyboard
+			r.messages[i].Edited = true
+			return nil
+		}
+	}
+	return fmt.Errorf("message %s not found in chat %d", messageID, chatID)
+}
+
+func (r *Recorder) AnswerCallback(ctx context.Context, callbackID string, text string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.answered = append(r.answered, callbackID)
+	return nil
+}
+
+func (r *Recorder) record(chatID int64, text string, keyboard Keyboard) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	id := strconv.Itoa(r.nextID)
+	r.messages = append(r.messages, Message{ID: id, ChatID: chatID, Text: text, Keyboard: keyboard})
+	return id
+}
+
+// Messages returns every message sent to chatID, oldest first.
+func (r *Recorder) Messages(chatID int64) []Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var messages []Message
+	for _, msg := range r.messages {
+		if msg.ChatID == chatID {
+			messages = append(messages, msg)
+		}
+	}
+	return messages
+}
+
+// Last returns the most recent message sent to chatID.
+func (r *Recorder) Last(chatID int64) (Message, bool) {
+	messages := r.Messages(chatID)
+	if len(messages) == 0 {
+		return Message{}, false
+	}
+	return messages[len(messages)-1], true
+}
+
+// Answered returns the IDs of callbacks acknowledged so far.
+func (r *Recorder) Answered() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.answered...)
+}
+
+// Reset forgets everything recorded so far.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages = nil
+	r.answered = nil
+}
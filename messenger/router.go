@@ -0,0 +1,207 @@
+package messenger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/logging"
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+)
+
+// Platform sends messages on one chat platform. Unlike Messenger it addresses
+// recipients by the platform's own channel or room ID.
+type Platform interface {
+	SendText(ctx context.Context, address string, text string) (string, error)
+	SendKeyboard(ctx context.Context, address string, text string, keyboard Keyboard) (string, error)
+	EditMessage(ctx context.Context, address string, messageID string, text string, keyboard Keyboard) error
+	AnswerCallback(ctx context.Context, callbackID string, text string) error
+}
+
+type platformKey struct{}
+
+// WithPlatform records the platform an update arrived on, so replies to it
+// go back to that platform rather than to every linked account.
+func WithPlatform(ctx context.Context, platform string) context.Context {
+	return context.WithValue(ctx, platformKey{}, platform)
+}
+
+// PlatformFrom returns the platform recorded by WithPlatform, or "" when the
+// context is not handling an update.
+func PlatformFrom(ctx context.Context) string {
+	platform, _ := ctx.Value(platformKey{}).(string)
+	return platform
+}
+
+// QualifyID prefixes a message or callback ID with its platform so the Router
+// can send edits and answers back to it. Telegram IDs are left as they are.
+func QualifyID(platform, id string) string {
+	if platform == models.PlatformTelegram {
+		return id
+	}
+	return platform + ":" + id
+}
+
+func splitID(id string) (platform, rawID string) {
+	platform, rawID, ok := strings.Cut(id, ":")
+	if !ok {
+		return models.PlatformTelegram, id
+	}
+	return platform, rawID
+}
+
+// AccountLookup returns the accounts linked to a chat ID.
+type AccountLookup func(chatID int64) ([]models.Account, error)
+
+// Router is a Messenger that delivers to every platform a user has linked.
+// Replies to an update go only to the platform it came from; notifications
+// go to all of them.
+type Router struct {
+	platforms map[string]Platform
+	accounts  AccountLookup
+}
+
+// NewRouter creates a router that sends to Telegram chats through telegram
+// and looks up other accounts with accounts. Further platforms are added with
+// Register before the router is used.
+func NewRouter(telegram Messenger, accounts AccountLookup) *Router {
+	return &Router{
+		platforms: map[string]Platform{models.PlatformTelegram: telegramPlatform{telegram}},
+		accounts:  accounts,
+	}
+}
+
+func (r *Router) Register(platform string, p Platform) {
+	r.platforms[platform] = p
+}
+
+var _ Messenger = (*Router)(nil)
+
+func (r *Router) SendText(ctx context.Context, chatID int64, text string) (string, error) {
+	return r.sendAll(ctx, chatID, func(p Platform, address string) (string, error) {
+		return p.SendText(ctx, address, text)
+	})
+}
+
+func (r *Router) SendKeyboard(ctx context.Context, chatID int64, text string, keyboard Keyboard) (string, error) {
+	return r.sendAll(ctx, chatID, func(p Platform, address string) (string, error) {
+		return p.SendKeyboard(ctx, address, text, keyboard)
+	})
+}
+
+func (r *Router) EditMessage(ctx context.Context, chatID int64, messageID string, text string, keyboard Keyboard) error {
+	platform, rawID := splitID(messageID)
+	targets, err := r.targets(WithPlatform(ctx, platform), chatID)
+	if err != nil {
+		return err
+	}
+	for _, account := range targets {
+		if account.Platform == platform {
+			return r.platforms[platform].EditMessage(ctx, account.Address, rawID, text, keyboard)
+		}
+	}
+	return fmt.Errorf("chat %d has no %s account", chatID, platform)
+}
+
+func (r *Router) AnswerCallback(ctx context.Context, callbackID string, text string) error {
+	platform, rawID := splitID(callbackID)
+	p, ok := r.platforms[platform]
+	if !ok {
+		return fmt.Errorf("unknown platform %q", platform)
+	}
+	return p.AnswerCallback(ctx, rawID, text)
+}
+
+// sendAll sends to each target and returns the first message ID. It fails
+// only when no platform accepted the message.
+func (r *Router) sendAll(ctx context.Context, chatID int64, send func(p Platform, address string) (string, error)) (string, error) {
+	targets, err := r.targets(ctx, chatID)
+	if err != nil {
+		return "", err
+	}
+
+	var messageID string
+	var errs []error
+	for _, account := range targets {
+		id, err := send(r.platforms[account.Platform], account.Address)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", account.Platform, err))
+			continue
+		}
+		if messageID == "" {
+			messageID = QualifyID(account.Platform, id)
+		}
+	}
+	if messageID == "" {
+		return "", errors.Join(errs...)
+	}
+	for _, err := range errs {
+		logging.FromContext(ctx).Warn("failed to deliver to a linked account", "recipient", chatID, "error", err)
+	}
+	return messageID, nil
+}
+
+// targets lists the accounts a message to chatID goes to.
+func (r *Router) targets(ctx context.Context, chatID int64) ([]models.Account, error) {
+	var all []models.Account
+	if !models.IsSyntheticChatID(chatID) {
+		id := strconv.FormatInt(chatID, 10)
+		all = append(all, models.Account{Platform: models.PlatformTelegram, ExternalID: id, Address: id, ChatID: chatID})
+	}
+	accounts, err := r.accounts(chatID)
+	if err != nil {
+		return nil, err
+	}
+	for _, account := range accounts {
+		if _, ok := r.platforms[account.Platform]; ok {
+			all = append(all, account)
+		}
+	}
+	if len(all) == 0 {
+		return nil, fmt.Errorf("chat %d has no reachable account", chatID)
+	}
+
+	if from := PlatformFrom(ctx); from != "" {
+		for _, account := range all {
+			if account.Platform == from {
+				return []models.Account{account}, nil
+			}
+		}
+	}
+	return all, nil
+}
+
+// telegramPlatform addresses a Telegram Messenger by chat ID string.
+type telegramPlatform struct {
+	m Messenger
+}
+
+func (t telegramPlatform) SendText(ctx context.Context, address string, text string) (string, error) {
+	chatID, err := strconv.ParseInt(address, 10, 64)
+	if err != nil {
+		return "", err
+	}
+	return t.m.SendText(ctx, chatID, text)
+}
+
+func (t telegramPlatform) SendKeyboard(ctx context.Context, address string, text string, keyboard Keyboard) (string, error) {
+	chatID, err := strconv.ParseInt(address, 10, 64)
+	if err != nil {
+		return "", err
+	}
+	return t.m.SendKeyboard(ctx, chatID, text, keyboard)
+}
+
+func (t telegramPlatform) EditMessage(ctx context.Context, address string, messageID string, text string, keyboard Keyboard) error {
+	chatID, err := strconv.ParseInt(address, 10, 64)
+	if err != nil {
+		return err
+	}
+	return t.m.EditMessage(ctx, chatID, messageID, text, keyboard)
+}
+
+func (t telegramPlatform) AnswerCallback(ctx context.Context, callbackID string, text string) error {
+	return t.m.AnswerCallback(ctx, callbackID, text)
+}
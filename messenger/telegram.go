@@ -0,0 +1,103 @@
+package messenger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/utils"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Telegram sends messages through the Telegram Bot API.
+type Telegram struct {
+	api *tgbotapi.BotAPI
+}
+
+func NewTelegram(api *tgbotapi.BotAPI) *Telegram {
+	return &Telegram{api: api}
+}
+
+var _ Messenger = (*Telegram)(nil)
+
+func (t *Telegram) SendText(ctx context.Context, chatID int64, text string) (string, error) {
+	msg := tgbotapi.NewMessage(chatID, utils.EscapeUnderscores(text))
+	msg.ParseMode = tgbotapi.ModeMarkdown
+	return t.send(msg)
+}
+
+func (t *Telegram) SendKeyboard(ctx context.Context, chatID int64, text string, keyboard Keyboard) (string, error) {
+	msg := tgbotapi.NewMessage(chatID, utils.EscapeUnderscores(text))
+	msg.ParseMode = tgbotapi.ModeMarkdown
+	msg.ReplyMarkup = inlineKeyboard(keyboard)
+	return t.send(msg)
+}
+
+func (t *Telegram) EditMessage(ctx context.Context, chatID int64, messageID string, text string, keyboard Keyboard) error {
+	id, err := strconv.Atoi(messageID)
+	if err != nil {
+		return fmt.Errorf("invalid Telegram message ID %q", messageID)
+	}
+	edit := tgbotapi.NewEditMessageText(chatID, id, utils.EscapeUnderscores(text))
+	edit.ParseMode = tgbotapi.ModeMarkdown
+	if keyboard != nil {
+		markup := inlineKeyboard(keyboard)
+		edit.ReplyMarkup = &markup
+	}
+	_, err = t.api.Request(edit)
+	if isNotModified(err) {
+		// The message already reads the same, e.g. /next refreshed twice
+		// within a minute, which is what the caller wanted.
+		return nil
+	}
+	return err
+}
+
+// isNotModified reports whether err is Telegram refusing an edit that would
+// leave the message unchanged.
+func isNotModified(err error) bool {
+	var apiErr *tgbotapi.Error
+	return errors.As(err, &apiErr) && strings.Contains(apiErr.Message, "message is not modified")
+}
+
+func (t *Telegram) AnswerCallback(ctx context.Context, callbackID string, text string) error {
+	_, err := t.api.Request(tgbotapi.NewCallback(callbackID, text))
+	return err
+}
+
+func (t *Telegram) send(msg tgbotapi.MessageConfig) (string, error) {
+	sent, err := t.api.Send(msg)
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(sent.MessageID), nil
+}
+
+func inlineKeyboard(keyboard Keyboard) tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(keyboard))
+	for _, row := range keyboard {
+		buttons := make([]tgbotapi.InlineKeyboardButton, 0, len(row))
+		for _, button := range row {
+			buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(button.Text, button.Data))
+		}
+		rows = append(rows, buttons)
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// CallbackFromTelegram converts a Telegram callback query. It returns false
+// when there is no query or it did not come from a message the bot sent.
+func CallbackFromTelegram(query *tgbotapi.CallbackQuery) (Callback, bool) {
+	if query == nil || query.Message == nil || query.Message.Chat == nil {
+		return Callback{}, false
+	}
+	return Callback{
+		ID:        query.ID,
+		ChatID:    query.Message.Chat.ID,
+		MessageID: strconv.Itoa(query.Message.MessageID),
+		Data:      query.Data,
+	}, true
+}
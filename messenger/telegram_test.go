@@ -0,0 +1,53 @@
+package messenger
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// fakeTelegram answers getMe and replies to editMessageText with editReply.
+func fakeTelegram(t *testing.T, editReply string) *Telegram {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/getMe"):
+			w.Write([]byte(`{"ok":true,"result":{"id":1,"is_bot":true,"first_name":"bot","username":"bot"}}`))
+		case strings.HasSuffix(r.URL.Path, "/editMessageText"):
+			w.Write([]byte(editReply))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	api, err := tgbotapi.NewBotAPIWithAPIEndpoint("token", server.URL+"/bot%s/%s")
+	if err != nil {
+		t.Fatalf("NewBotAPIWithAPIEndpoint: %v", err)
+	}
+	return NewTelegram(api)
+}
+
+func TestTelegramEditMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   string
+		wantErr bool
+	}{
+		{"edited", `{"ok":true,"result":{"message_id":5}}`, false},
+		{"not modified", `{"ok":false,"error_code":400,"description":"Bad Request: message is not modified: specified new message content and reply markup are exactly the same as a current content and reply markup of the message"}`, false},
+		{"not found", `{"ok":false,"error_code":400,"description":"Bad Request: message to edit not found"}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tg := fakeTelegram(t, tt.reply)
+			err := tg.EditMessage(context.Background(), 1, "5", "No upcoming lectures.", nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("EditMessage = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"log/slog"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "ucl_timetable_bot"
+
+var (
+	UpdatesProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "updates_processed_total",
+		Help:      "Telegram updates processed, by command.",
+	}, []string{"command"})
+
+	CalendarFetchDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "calendar_fetch_duration_seconds",
+		Help:      "Time taken to fetch and parse a calendar feed.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	CalendarFetchErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "calendar_fetch_errors_total",
+		Help:      "Calendar feeds that could not be fetched or parsed.",
+	})
+
+	MessagesSent = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_sent_total",
+		Help:      "Messages successfully sent to users.",
+	})
+
+	MessagesFailed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_failed_total",
+		Help:      "Messages that could not be sent to users.",
+	})
+)
+
+// RegisterGauges exposes values that are read on every scrape rather than
+// updated as events happen.
+func RegisterGauges(scheduledTimers func() int, registeredUsers func() (int, error)) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "scheduled_timers",
+		Help:      "Notification timers currently scheduled.",
+	}, func() float64 {
+		return float64(scheduledTimers())
+	})
+
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "registered_users",
+		Help:      "Users registered with the bot.",
+	}, func() float64 {
+		count, err := registeredUsers()
+		if err != nil {
+			slog.Error("failed to count users", "error", err)
+			return 0
+		}
+		return float64(count)
+	})
+}
+
+// ObserveSend records the outcome of sending a message.
+func ObserveSend(err error) {
+	if err != nil {
+		MessagesFailed.Inc()
+		return
+	}
+	MessagesSent.Inc()
+}
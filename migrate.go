@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/config"
+	"github.com/artem-streltsov/ucl-timetable-bot/database"
+)
+
+const migrateUsage = `usage: ucl-timetable-bot migrate [flags] <command>
+
+commands:
+  up [N]      apply all pending migrations, or the next N
+  down [N]    roll back the last N migrations (default 1)
+  version     print the current schema version
+  force V     set the schema version to V without running migrations`
+
+var errMigrateUsage = errors.New("invalid migrate usage")
+
+func runMigrate(args []string) error {
+	cfg, rest, err := config.LoadMigrate(args)
+	if err != nil {
+		return err
+	}
+	if len(rest) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return errMigrateUsage
+	}
+
+	m, err := database.NewMigrator(cfg.DBDriver, cfg.DSN(), cfg.MigrationsPath)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	command, params := rest[0], rest[1:]
+	switch command {
+	case "up":
+		steps, err := optionalCount(params, 0)
+		if err != nil {
+			return err
+		}
+		if err := m.Up(steps); err != nil {
+			return err
+		}
+	case "down":
+		steps, err := optionalCount(params, 1)
+		if err != nil {
+			return err
+		}
+		if err := m.Down(steps); err != nil {
+			return err
+		}
+	case "version":
+	case "force":
+		if len(params) != 1 {
+			return errors.New("force requires a version")
+		}
+		version, err := strconv.Atoi(params[0])
+		if err != nil {
+			return fmt.Errorf("invalid version %q", params[0])
+		}
+		if err := m.Force(version); err != nil {
+			return err
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown migrate command %q\n%s\n", command, migrateUsage)
+		return errMigrateUsage
+	}
+
+	version, dirty, err := m.Version()
+	if err != nil {
+		return err
+	}
+	if dirty {
+		fmt.Printf("version %d (dirty)\n", version)
+	} else {
+		fmt.Printf("version %d\n", version)
+	}
+	return nil
+}
+
+func optionalCount(params []string, fallback int) (int, error) {
+	if len(params) == 0 {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(params[0])
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid step count %q", params[0])
+	}
+	return n, nil
+}
@@ -0,0 +1,17 @@
+// Package migrations embeds the SQL schema migrations so the binary can run
+// them regardless of its working directory. Each database driver has its own
+// directory; version numbers are kept in step across them.
+package migrations
+
+import "embed"
+
+//go:embed sqlite/*.sql postgres/*.sql
+var FS embed.FS
+
+// Dir returns the directory in FS holding the migrations for driver.
+func Dir(driver string) string {
+	if driver == "postgres" {
+		return "postgres"
+	}
+	return "sqlite"
+}
@@ -0,0 +1,6 @@
+CREATE TABLE users (
+    chat_id BIGINT PRIMARY KEY,
+    webcal_url TEXT,
+    daily_time TEXT,
+    weekly_time TEXT
+);
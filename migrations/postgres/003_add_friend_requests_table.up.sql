@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS friend_requests (
+    requestor_id BIGINT NOT NULL,
+    requestee_id BIGINT NOT NULL,
+    PRIMARY KEY (requestor_id, requestee_id),
+    CONSTRAINT fk_requestor FOREIGN KEY (requestor_id) REFERENCES users(chat_id) ON DELETE CASCADE,
+    CONSTRAINT fk_requestee FOREIGN KEY (requestee_id) REFERENCES users(chat_id) ON DELETE CASCADE,
+    CONSTRAINT chk_requestor_requestee CHECK (requestor_id <> requestee_id)
+);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS friends (
+    user_id1 BIGINT NOT NULL,
+    user_id2 BIGINT NOT NULL,
+    PRIMARY KEY (user_id1, user_id2),
+    CONSTRAINT fk_user1 FOREIGN KEY (user_id1) REFERENCES users(chat_id) ON DELETE CASCADE,
+    CONSTRAINT fk_user2 FOREIGN KEY (user_id2) REFERENCES users(chat_id) ON DELETE CASCADE,
+    CONSTRAINT chk_user_order CHECK (user_id1 < user_id2)
+);
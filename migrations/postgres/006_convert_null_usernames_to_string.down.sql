@@ -0,0 +1 @@
+UPDATE users SET username = NULL WHERE username = '';
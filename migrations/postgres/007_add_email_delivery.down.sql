@@ -0,0 +1,5 @@
+ALTER TABLE users DROP COLUMN reminder_channel;
+ALTER TABLE users DROP COLUMN weekly_channel;
+ALTER TABLE users DROP COLUMN daily_channel;
+ALTER TABLE users DROP COLUMN email_verified;
+ALTER TABLE users DROP COLUMN email;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_accounts_chat_id;
+DROP TABLE IF EXISTS accounts;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS accounts (
+    platform TEXT NOT NULL,
+    external_id TEXT NOT NULL,
+    address TEXT NOT NULL,
+    chat_id BIGINT NOT NULL,
+    PRIMARY KEY (platform, external_id)
+);
+CREATE INDEX IF NOT EXISTS idx_accounts_chat_id ON accounts(chat_id);
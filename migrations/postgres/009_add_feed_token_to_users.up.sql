@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN feed_token TEXT NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_feed_token ON users(feed_token) WHERE feed_token <> '';
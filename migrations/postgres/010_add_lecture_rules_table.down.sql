@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_lecture_rules_chat_id;
+DROP TABLE IF EXISTS lecture_rules;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS lecture_rules (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    action TEXT NOT NULL,
+    match_on TEXT NOT NULL,
+    pattern TEXT NOT NULL,
+    replacement TEXT NOT NULL DEFAULT '',
+    CONSTRAINT fk_lecture_rules_user FOREIGN KEY (chat_id) REFERENCES users(chat_id) ON DELETE CASCADE,
+    CONSTRAINT chk_action CHECK (action IN ('hide', 'rename')),
+    CONSTRAINT chk_match_on CHECK (match_on IN ('module', 'title'))
+);
+CREATE INDEX IF NOT EXISTS idx_lecture_rules_chat_id ON lecture_rules(chat_id);
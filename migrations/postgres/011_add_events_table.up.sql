@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS events (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    title TEXT NOT NULL,
+    location TEXT NOT NULL DEFAULT '',
+    start_at TIMESTAMPTZ NOT NULL,
+    end_at TIMESTAMPTZ NOT NULL,
+    weekly BOOLEAN NOT NULL DEFAULT FALSE,
+    CONSTRAINT fk_events_user FOREIGN KEY (chat_id) REFERENCES users(chat_id) ON DELETE CASCADE,
+    CONSTRAINT chk_event_end CHECK (end_at >= start_at)
+);
+CREATE INDEX IF NOT EXISTS idx_events_chat_id ON events(chat_id);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS deadlines (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    module TEXT NOT NULL,
+    title TEXT NOT NULL,
+    due_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT fk_deadlines_user FOREIGN KEY (chat_id) REFERENCES users(chat_id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_deadlines_chat_id ON deadlines(chat_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_deadlines_chat_id_uid;
+ALTER TABLE deadlines DROP COLUMN uid;
+ALTER TABLE users DROP COLUMN moodle_url;
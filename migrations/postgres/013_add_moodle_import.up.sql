@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN moodle_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE deadlines ADD COLUMN uid TEXT NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_deadlines_chat_id_uid ON deadlines(chat_id, uid) WHERE uid <> '';
@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN paused_until;
+ALTER TABLE users DROP COLUMN paused;
@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN paused BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN paused_until TIMESTAMPTZ;
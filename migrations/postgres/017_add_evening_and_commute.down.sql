@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN commute_minutes;
+ALTER TABLE users DROP COLUMN evening_time;
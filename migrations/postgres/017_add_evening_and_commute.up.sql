@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN evening_time TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN commute_minutes INTEGER NOT NULL DEFAULT 0;
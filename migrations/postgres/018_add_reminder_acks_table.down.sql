@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_reminder_acks_chat_id_starts_at;
+DROP TABLE IF EXISTS reminder_acks;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS reminder_acks (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    lecture_uid TEXT NOT NULL,
+    module TEXT NOT NULL DEFAULT '',
+    title TEXT NOT NULL,
+    starts_at TIMESTAMPTZ NOT NULL,
+    action TEXT NOT NULL,
+    acked_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT fk_reminder_acks_user FOREIGN KEY (chat_id) REFERENCES users(chat_id) ON DELETE CASCADE,
+    CONSTRAINT chk_ack_action CHECK (action IN ('got_it', 'snooze', 'skip'))
+);
+CREATE INDEX IF NOT EXISTS idx_reminder_acks_chat_id_starts_at ON reminder_acks(chat_id, starts_at);
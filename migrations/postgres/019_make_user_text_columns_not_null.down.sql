@@ -0,0 +1,9 @@
+ALTER TABLE users
+    ALTER COLUMN webcal_url DROP NOT NULL,
+    ALTER COLUMN webcal_url DROP DEFAULT,
+    ALTER COLUMN daily_time DROP NOT NULL,
+    ALTER COLUMN daily_time DROP DEFAULT,
+    ALTER COLUMN weekly_time DROP NOT NULL,
+    ALTER COLUMN weekly_time DROP DEFAULT,
+    ALTER COLUMN username DROP NOT NULL,
+    ALTER COLUMN username DROP DEFAULT;
@@ -0,0 +1,13 @@
+UPDATE users SET webcal_url = '' WHERE webcal_url IS NULL;
+UPDATE users SET daily_time = '' WHERE daily_time IS NULL;
+UPDATE users SET weekly_time = '' WHERE weekly_time IS NULL;
+UPDATE users SET username = '' WHERE username IS NULL;
+ALTER TABLE users
+    ALTER COLUMN webcal_url SET DEFAULT '',
+    ALTER COLUMN webcal_url SET NOT NULL,
+    ALTER COLUMN daily_time SET DEFAULT '',
+    ALTER COLUMN daily_time SET NOT NULL,
+    ALTER COLUMN weekly_time SET DEFAULT '',
+    ALTER COLUMN weekly_time SET NOT NULL,
+    ALTER COLUMN username SET DEFAULT '',
+    ALTER COLUMN username SET NOT NULL;
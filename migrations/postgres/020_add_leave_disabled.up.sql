@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN leave_disabled BOOLEAN NOT NULL DEFAULT FALSE;
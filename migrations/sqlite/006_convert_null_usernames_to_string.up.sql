@@ -0,0 +1 @@
+UPDATE users SET username = '' WHERE username IS NULL;
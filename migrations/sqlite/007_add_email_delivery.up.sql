@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN email TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN daily_channel TEXT NOT NULL DEFAULT 'telegram';
+ALTER TABLE users ADD COLUMN weekly_channel TEXT NOT NULL DEFAULT 'telegram';
+ALTER TABLE users ADD COLUMN reminder_channel TEXT NOT NULL DEFAULT 'telegram';
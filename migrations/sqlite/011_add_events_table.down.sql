@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_events_chat_id;
+DROP TABLE IF EXISTS events;
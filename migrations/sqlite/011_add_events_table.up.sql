@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    chat_id INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    location TEXT NOT NULL DEFAULT '',
+    start_at TIMESTAMP NOT NULL,
+    end_at TIMESTAMP NOT NULL,
+    weekly BOOLEAN NOT NULL DEFAULT 0,
+    CONSTRAINT chk_event_end CHECK (end_at >= start_at)
+);
+CREATE INDEX IF NOT EXISTS idx_events_chat_id ON events(chat_id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_deadlines_chat_id;
+DROP TABLE IF EXISTS deadlines;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS deadlines (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    chat_id INTEGER NOT NULL,
+    module TEXT NOT NULL,
+    title TEXT NOT NULL,
+    due_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_deadlines_chat_id ON deadlines(chat_id);
@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN paused BOOLEAN NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN paused_until TIMESTAMP;
@@ -0,0 +1,5 @@
+ALTER TABLE users DROP COLUMN daily_skip_empty;
+ALTER TABLE users DROP COLUMN daily_weekends;
+ALTER TABLE users DROP COLUMN reminders_disabled;
+ALTER TABLE users DROP COLUMN weekly_disabled;
+ALTER TABLE users DROP COLUMN daily_disabled;
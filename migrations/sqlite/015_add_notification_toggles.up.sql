@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN daily_disabled BOOLEAN NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN weekly_disabled BOOLEAN NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN reminders_disabled BOOLEAN NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN daily_weekends BOOLEAN NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN daily_skip_empty BOOLEAN NOT NULL DEFAULT 0;
@@ -0,0 +1,7 @@
+ALTER TABLE users DROP COLUMN reminders_first_only;
+ALTER TABLE users ADD COLUMN reminder_offset TEXT;
+UPDATE users SET reminder_offset = COALESCE(
+    (SELECT CAST(MAX(minutes) AS TEXT) FROM reminder_offsets
+        WHERE reminder_offsets.chat_id = users.chat_id AND session_type = '' AND module = ''),
+    '15');
+DROP TABLE IF EXISTS reminder_offsets;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS reminder_offsets (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    chat_id INTEGER NOT NULL,
+    minutes INTEGER NOT NULL,
+    session_type TEXT NOT NULL DEFAULT '',
+    module TEXT NOT NULL DEFAULT '',
+    CONSTRAINT chk_minutes CHECK (minutes > 0),
+    CONSTRAINT uq_reminder_offset UNIQUE (chat_id, session_type, module, minutes)
+);
+INSERT INTO reminder_offsets (chat_id, minutes)
+    SELECT chat_id, CAST(reminder_offset AS INTEGER) FROM users
+    WHERE trim(reminder_offset) <> '' AND trim(reminder_offset) NOT GLOB '*[^0-9]*'
+        AND CAST(reminder_offset AS INTEGER) > 0;
+-- A missing or unreadable offset meant the default of 15 minutes.
+INSERT INTO reminder_offsets (chat_id, minutes)
+    SELECT chat_id, 15 FROM users
+    WHERE reminder_offset IS NULL OR trim(reminder_offset) = '' OR trim(reminder_offset) GLOB '*[^0-9]*';
+ALTER TABLE users DROP COLUMN reminder_offset;
+ALTER TABLE users ADD COLUMN reminders_first_only BOOLEAN NOT NULL DEFAULT 0;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS reminder_acks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    chat_id INTEGER NOT NULL,
+    lecture_uid TEXT NOT NULL,
+    module TEXT NOT NULL DEFAULT '',
+    title TEXT NOT NULL,
+    starts_at TIMESTAMP NOT NULL,
+    action TEXT NOT NULL,
+    acked_at TIMESTAMP NOT NULL,
+    CONSTRAINT chk_ack_action CHECK (action IN ('got_it', 'snooze', 'skip'))
+);
+CREATE INDEX IF NOT EXISTS idx_reminder_acks_chat_id_starts_at ON reminder_acks(chat_id, starts_at);
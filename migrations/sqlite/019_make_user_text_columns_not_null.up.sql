@@ -0,0 +1,40 @@
+-- SQLite cannot add NOT NULL to an existing column, so the table is rebuilt.
+CREATE TABLE users_new (
+    chat_id INTEGER PRIMARY KEY,
+    username TEXT NOT NULL DEFAULT '',
+    webcal_url TEXT NOT NULL DEFAULT '',
+    daily_time TEXT NOT NULL DEFAULT '',
+    weekly_time TEXT NOT NULL DEFAULT '',
+    email TEXT NOT NULL DEFAULT '',
+    email_verified BOOLEAN NOT NULL DEFAULT 0,
+    daily_channel TEXT NOT NULL DEFAULT 'telegram',
+    weekly_channel TEXT NOT NULL DEFAULT 'telegram',
+    reminder_channel TEXT NOT NULL DEFAULT 'telegram',
+    feed_token TEXT NOT NULL DEFAULT '',
+    moodle_url TEXT NOT NULL DEFAULT '',
+    paused BOOLEAN NOT NULL DEFAULT 0,
+    paused_until TIMESTAMP,
+    daily_disabled BOOLEAN NOT NULL DEFAULT 0,
+    weekly_disabled BOOLEAN NOT NULL DEFAULT 0,
+    reminders_disabled BOOLEAN NOT NULL DEFAULT 0,
+    daily_weekends BOOLEAN NOT NULL DEFAULT 0,
+    daily_skip_empty BOOLEAN NOT NULL DEFAULT 0,
+    reminders_first_only BOOLEAN NOT NULL DEFAULT 0,
+    evening_time TEXT NOT NULL DEFAULT '',
+    commute_minutes INTEGER NOT NULL DEFAULT 0
+);
+INSERT INTO users_new (
+    chat_id, username, webcal_url, daily_time, weekly_time,
+    email, email_verified, daily_channel, weekly_channel, reminder_channel, feed_token, moodle_url,
+    paused, paused_until, daily_disabled, weekly_disabled, reminders_disabled, daily_weekends,
+    daily_skip_empty, reminders_first_only, evening_time, commute_minutes
+)
+SELECT
+    chat_id, COALESCE(username, ''), COALESCE(webcal_url, ''), COALESCE(daily_time, ''), COALESCE(weekly_time, ''),
+    email, email_verified, daily_channel, weekly_channel, reminder_channel, feed_token, moodle_url,
+    paused, paused_until, daily_disabled, weekly_disabled, reminders_disabled, daily_weekends,
+    daily_skip_empty, reminders_first_only, evening_time, commute_minutes
+FROM users;
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_feed_token ON users(feed_token) WHERE feed_token <> '';
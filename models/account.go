@@ -0,0 +1,39 @@
+package models
+
+import "hash/fnv"
+
+const (
+	PlatformTelegram = "telegram"
+	PlatformDiscord  = "discord"
+	PlatformMatrix   = "matrix"
+)
+
+// SyntheticChatIDBase is the highest chat ID given to users who did not
+// arrive through Telegram. Telegram chat IDs never get this low.
+const SyntheticChatIDBase = -1 << 52
+
+// Account is a user's identity on a chat platform other than Telegram.
+// ExternalID identifies the person on the platform; Address is where the bot
+// sends them messages (a DM channel or room). Several accounts may share one
+// ChatID once they are linked.
+type Account struct {
+	Platform   string
+	ExternalID string
+	Address    string
+	ChatID     int64
+}
+
+// SyntheticChatID derives the chat ID for a new account on a platform other
+// than Telegram. It is stable for a given identity, so an account that is
+// created twice gets the same user.
+func SyntheticChatID(platform, externalID string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(platform + ":" + externalID))
+	return SyntheticChatIDBase - int64(h.Sum64()&(1<<50-1))
+}
+
+// IsSyntheticChatID reports whether chatID was made by SyntheticChatID,
+// rather than being a Telegram chat.
+func IsSyntheticChatID(chatID int64) bool {
+	return chatID <= SyntheticChatIDBase
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Deadline is a piece of coursework due at a fixed time. Module is the UCL
+// module code it belongs to.
+type Deadline struct {
+	ID     int64
+	ChatID int64
+	Module string
+	Title  string
+	Due    time.Time
+	// UID identifies a deadline imported from Moodle. It is "" for
+	// deadlines the user added.
+	UID string
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Event is something a user added to their timetable themselves. Weekly
+// events repeat every week from Start, at the same local time.
+type Event struct {
+	ID       int64
+	ChatID   int64
+	Title    string
+	Location string
+	Start    time.Time
+	End      time.Time
+	Weekly   bool
+}
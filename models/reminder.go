@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// ReminderOffset is how many minutes before a session a reminder is sent.
+// An offset with a SessionType or Module only applies to matching sessions,
+// and a session is reminded by the most specific offsets that match it:
+// module and type, then module, then type, then the general ones with
+// neither.
+type ReminderOffset struct {
+	ID          int64
+	ChatID      int64
+	Minutes     int
+	SessionType string
+	Module      string
+}
+
+// Answers to a lecture reminder.
+const (
+	AckGotIt  = "got_it"
+	AckSnooze = "snooze"
+	AckSkip   = "skip"
+)
+
+// ReminderAck records a user answering a reminder for the lecture starting
+// at Start, kept for attendance statistics. Action is one of AckGotIt,
+// AckSnooze and AckSkip.
+type ReminderAck struct {
+	ID         int64
+	ChatID     int64
+	LectureUID string
+	Module     string
+	Title      string
+	Start      time.Time
+	Action     string
+	At         time.Time
+}
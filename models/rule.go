@@ -0,0 +1,21 @@
+package models
+
+const (
+	RuleHide   = "hide"
+	RuleRename = "rename"
+
+	MatchModule = "module"
+	MatchTitle  = "title"
+)
+
+// Rule changes how a user's lectures are shown. It matches lectures by module
+// code or by a case-insensitive regular expression on the title, and hides
+// them or replaces their title with Replacement.
+type Rule struct {
+	ID          int64
+	ChatID      int64
+	Action      string
+	Match       string
+	Pattern     string
+	Replacement string
+}
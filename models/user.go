@@ -1,10 +1,64 @@
 package models
 
+import "time"
+
+// Delivery channels. ChannelTelegram keeps its stored name but means the chat
+// on every platform the user has linked.
+const (
+	ChannelTelegram = "telegram"
+	ChannelEmail    = "email"
+	ChannelBoth     = "both"
+)
+
 type User struct {
-	ChatID         int64
-	Username       string
-	WebCalURL      string
-	DailyTime      string
-	WeeklyTime     string
-	ReminderOffset string
+	ChatID          int64
+	Username        string
+	WebCalURL       string
+	DailyTime       string
+	WeeklyTime      string
+	Email           string
+	EmailVerified   bool
+	DailyChannel    string
+	WeeklyChannel   string
+	ReminderChannel string
+	// FeedToken is the secret in the user's calendar feed URL, or "" if
+	// they have not asked for one.
+	FeedToken string
+	// MoodleURL is the user's Moodle calendar export, which deadlines are
+	// imported from.
+	MoodleURL string
+	// Paused silences scheduled notifications until PausedUntil, or until
+	// the user resumes them if it is nil.
+	Paused      bool
+	PausedUntil *time.Time
+	// DailyDisabled, WeeklyDisabled, RemindersDisabled and LeaveDisabled
+	// turn off one kind of notification each, so a new user gets all of them.
+	DailyDisabled     bool
+	WeeklyDisabled    bool
+	RemindersDisabled bool
+	LeaveDisabled     bool
+	// DailyWeekends sends the daily summary on Saturdays and Sundays too,
+	// and DailySkipEmpty leaves it out on days without lectures.
+	DailyWeekends  bool
+	DailySkipEmpty bool
+	// RemindersFirstOnly sends reminders for the first session of each day
+	// only.
+	RemindersFirstOnly bool
+	// EveningTime is when tomorrow's lectures are previewed the evening
+	// before, or "" for no preview.
+	EveningTime string
+	// CommuteMinutes is how long the user takes to get to their first
+	// lecture, for the reminder to leave. 0 means no reminder.
+	CommuteMinutes int
+}
+
+// WantsTelegram reports whether notifications sent on channel go to the chat.
+// An unset channel means Telegram only.
+func WantsTelegram(channel string) bool {
+	return channel != ChannelEmail
+}
+
+// WantsEmail reports whether notifications sent on channel go to email.
+func WantsEmail(channel string) bool {
+	return channel == ChannelEmail || channel == ChannelBoth
 }
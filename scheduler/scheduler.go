@@ -1,25 +1,56 @@
 package scheduler
 
 import (
+	"context"
 	"fmt"
-	"log"
-	"strconv"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/artem-streltsov/ucl-timetable-bot/academic"
 	"github.com/artem-streltsov/ucl-timetable-bot/database"
+	"github.com/artem-streltsov/ucl-timetable-bot/email"
+	"github.com/artem-streltsov/ucl-timetable-bot/logging"
+	"github.com/artem-streltsov/ucl-timetable-bot/messenger"
+	"github.com/artem-streltsov/ucl-timetable-bot/metrics"
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
 	"github.com/artem-streltsov/ucl-timetable-bot/timetable"
 	"github.com/artem-streltsov/ucl-timetable-bot/utils"
+)
 
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+const (
+	// weeklyDeadlinesAhead is how far ahead the weekly summary lists
+	// deadlines.
+	weeklyDeadlinesAhead = 14 * 24 * time.Hour
+	// moodleSyncInterval is how often deadlines are imported from Moodle.
+	moodleSyncInterval = 6 * time.Hour
+	// SnoozeFor is how long the snooze button on a reminder puts it off.
+	SnoozeFor = 5 * time.Minute
 )
 
-var ukLocation, _ = time.LoadLocation("Europe/London")
+// deadlineReminders are sent before each deadline, furthest first.
+var deadlineReminders = []struct {
+	before time.Duration
+	label  string
+}{
+	{7 * 24 * time.Hour, "7 days"},
+	{24 * time.Hour, "1 day"},
+	{2 * time.Hour, "2 hours"},
+}
 
 type Scheduler struct {
-	api    *tgbotapi.BotAPI
-	db     *database.DB
-	timers map[int64]*UserTimers
+	messenger messenger.Messenger
+	mailer    email.Sender
+	db        database.Store
+	timers    map[int64]*UserTimers
+	// moodleTimers are kept apart from timers so that rescheduling a user,
+	// which happens on most setting changes, doesn't put off their import.
+	moodleTimers map[int64]*time.Timer
+	// lectureRuns numbers each scheduleLectureReminders call per user, so
+	// a call that was overtaken while fetching the calendar drops its timers.
+	lectureRuns map[int64]uint64
+	mu          sync.Mutex
 }
 
 type UserTimers struct {
@@ -27,157 +58,527 @@ type UserTimers struct {
 	weeklyTimer      *time.Timer
 	lectureTimers    []*time.Timer
 	lectureScheduler *time.Timer
+	deadlineTimers   []*time.Timer
+	resumeTimer      *time.Timer
+	eveningTimer     *time.Timer
 }
 
-func NewScheduler(api *tgbotapi.BotAPI, db *database.DB) *Scheduler {
+// NewScheduler creates a scheduler. mailer may be nil when email delivery is
+// not configured, in which case everything goes to the chat.
+func NewScheduler(m messenger.Messenger, mailer email.Sender, db database.Store) *Scheduler {
 	return &Scheduler{
-		api:    api,
-		db:     db,
-		timers: make(map[int64]*UserTimers),
+		messenger: m,
+		mailer:    mailer,
+		db:        db,
+		timers:    make(map[int64]*UserTimers),
+
+		moodleTimers: make(map[int64]*time.Timer),
+		lectureRuns:  make(map[int64]uint64),
 	}
 }
 
-func (s *Scheduler) ScheduleAll() {
-	users, _ := s.db.GetAllUsers()
+// jobContext starts a fresh correlation scope for a timer firing, since the
+// update that scheduled it has long finished.
+func jobContext(chatID int64, job string) context.Context {
+	return logging.With(logging.WithCorrelationID(context.Background(), chatID), "job", job)
+}
+
+func (s *Scheduler) ScheduleAll(ctx context.Context) {
+	users, err := s.db.GetAllUsers()
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to load users for scheduling", "error", err)
+		return
+	}
 	for _, user := range users {
-		s.ScheduleUser(user.ChatID)
+		s.ScheduleUser(logging.With(ctx, "chat_id", user.ChatID), user.ChatID)
 	}
 }
 
-func (s *Scheduler) ScheduleUser(chatID int64) {
+func (s *Scheduler) ScheduleUser(ctx context.Context, chatID int64) {
 	user, err := s.db.GetUser(chatID)
-	if err != nil || user == nil {
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to get user for scheduling", "error", err)
+		return
+	}
+	if user == nil {
+		return
+	}
+
+	if user.Paused {
+		s.schedulePaused(ctx, user)
 		return
 	}
 
-	s.CancelUser(chatID)
+	deadlineTimers := s.deadlineTimers(ctx, user)
+
+	s.mu.Lock()
+	s.cancelUser(chatID)
+	s.timers[chatID] = &UserTimers{deadlineTimers: deadlineTimers}
+	s.scheduleMoodle(user)
+
+	var dailyTime, weeklyTime, eveningTime time.Time
+	if !user.DailyDisabled {
+		dailyTime = nextDailyTime(user)
+		s.timers[chatID].dailyTimer = time.AfterFunc(time.Until(dailyTime), func() {
+			ctx := jobContext(chatID, "daily_summary")
+			s.sendDailyTimetable(ctx, chatID)
+			s.ScheduleUser(ctx, chatID)
+		})
+	}
+
+	if !user.WeeklyDisabled {
+		weeklyTime = utils.GetNextWeekTime(user.WeeklyTime)
+		s.timers[chatID].weeklyTimer = time.AfterFunc(time.Until(weeklyTime), func() {
+			ctx := jobContext(chatID, "weekly_summary")
+			s.sendWeeklyTimetable(ctx, chatID)
+			s.ScheduleUser(ctx, chatID)
+		})
+	}
+
+	if user.EveningTime != "" {
+		eveningTime = nextEveningTime(user)
+		s.timers[chatID].eveningTimer = time.AfterFunc(time.Until(eveningTime), func() {
+			ctx := jobContext(chatID, "evening_preview")
+			s.sendEveningPreview(ctx, chatID)
+			s.ScheduleUser(ctx, chatID)
+		})
+	}
+	s.mu.Unlock()
+
+	logging.FromContext(ctx).Debug("scheduled notifications", "daily_at", dailyTime, "weekly_at", weeklyTime, "evening_at", eveningTime)
 
+	s.scheduleLectureRemindersAtMidnight(ctx, chatID)
+}
+
+// nextDailyTime returns when user's next daily summary is due. Summaries are
+// only sent in term, so there is no "No lectures today." over the holidays,
+// and only on weekdays unless the user asked for weekends too.
+func nextDailyTime(user *models.User) time.Time {
+	if user.DailyWeekends {
+		return academic.Current().NextTermDay(utils.GetNextDayTime(user.DailyTime))
+	}
+	return academic.Current().NextTeachingDay(utils.GetNextTime(user.DailyTime))
+}
+
+// nextEveningTime returns when user's next evening preview is due: the
+// evening before the next day that gets a daily summary.
+func nextEveningTime(user *models.User) time.Time {
+	tomorrow := utils.GetNextDayTime(user.EveningTime).AddDate(0, 0, 1)
+	if user.DailyWeekends {
+		return academic.Current().NextTermDay(tomorrow).AddDate(0, 0, -1)
+	}
+	return academic.Current().NextTeachingDay(tomorrow).AddDate(0, 0, -1)
+}
+
+// schedulePaused replaces a paused user's notifications with a timer that
+// resumes them when the pause runs out. Moodle deadlines keep being imported
+// so reminders are up to date afterwards.
+func (s *Scheduler) schedulePaused(ctx context.Context, user *models.User) {
+	chatID := user.ChatID
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cancelUser(chatID)
 	s.timers[chatID] = &UserTimers{}
+	s.scheduleMoodle(user)
+	if user.PausedUntil != nil {
+		// A pause that has already run out resumes straight away.
+		s.timers[chatID].resumeTimer = time.AfterFunc(time.Until(*user.PausedUntil), func() {
+			ctx := jobContext(chatID, "resume")
+			s.resume(ctx, chatID)
+		})
+	}
+	logging.FromContext(ctx).Debug("notifications paused", "until", user.PausedUntil)
+}
 
-	dailyTime := utils.GetNextTime(user.DailyTime)
-	dailyDuration := time.Until(dailyTime)
-	dailyTimer := time.AfterFunc(dailyDuration, func() {
-		s.sendDailyTimetable(chatID)
-		s.ScheduleUser(chatID)
-	})
-	s.timers[chatID].dailyTimer = dailyTimer
+// resume ends a user's pause once its end has passed and tells them their
+// notifications are back on.
+func (s *Scheduler) resume(ctx context.Context, chatID int64) {
+	logger := logging.FromContext(ctx)
+	user, err := s.db.GetUser(chatID)
+	if err != nil {
+		logger.Error("failed to get user to resume", "error", err)
+		return
+	}
+	// The pause may have been lifted or changed since the timer was set.
+	if user == nil || !user.Paused || user.PausedUntil == nil || user.PausedUntil.After(time.Now()) {
+		return
+	}
+	user.Paused = false
+	user.PausedUntil = nil
+	if err := s.db.SaveUser(user); err != nil {
+		logger.Error("failed to resume notifications", "error", err)
+		return
+	}
+	s.ScheduleUser(ctx, chatID)
+	s.sendMessage(ctx, chatID, "▶️ Notifications resumed. Use /pause to pause them again.")
+}
+
+// scheduleMoodle starts the timer for user's next Moodle import unless one
+// is already pending, and stops it when they have no Moodle link. s.mu must
+// be held.
+func (s *Scheduler) scheduleMoodle(user *models.User) {
+	chatID := user.ChatID
+	if user.MoodleURL == "" {
+		s.stopMoodle(chatID)
+		return
+	}
+	if s.moodleTimers[chatID] != nil {
+		return
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(moodleSyncInterval, func() {
+		s.mu.Lock()
+		if s.moodleTimers[chatID] == timer {
+			delete(s.moodleTimers, chatID)
+		}
+		s.mu.Unlock()
 
-	weeklyTime := utils.GetNextWeekTime(user.WeeklyTime)
-	weeklyDuration := time.Until(weeklyTime)
-	weeklyTimer := time.AfterFunc(weeklyDuration, func() {
-		s.sendWeeklyTimetable(chatID)
-		s.ScheduleUser(chatID)
+		ctx := jobContext(chatID, "moodle_sync")
+		s.syncMoodle(ctx, chatID)
+		s.ScheduleUser(ctx, chatID)
 	})
-	s.timers[chatID].weeklyTimer = weeklyTimer
+	s.moodleTimers[chatID] = timer
+}
+
+// stopMoodle stops chatID's pending Moodle import. s.mu must be held.
+func (s *Scheduler) stopMoodle(chatID int64) {
+	if timer := s.moodleTimers[chatID]; timer != nil {
+		timer.Stop()
+		delete(s.moodleTimers, chatID)
+	}
+}
+
+// deadlineTimers starts a timer for each of user's deadline reminders that
+// is still to come.
+func (s *Scheduler) deadlineTimers(ctx context.Context, user *models.User) []*time.Timer {
+	deadlines, err := s.db.GetDeadlines(user.ChatID)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to get deadlines", "error", err)
+		return nil
+	}
+	var timers []*time.Timer
+	now := time.Now()
+	for _, deadline := range deadlines {
+		for _, reminder := range deadlineReminders {
+			at := deadline.Due.Add(-reminder.before)
+			if !at.After(now) {
+				continue
+			}
+			chatID, deadline, label := user.ChatID, deadline, reminder.label
+			timers = append(timers, time.AfterFunc(at.Sub(now), func() {
+				ctx := jobContext(chatID, "deadline_reminder")
+				s.sendDeadlineReminder(ctx, chatID, deadline, label)
+			}))
+		}
+	}
+	return timers
+}
 
-	s.scheduleLectureRemindersAtMidnight(chatID)
+// sendDeadlineReminder reminds the user that deadline is due in label. The
+// user is read again so a pause or channel change made since the timer was
+// set applies.
+func (s *Scheduler) sendDeadlineReminder(ctx context.Context, chatID int64, deadline models.Deadline, label string) {
+	user, err := s.db.GetUser(chatID)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to get user for deadline reminder", "error", err)
+		return
+	}
+	if user == nil || user.Paused {
+		return
+	}
+	message := "📝 Due in " + label + "\n\n" + timetable.FormatDeadline(deadline, time.Now())
+	s.notify(ctx, user, user.ReminderChannel, message, func(to string) (email.Message, error) {
+		return email.DeadlineReminder(to, deadline, label)
+	})
 }
 
-func (s *Scheduler) scheduleLectureRemindersAtMidnight(chatID int64) {
-	now := time.Now().In(ukLocation)
-	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 1, 0, ukLocation).AddDate(0, 0, 1)
+func (s *Scheduler) scheduleLectureRemindersAtMidnight(ctx context.Context, chatID int64) {
+	now := time.Now().In(utils.Location())
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 1, 0, utils.Location()).AddDate(0, 0, 1)
 	durationUntilMidnight := time.Until(midnight)
 
 	lectureScheduler := time.AfterFunc(durationUntilMidnight, func() {
-		s.scheduleLectureReminders(chatID)
-		s.scheduleLectureRemindersAtMidnight(chatID)
+		ctx := jobContext(chatID, "lecture_reminders")
+		s.scheduleLectureRemindersAtMidnight(ctx, chatID)
 	})
-	s.timers[chatID].lectureScheduler = lectureScheduler
+	s.mu.Lock()
+	if timers, exists := s.timers[chatID]; exists {
+		timers.lectureScheduler = lectureScheduler
+	} else {
+		lectureScheduler.Stop()
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
 
-	s.scheduleLectureReminders(chatID)
+	s.scheduleLectureReminders(ctx, chatID)
 }
 
-func (s *Scheduler) scheduleLectureReminders(chatID int64) {
+func (s *Scheduler) scheduleLectureReminders(ctx context.Context, chatID int64) {
+	s.mu.Lock()
+	s.lectureRuns[chatID]++
+	run := s.lectureRuns[chatID]
 	if s.timers[chatID] != nil {
 		for _, timer := range s.timers[chatID].lectureTimers {
 			timer.Stop()
 		}
 		s.timers[chatID].lectureTimers = nil
 	}
+	s.mu.Unlock()
 
-	user, _ := s.db.GetUser(chatID)
-	if user == nil || user.WebCalURL == "" {
+	logger := logging.FromContext(ctx)
+	user, err := s.db.GetUser(chatID)
+	if err != nil {
+		logger.Error("failed to get user for reminders", "error", err)
+		return
+	}
+	if user == nil || user.Paused {
+		return
+	}
+	leave := user.CommuteMinutes > 0 && !user.LeaveDisabled
+	if user.RemindersDisabled && !leave {
 		return
 	}
 
-	cal, err := timetable.FetchCalendar(user.WebCalURL)
+	all, err := timetable.ForUser(s.db, user)
 	if err != nil {
+		logger.Error("failed to fetch calendar for reminders", "error", err)
 		return
 	}
 
-	day := time.Now().In(ukLocation)
-	lectures, err := timetable.GetLectures(cal, day)
-	if err != nil || len(lectures) == 0 {
+	today := time.Now().In(utils.Location())
+	dayStart := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, utils.Location())
+	acks, err := s.db.GetReminderAcks(chatID, dayStart, dayStart.AddDate(0, 0, 1))
+	if err != nil {
+		logger.Error("failed to get reminder answers", "error", err)
+		return
+	}
+	lectures := slices.DeleteFunc(timetable.OnDay(all, today), func(lecture timetable.Lecture) bool {
+		return skipped(acks, lecture)
+	})
+	if len(lectures) == 0 {
 		return
 	}
 
-	offsetMinutes, err := strconv.Atoi(user.ReminderOffset)
+	offsets, err := s.db.GetReminderOffsets(chatID)
 	if err != nil {
-		offsetMinutes = 15
+		logger.Error("failed to get reminder offsets", "error", err)
+		return
 	}
 
 	timers := []*time.Timer{}
-	now := time.Now().In(ukLocation)
+	now := time.Now().In(utils.Location())
+
+	// The reminder to leave is timed from the first session of the day.
+	first := lectures[0]
+	leaveAt := first.Start.Add(-time.Duration(user.CommuteMinutes) * time.Minute)
+	if leave && leaveAt.After(now) {
+		timers = append(timers, time.AfterFunc(time.Until(leaveAt), func() {
+			ctx := jobContext(chatID, "leave_reminder")
+			message := fmt.Sprintf("🚶 Leave now\n📚 %s at %s\n📍 %s",
+				timetable.CleanTitle(first.Title),
+				first.Start.Format("15:04"),
+				first.Location,
+			)
+			s.notify(ctx, user, user.ReminderChannel, message, func(to string) (email.Message, error) {
+				return email.LeaveReminder(to, first, user.CommuteMinutes)
+			})
+		}))
+	}
 
+	if user.RemindersDisabled {
+		lectures = nil
+	} else if user.RemindersFirstOnly {
+		lectures = lectures[:1]
+	}
 	for _, lecture := range lectures {
-		reminderTime := lecture.Start.Add(-time.Duration(offsetMinutes) * time.Minute)
-		if reminderTime.After(now) {
-			duration := time.Until(reminderTime)
-			lectureCopy := lecture
-			timer := time.AfterFunc(duration, func() {
-				reminderMessage := fmt.Sprintf("⏰ In %d minutes\n📚 %s\n📍 %s",
-					offsetMinutes,
-					timetable.CleanTitle(lectureCopy.Title),
-					lectureCopy.Location,
-				)
-				s.sendMessage(chatID, reminderMessage)
+		for _, offsetMinutes := range timetable.ReminderMinutes(offsets, lecture) {
+			reminderTime := lecture.Start.Add(-time.Duration(offsetMinutes) * time.Minute)
+			if !reminderTime.After(now) {
+				continue
+			}
+			lectureCopy, offsetMinutes := lecture, offsetMinutes
+			timer := time.AfterFunc(time.Until(reminderTime), func() {
+				ctx := jobContext(chatID, "lecture_reminder")
+				s.sendLectureReminder(ctx, user, lectureCopy, offsetMinutes)
 			})
 			timers = append(timers, timer)
 		}
 	}
-	s.timers[chatID].lectureTimers = timers
-}
 
-func (s *Scheduler) sendDailyTimetable(chatID int64) {
-	user, _ := s.db.GetUser(chatID)
-	if user == nil || user.WebCalURL == "" {
-		s.sendMessage(chatID, "Please set your calendar link using /set_calendar")
+	for _, ack := range acks {
+		snoozeUntil := ack.At.Add(SnoozeFor)
+		if ack.Action != models.AckSnooze || !snoozeUntil.After(now) {
+			continue
+		}
+		for _, lecture := range lectures {
+			if lecture.UID != ack.LectureUID || !lecture.Start.Equal(ack.Start) {
+				continue
+			}
+			lectureCopy := lecture
+			timers = append(timers, time.AfterFunc(time.Until(snoozeUntil), func() {
+				ctx := jobContext(chatID, "snoozed_reminder")
+				minutes := int(time.Until(lectureCopy.Start).Round(time.Minute).Minutes())
+				s.sendLectureReminder(ctx, user, lectureCopy, minutes)
+			}))
+		}
+	}
+	logger.Debug("scheduled lecture reminders", "count", len(timers))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	userTimers, exists := s.timers[chatID]
+	if !exists || s.lectureRuns[chatID] != run {
+		// The user was cancelled, or a later call has taken over.
+		for _, timer := range timers {
+			timer.Stop()
+		}
 		return
 	}
-	cal, err := timetable.FetchCalendar(user.WebCalURL)
+	for _, timer := range userTimers.lectureTimers {
+		timer.Stop()
+	}
+	userTimers.lectureTimers = timers
+}
+
+// ScheduleReminders replaces the user's lecture reminders for today, such as
+// after they snooze or skip one.
+func (s *Scheduler) ScheduleReminders(ctx context.Context, chatID int64) {
+	s.scheduleLectureReminders(ctx, chatID)
+}
+
+// skipped reports whether the user asked to skip lecture's module, or
+// lecture itself if it has none, in acks.
+func skipped(acks []models.ReminderAck, lecture timetable.Lecture) bool {
+	for _, ack := range acks {
+		if ack.Action != models.AckSkip {
+			continue
+		}
+		if lecture.Module != "" && ack.Module == lecture.Module || ack.LectureUID == lecture.UID {
+			return true
+		}
+	}
+	return false
+}
+
+// sendLectureReminder reminds user that lecture starts in minutes, with
+// buttons to snooze, skip or acknowledge the reminder in the chat.
+func (s *Scheduler) sendLectureReminder(ctx context.Context, user *models.User, lecture timetable.Lecture, minutes int) {
+	when := fmt.Sprintf("⏰ In %d minutes", minutes)
+	if minutes <= 0 {
+		when = "⏰ Starting now"
+	}
+	message := fmt.Sprintf("%s\n📚 %s\n📍 %s", when, timetable.CleanTitle(lecture.Title), lecture.Location)
+	s.notifyWithKeyboard(ctx, user, user.ReminderChannel, message, reminderKeyboard(lecture), func(to string) (email.Message, error) {
+		return email.LectureReminder(to, lecture, max(minutes, 0))
+	})
+}
+
+func reminderKeyboard(lecture timetable.Lecture) messenger.Keyboard {
+	key := timetable.LectureKey(lecture)
+	skip := "Skip this module today"
+	if lecture.Module == "" {
+		skip = "Skip this today"
+	}
+	return messenger.Keyboard{
+		{
+			{Text: fmt.Sprintf("Snooze %d min", int(SnoozeFor.Minutes())), Data: "reminder_snooze_" + key},
+			{Text: skip, Data: "reminder_skip_" + key},
+		},
+		{{Text: "Got it", Data: "reminder_ack_" + key}},
+	}
+}
+
+func (s *Scheduler) sendDailyTimetable(ctx context.Context, chatID int64) {
+	logger := logging.FromContext(ctx)
+	user, err := s.db.GetUser(chatID)
 	if err != nil {
-		s.sendMessage(chatID, "Error fetching calendar: "+err.Error())
+		logger.Error("failed to get user for daily summary", "error", err)
 		return
 	}
-
-	day := time.Now().In(ukLocation)
-	lectures, err := timetable.GetLectures(cal, day)
+	if user == nil || !timetable.HasTimetable(s.db, user) {
+		s.sendMessage(ctx, chatID, "Please set your calendar link using /set_calendar")
+		return
+	}
+	all, err := timetable.ForUser(s.db, user)
 	if err != nil {
-		s.sendMessage(chatID, "Error processing calendar: "+err.Error())
+		logger.Error("failed to fetch calendar", "error", err)
+		s.sendMessage(ctx, chatID, "Error fetching calendar: "+err.Error())
 		return
 	}
+
+	day := time.Now().In(utils.Location())
+	lectures := timetable.OnDay(all, day)
+	buildEmail := func(to string) (email.Message, error) {
+		return email.DailySummary(to, day, lectures)
+	}
 	if len(lectures) == 0 {
-		s.sendMessage(chatID, "No lectures today.")
+		if user.DailySkipEmpty {
+			logger.Debug("skipping daily summary without lectures")
+			return
+		}
+		s.notify(ctx, user, user.DailyChannel, "No lectures today.", buildEmail)
 		return
 	}
 	dateStr := day.Format("Mon, 02 Jan")
 	message := fmt.Sprintf("*%s:*\n\n", dateStr) + timetable.FormatLectures(lectures)
-	s.sendMessage(chatID, message)
+	s.notify(ctx, user, user.DailyChannel, message, buildEmail)
 }
 
-func (s *Scheduler) sendWeeklyTimetable(chatID int64) {
-	user, _ := s.db.GetUser(chatID)
-	if user == nil || user.WebCalURL == "" {
-		s.sendMessage(chatID, "Please set your calendar link using /set_calendar")
+// sendEveningPreview sends tomorrow's lectures, over the same channel as the
+// daily summary.
+func (s *Scheduler) sendEveningPreview(ctx context.Context, chatID int64) {
+	logger := logging.FromContext(ctx)
+	user, err := s.db.GetUser(chatID)
+	if err != nil {
+		logger.Error("failed to get user for evening preview", "error", err)
+		return
+	}
+	if user == nil || !timetable.HasTimetable(s.db, user) {
 		return
 	}
-	cal, err := timetable.FetchCalendar(user.WebCalURL)
+	all, err := timetable.ForUser(s.db, user)
 	if err != nil {
-		s.sendMessage(chatID, "Error fetching calendar: "+err.Error())
+		logger.Error("failed to fetch calendar", "error", err)
 		return
 	}
 
-	now := time.Now().In(ukLocation)
+	day := time.Now().In(utils.Location()).AddDate(0, 0, 1)
+	lectures := timetable.OnDay(all, day)
+	buildEmail := func(to string) (email.Message, error) {
+		return email.EveningPreview(to, day, lectures)
+	}
+	if len(lectures) == 0 {
+		if user.DailySkipEmpty {
+			logger.Debug("skipping evening preview without lectures")
+			return
+		}
+		s.notify(ctx, user, user.DailyChannel, "No lectures tomorrow.", buildEmail)
+		return
+	}
+	message := fmt.Sprintf("🌙 *Tomorrow, %s:*\n\n", day.Format("Mon 02 Jan")) + timetable.FormatLectures(lectures)
+	s.notify(ctx, user, user.DailyChannel, message, buildEmail)
+}
+
+func (s *Scheduler) sendWeeklyTimetable(ctx context.Context, chatID int64) {
+	logger := logging.FromContext(ctx)
+	user, err := s.db.GetUser(chatID)
+	if err != nil {
+		logger.Error("failed to get user for weekly summary", "error", err)
+		return
+	}
+	if user == nil || !timetable.HasTimetable(s.db, user) {
+		s.sendMessage(ctx, chatID, "Please set your calendar link using /set_calendar")
+		return
+	}
+	all, err := timetable.ForUser(s.db, user)
+	if err != nil {
+		logger.Error("failed to fetch calendar", "error", err)
+		s.sendMessage(ctx, chatID, "Error fetching calendar: "+err.Error())
+		return
+	}
+
+	now := time.Now().In(utils.Location())
 	weekday := int(now.Weekday())
 	if weekday == 0 {
 		weekday = 7 // make Sunday 7
@@ -185,42 +586,191 @@ func (s *Scheduler) sendWeeklyTimetable(chatID int64) {
 	weekStart := now.AddDate(0, 0, -(weekday - 1)) // Monday
 	weekEnd := weekStart.AddDate(0, 0, 4)          // Friday
 
-	lecturesMap, err := timetable.GetLecturesInRange(cal, weekStart, weekEnd)
+	days := timetable.GroupByDay(all, weekStart, weekEnd)
+	deadlines, err := s.db.GetDeadlines(chatID)
 	if err != nil {
-		s.sendMessage(chatID, "Error processing calendar: "+err.Error())
-		return
+		logger.Error("failed to get deadlines for weekly summary", "error", err)
 	}
-	if len(lecturesMap) == 0 {
-		s.sendMessage(chatID, "No lectures this week.")
+	deadlines = timetable.DueBetween(deadlines, now, now.Add(weeklyDeadlinesAhead))
+	if !academic.Current().TeachingWeek(weekStart) && len(deadlines) == 0 {
+		logger.Debug("skipping weekly summary outside term")
 		return
 	}
-	startDateStr := weekStart.Format("Mon, 02 Jan")
-	endDateStr := weekEnd.Format("Fri, 02 Jan")
-	dateRangeStr := fmt.Sprintf("*%s - %s:*\n\n", startDateStr, endDateStr)
+	buildEmail := func(to string) (email.Message, error) {
+		return email.WeeklySummary(to, weekStart, weekEnd, days, deadlines)
+	}
 
 	var sb strings.Builder
-	sb.WriteString(dateRangeStr)
-	for day := weekStart; !day.After(weekEnd); day = day.AddDate(0, 0, 1) {
-		dayKey := day.Format("Monday")
-		lectures, ok := lecturesMap[dayKey]
-		if ok {
-			sb.WriteString("\n" + "*" + dayKey + "*" + "\n")
-			message := timetable.FormatLectures(lectures)
+	if len(days) == 0 {
+		sb.WriteString("No lectures this week.\n")
+	} else {
+		startDateStr := weekStart.Format("Mon, 02 Jan")
+		endDateStr := weekEnd.Format("Fri, 02 Jan")
+		if label := academic.Current().WeekLabel(weekStart); label != "" {
+			sb.WriteString("📅 *" + label + "*\n")
+		}
+		sb.WriteString(fmt.Sprintf("*%s - %s:*\n\n", startDateStr, endDateStr))
+		for _, day := range days {
+			sb.WriteString("\n" + "*" + day.Date.Format("Mon 02 Jan") + "*" + "\n")
+			message := timetable.FormatLectures(day.Lectures)
 			sb.WriteString(message)
 		}
 	}
-	s.sendMessage(chatID, sb.String())
+	if len(deadlines) > 0 {
+		sb.WriteString("\n*Upcoming deadlines:*\n\n")
+		sb.WriteString(timetable.FormatDeadlines(deadlines, now))
+	}
+	s.notify(ctx, user, user.WeeklyChannel, sb.String(), buildEmail)
 }
 
-func (s *Scheduler) sendMessage(chatID int64, text string) {
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ParseMode = "Markdown"
-	if _, err := s.api.Send(msg); err != nil {
-		log.Printf("Error sending message: %v", err)
+// syncMoodle imports the user's Moodle deadlines and announces any that are
+// new, moved or removed.
+func (s *Scheduler) syncMoodle(ctx context.Context, chatID int64) {
+	logger := logging.FromContext(ctx)
+	user, err := s.db.GetUser(chatID)
+	if err != nil {
+		logger.Error("failed to get user for moodle sync", "error", err)
+		return
+	}
+	if user == nil || user.MoodleURL == "" {
+		return
+	}
+	changes, err := s.SyncMoodle(user)
+	if err != nil {
+		logger.Error("failed to sync moodle deadlines", "error", err)
+		return
+	}
+	if user.Paused {
+		return
+	}
+	if message := describeDeadlineChanges(changes, time.Now()); message != "" {
+		s.notify(ctx, user, user.ReminderChannel, message, func(to string) (email.Message, error) {
+			return email.DeadlineChanges(to, changes)
+		})
 	}
 }
 
+// SyncMoodle imports deadlines from user's Moodle calendar and returns what
+// changed. It does not reschedule the user's reminders.
+func (s *Scheduler) SyncMoodle(user *models.User) (timetable.DeadlineChanges, error) {
+	cal, err := timetable.FetchCalendar(user.MoodleURL)
+	if err != nil {
+		return timetable.DeadlineChanges{}, err
+	}
+	existing, err := s.db.GetDeadlines(user.ChatID)
+	if err != nil {
+		return timetable.DeadlineChanges{}, err
+	}
+	changes := timetable.DiffDeadlines(existing, timetable.MoodleDeadlines(cal), time.Now())
+
+	for i := range changes.Added {
+		changes.Added[i].ChatID = user.ChatID
+		if err := s.db.AddDeadline(&changes.Added[i]); err != nil {
+			return changes, err
+		}
+	}
+	for _, move := range changes.Moved {
+		if err := s.db.UpdateDeadline(&move.To); err != nil {
+			return changes, err
+		}
+	}
+	for i := range changes.Updated {
+		if err := s.db.UpdateDeadline(&changes.Updated[i]); err != nil {
+			return changes, err
+		}
+	}
+	for _, deadline := range changes.Removed {
+		if _, err := s.db.DeleteDeadline(user.ChatID, deadline.ID); err != nil {
+			return changes, err
+		}
+	}
+	return changes, nil
+}
+
+// describeDeadlineChanges lists added, moved and removed deadlines, or
+// returns "" when there is nothing to announce.
+func describeDeadlineChanges(changes timetable.DeadlineChanges, now time.Time) string {
+	var sb strings.Builder
+	if len(changes.Added) > 0 {
+		sb.WriteString("*New deadlines from Moodle:*\n\n")
+		sb.WriteString(timetable.FormatDeadlines(changes.Added, now))
+	}
+	if len(changes.Moved) > 0 {
+		sb.WriteString("*Moved deadlines:*\n\n")
+		for _, move := range changes.Moved {
+			sb.WriteString(timetable.FormatDeadline(move.To, now))
+			sb.WriteString("Was " + move.From.Due.In(utils.Location()).Format("Mon 02 Jan 15:04") + "\n\n")
+		}
+	}
+	if len(changes.Removed) > 0 {
+		sb.WriteString("*Removed from Moodle:*\n\n")
+		sb.WriteString(timetable.FormatDeadlines(changes.Removed, now))
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// notify delivers a notification over the channel the user chose for it.
+// Email falls back to the chat when it is unavailable or fails, so the
+// notification is never silently lost.
+func (s *Scheduler) notify(ctx context.Context, user *models.User, channel string, text string, buildEmail func(to string) (email.Message, error)) {
+	s.notifyWithKeyboard(ctx, user, channel, text, nil, buildEmail)
+}
+
+// notifyWithKeyboard is notify with buttons attached to the chat message.
+func (s *Scheduler) notifyWithKeyboard(ctx context.Context, user *models.User, channel string, text string, keyboard messenger.Keyboard, buildEmail func(to string) (email.Message, error)) {
+	canEmail := s.mailer != nil && user.EmailVerified && user.Email != ""
+	sendChat := models.WantsTelegram(channel) || !canEmail
+
+	if models.WantsEmail(channel) && canEmail {
+		if err := s.sendEmail(ctx, user.Email, buildEmail); err != nil {
+			logging.FromContext(ctx).Error("failed to send email", "error", err)
+			sendChat = true
+		}
+	}
+	if sendChat && keyboard != nil {
+		s.sendKeyboard(ctx, user.ChatID, text, keyboard)
+	} else if sendChat {
+		s.sendMessage(ctx, user.ChatID, text)
+	}
+}
+
+func (s *Scheduler) sendEmail(ctx context.Context, to string, buildEmail func(to string) (email.Message, error)) error {
+	msg, err := buildEmail(to)
+	if err != nil {
+		return err
+	}
+	err = s.mailer.Send(ctx, msg)
+	metrics.ObserveSend(err)
+	return err
+}
+
+func (s *Scheduler) sendMessage(ctx context.Context, chatID int64, text string) {
+	_, err := s.messenger.SendText(ctx, chatID, text)
+	metrics.ObserveSend(err)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to send message", "error", err)
+	}
+}
+
+func (s *Scheduler) sendKeyboard(ctx context.Context, chatID int64, text string, keyboard messenger.Keyboard) {
+	_, err := s.messenger.SendKeyboard(ctx, chatID, text, keyboard)
+	metrics.ObserveSend(err)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to send message", "error", err)
+	}
+}
+
+// CancelUser stops all of chatID's timers, including the Moodle import.
 func (s *Scheduler) CancelUser(chatID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cancelUser(chatID)
+	s.stopMoodle(chatID)
+}
+
+// cancelUser stops chatID's notification timers before they are scheduled
+// again. The Moodle import is left to run on time. s.mu must be held.
+func (s *Scheduler) cancelUser(chatID int64) {
 	if timers, exists := s.timers[chatID]; exists {
 		if timers.dailyTimer != nil {
 			timers.dailyTimer.Stop()
@@ -231,15 +781,45 @@ func (s *Scheduler) CancelUser(chatID int64) {
 		if timers.lectureScheduler != nil {
 			timers.lectureScheduler.Stop()
 		}
+		if timers.resumeTimer != nil {
+			timers.resumeTimer.Stop()
+		}
+		if timers.eveningTimer != nil {
+			timers.eveningTimer.Stop()
+		}
 		for _, timer := range timers.lectureTimers {
 			timer.Stop()
 		}
+		for _, timer := range timers.deadlineTimers {
+			timer.Stop()
+		}
 		delete(s.timers, chatID)
 	}
 }
 
 func (s *Scheduler) StopAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	for chatID := range s.timers {
-		s.CancelUser(chatID)
+		s.cancelUser(chatID)
+	}
+	for chatID := range s.moodleTimers {
+		s.stopMoodle(chatID)
+	}
+}
+
+// ActiveTimers returns the number of notification timers currently held.
+func (s *Scheduler) ActiveTimers() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, timers := range s.timers {
+		for _, timer := range []*time.Timer{timers.dailyTimer, timers.weeklyTimer, timers.lectureScheduler, timers.resumeTimer, timers.eveningTimer} {
+			if timer != nil {
+				count++
+			}
+		}
+		count += len(timers.lectureTimers) + len(timers.deadlineTimers)
 	}
+	return count + len(s.moodleTimers)
 }
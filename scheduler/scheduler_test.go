@@ -0,0 +1,227 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/database"
+	"github.com/artem-streltsov/ucl-timetable-bot/email"
+	"github.com/artem-streltsov/ucl-timetable-bot/messenger"
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+	"github.com/artem-streltsov/ucl-timetable-bot/utils"
+)
+
+const chatID int64 = 100
+
+// mailbox is an email.Sender that keeps what it sends.
+type mailbox struct {
+	sent []email.Message
+	mu   sync.Mutex
+}
+
+func (m *mailbox) Send(ctx context.Context, msg email.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func (m *mailbox) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.sent)
+}
+
+func newTestScheduler(t *testing.T) (*Scheduler, *messenger.Recorder, *mailbox, *database.Memory) {
+	t.Helper()
+	rec := messenger.NewRecorder()
+	mail := &mailbox{}
+	db := database.NewMemory()
+	s := NewScheduler(rec, mail, db)
+	t.Cleanup(s.StopAll)
+	return s, rec, mail, db
+}
+
+func TestDeadlineReminderReadsCurrentUser(t *testing.T) {
+	s, rec, mail, db := newTestScheduler(t)
+	ctx := context.Background()
+	user := &models.User{
+		ChatID:          chatID,
+		Email:           "alice@ucl.ac.uk",
+		EmailVerified:   true,
+		ReminderChannel: models.ChannelTelegram,
+	}
+	if err := db.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+	deadline := models.Deadline{ChatID: chatID, Module: "COMP0001", Title: "CW1", Due: time.Now().Add(24 * time.Hour)}
+
+	// The channel changes after the reminder was scheduled.
+	user.ReminderChannel = models.ChannelEmail
+	if err := db.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+	s.sendDeadlineReminder(ctx, chatID, deadline, "1 day")
+	if mail.count() != 1 || len(rec.Messages(chatID)) != 0 {
+		t.Errorf("reminder after switching to email: %d emails, %d chat messages, want 1 and 0", mail.count(), len(rec.Messages(chatID)))
+	}
+
+	user.Paused = true
+	if err := db.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+	s.sendDeadlineReminder(ctx, chatID, deadline, "1 day")
+	if mail.count() != 1 || len(rec.Messages(chatID)) != 0 {
+		t.Errorf("reminder while paused was sent: %d emails, %d chat messages", mail.count(), len(rec.Messages(chatID)))
+	}
+}
+
+func TestScheduleUserKeepsPendingMoodleSync(t *testing.T) {
+	s, _, _, db := newTestScheduler(t)
+	ctx := context.Background()
+	user := &models.User{ChatID: chatID, DailyTime: "07:00", WeeklyTime: "SUN 18:00", MoodleURL: "https://moodle.ucl.ac.uk/calendar/export_execute.php"}
+	if err := db.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+	pending := func() *time.Timer {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.moodleTimers[chatID]
+	}
+
+	s.ScheduleUser(ctx, chatID)
+	first := pending()
+	if first == nil {
+		t.Fatal("no Moodle sync was scheduled")
+	}
+	s.ScheduleUser(ctx, chatID)
+	if pending() != first {
+		t.Error("rescheduling the user replaced the pending Moodle sync")
+	}
+
+	user.MoodleURL = ""
+	if err := db.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+	s.ScheduleUser(ctx, chatID)
+	if pending() != nil {
+		t.Error("Moodle sync still pending after the link was removed")
+	}
+
+	user.MoodleURL = "https://moodle.ucl.ac.uk/calendar/export_execute.php"
+	if err := db.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+	s.ScheduleUser(ctx, chatID)
+	s.CancelUser(chatID)
+	if pending() != nil {
+		t.Error("Moodle sync still pending after CancelUser")
+	}
+}
+
+// serveCalendar serves an ICS calendar with one lecture starting at start.
+func serveCalendar(t *testing.T, start time.Time) string {
+	t.Helper()
+	const layout = "20060102T150405Z"
+	ics := fmt.Sprintf("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:test\r\n"+
+		"BEGIN:VEVENT\r\nUID:lecture-1\r\nDTSTAMP:%s\r\nDTSTART:%s\r\nDTEND:%s\r\n"+
+		"SUMMARY:COMP0001 Lecture\r\nLOCATION:Cruciform LT1\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+		start.UTC().Format(layout), start.UTC().Format(layout), start.Add(time.Hour).UTC().Format(layout))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(ics))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL + "/calendar.ics"
+}
+
+func TestLeaveReminderHasItsOwnToggle(t *testing.T) {
+	now := time.Now().In(utils.Location())
+	start := now.Add(2 * time.Hour).Truncate(time.Minute)
+	if start.Day() != now.Day() {
+		t.Skip("the lecture would fall tomorrow")
+	}
+	calendar := serveCalendar(t, start)
+
+	tests := []struct {
+		name              string
+		remindersDisabled bool
+		leaveDisabled     bool
+		want              int
+	}{
+		{"both on", false, false, 2},
+		{"reminders off", true, false, 1},
+		{"leave off", false, true, 1},
+		{"both off", true, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _, _, db := newTestScheduler(t)
+			user := &models.User{
+				ChatID:            chatID,
+				WebCalURL:         calendar,
+				DailyTime:         "07:00",
+				WeeklyTime:        "SUN 18:00",
+				CommuteMinutes:    30,
+				RemindersDisabled: tt.remindersDisabled,
+				LeaveDisabled:     tt.leaveDisabled,
+			}
+			if err := db.SaveUser(user); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.SetReminderOffsets(chatID, "", "", []int{15}); err != nil {
+				t.Fatal(err)
+			}
+
+			s.ScheduleUser(context.Background(), chatID)
+			s.mu.Lock()
+			got := len(s.timers[chatID].lectureTimers)
+			s.mu.Unlock()
+			if got != tt.want {
+				t.Errorf("%d reminder timers, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConcurrentReminderSchedulingKeepsOneSet(t *testing.T) {
+	// The 15-minute reminder falls due a second or so from now.
+	now := time.Now().In(utils.Location())
+	start := now.Add(15*time.Minute + 1500*time.Millisecond)
+	if start.Day() != now.Day() {
+		t.Skip("the lecture would fall tomorrow")
+	}
+	s, rec, _, db := newTestScheduler(t)
+	user := &models.User{ChatID: chatID, WebCalURL: serveCalendar(t, start), DailyTime: "07:00", WeeklyTime: "SUN 18:00"}
+	if err := db.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetReminderOffsets(chatID, "", "", []int{15}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	s.ScheduleUser(ctx, chatID)
+	want := s.ActiveTimers()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.ScheduleReminders(ctx, chatID)
+		}()
+	}
+	wg.Wait()
+	if got := s.ActiveTimers(); got != want {
+		t.Errorf("%d active timers after scheduling reminders twice at once, want %d", got, want)
+	}
+
+	time.Sleep(time.Until(start.Add(-15*time.Minute)) + 500*time.Millisecond)
+	if got := len(rec.Messages(chatID)); got != 1 {
+		t.Errorf("%d reminders sent, want 1", got)
+	}
+}
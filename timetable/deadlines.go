@@ -0,0 +1,54 @@
+package timetable
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+	"github.com/artem-streltsov/ucl-timetable-bot/utils"
+)
+
+// Modules returns the module codes that appear in lectures, sorted.
+func Modules(lectures []Lecture) []string {
+	seen := make(map[string]bool)
+	var modules []string
+	for _, lecture := range lectures {
+		if lecture.Module != "" && !seen[lecture.Module] {
+			seen[lecture.Module] = true
+			modules = append(modules, lecture.Module)
+		}
+	}
+	sort.Strings(modules)
+	return modules
+}
+
+// DueBetween returns the deadlines due after from and no later than to.
+func DueBetween(deadlines []models.Deadline, from, to time.Time) []models.Deadline {
+	var due []models.Deadline
+	for _, deadline := range deadlines {
+		if deadline.Due.After(from) && !deadline.Due.After(to) {
+			due = append(due, deadline)
+		}
+	}
+	return due
+}
+
+// FormatDeadline describes deadline with a countdown from now.
+func FormatDeadline(deadline models.Deadline, now time.Time) string {
+	due := deadline.Due.In(utils.Location())
+	countdown := "passed"
+	if due.After(now) {
+		countdown = "in " + utils.FormatCountdown(due.Sub(now))
+	}
+	return "📝 *" + deadline.Module + "* " + deadline.Title + "\n" +
+		"⏰ " + due.Format("Mon 02 Jan 15:04") + " (" + countdown + ")\n"
+}
+
+func FormatDeadlines(deadlines []models.Deadline, now time.Time) string {
+	var sb strings.Builder
+	for _, deadline := range deadlines {
+		sb.WriteString(FormatDeadline(deadline, now) + "\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,38 @@
+package timetable
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+	"github.com/artem-streltsov/ucl-timetable-bot/utils"
+)
+
+// EventLectures returns the occurrences of events that overlap from..to as
+// lectures. Weekly events keep their local start time across clock changes.
+func EventLectures(events []models.Event, from, to time.Time) []Lecture {
+	var lectures []Lecture
+	for _, event := range events {
+		start := event.Start.In(utils.Location())
+		duration := event.End.Sub(event.Start)
+		for week := 0; !start.After(to); week++ {
+			if end := start.Add(duration); !end.Before(from) {
+				lectures = append(lectures, Lecture{
+					UID:      "event-" + strconv.FormatInt(event.ID, 10) + "-" + start.Format("20060102") + "@ucl-timetable-bot",
+					Title:    event.Title,
+					Module:   ModuleCode(event.Title),
+					Type:     SessionType(event.Title),
+					Start:    start,
+					End:      end,
+					Location: event.Location,
+				})
+			}
+			if !event.Weekly {
+				break
+			}
+			first := event.Start.In(utils.Location())
+			start = time.Date(first.Year(), first.Month(), first.Day()+7*(week+1), first.Hour(), first.Minute(), 0, 0, utils.Location())
+		}
+	}
+	return lectures
+}
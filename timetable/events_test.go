@@ -0,0 +1,65 @@
+package timetable
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+	"github.com/artem-streltsov/ucl-timetable-bot/utils"
+)
+
+func TestEventLectures(t *testing.T) {
+	at := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2025, month, day, hour, 0, 0, 0, utils.Location())
+	}
+	// Mondays at 09:00 either side of the clocks going back on 26 October.
+	weekly := models.Event{ID: 1, Title: "COMP0001 Tutorial", Location: "Online", Start: at(time.October, 13, 9), End: at(time.October, 13, 10), Weekly: true}
+	once := models.Event{ID: 2, Title: "Careers fair", Start: at(time.October, 22, 14), End: at(time.October, 22, 16)}
+
+	tests := []struct {
+		name     string
+		events   []models.Event
+		from, to time.Time
+		want     []time.Time // starts of the lectures returned
+	}{
+		{"weekly across the clock change", []models.Event{weekly}, at(time.October, 1, 0), at(time.November, 4, 0),
+			[]time.Time{at(time.October, 13, 9), at(time.October, 20, 9), at(time.October, 27, 9), at(time.November, 3, 9)}},
+		{"weekly from a later week", []models.Event{weekly}, at(time.October, 21, 0), at(time.October, 31, 0),
+			[]time.Time{at(time.October, 27, 9)}},
+		// An occurrence still running at from is included.
+		{"weekly overlapping from", []models.Event{weekly}, at(time.October, 20, 9).Add(30 * time.Minute), at(time.October, 21, 0),
+			[]time.Time{at(time.October, 20, 9)}},
+		{"weekly before it starts", []models.Event{weekly}, at(time.September, 1, 0), at(time.October, 12, 0), nil},
+		{"one-off in range", []models.Event{once}, at(time.October, 1, 0), at(time.October, 31, 0),
+			[]time.Time{at(time.October, 22, 14)}},
+		{"one-off out of range", []models.Event{once}, at(time.October, 23, 0), at(time.October, 31, 0), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lectures := EventLectures(tt.events, tt.from, tt.to)
+			var got []time.Time
+			for _, lecture := range lectures {
+				got = append(got, lecture.Start)
+				if d := lecture.End.Sub(lecture.Start); d != time.Hour && d != 2*time.Hour {
+					t.Errorf("%s lasts %s", lecture.Start, d)
+				}
+			}
+			if !slices.EqualFunc(got, tt.want, time.Time.Equal) {
+				t.Errorf("starts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	lectures := EventLectures([]models.Event{weekly}, at(time.October, 1, 0), at(time.October, 21, 0))
+	if len(lectures) != 2 {
+		t.Fatalf("got %d lectures, want 2", len(lectures))
+	}
+	first := lectures[0]
+	if first.UID != "event-1-20251013@ucl-timetable-bot" || first.UID == lectures[1].UID {
+		t.Errorf("UIDs = %q and %q, want one per occurrence", first.UID, lectures[1].UID)
+	}
+	if first.Title != "COMP0001 Tutorial" || first.Module != "COMP0001" || first.Type != "tutorial" || first.Location != "Online" {
+		t.Errorf("lecture = %+v, want the event's title, location, module and type", first)
+	}
+}
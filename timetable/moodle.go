@@ -0,0 +1,121 @@
+package timetable
+
+import (
+	"strings"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+
+	ical "github.com/arran4/golang-ical"
+)
+
+// moodleDueSuffixes end the summaries Moodle gives due dates, such as
+// "Coursework 1 is due". Other events, like "Quiz opens", are not deadlines.
+var moodleDueSuffixes = []string{" is due", " (due)", " due", " closes"}
+
+// MoodleDeadlines returns the due dates in a Moodle calendar export. The
+// module comes from the course shortname in CATEGORIES, falling back to a
+// module code in the summary.
+func MoodleDeadlines(cal *ical.Calendar) []models.Deadline {
+	var deadlines []models.Deadline
+	for _, event := range cal.Events() {
+		title, ok := moodleDueTitle(propertyValue(event, ical.ComponentPropertySummary))
+		if !ok {
+			continue
+		}
+		due, err := event.GetStartAt()
+		if err != nil {
+			continue
+		}
+		course := strings.TrimSpace(propertyValue(event, ical.ComponentPropertyCategories))
+		// Shortnames often add the year after an underscore, as in
+		// COMP0010_24-25, which \b does not separate from the code.
+		module := ModuleCode(strings.ToUpper(strings.ReplaceAll(course, "_", " ")))
+		if module == "" {
+			module = ModuleCode(title)
+		}
+		if module == "" {
+			module = course
+		}
+		deadlines = append(deadlines, models.Deadline{
+			Module: module,
+			Title:  title,
+			Due:    due,
+			UID:    event.Id(),
+		})
+	}
+	return deadlines
+}
+
+func moodleDueTitle(summary string) (string, bool) {
+	lower := strings.ToLower(summary)
+	for _, suffix := range moodleDueSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return strings.TrimSpace(summary[:len(summary)-len(suffix)]), true
+		}
+	}
+	return "", false
+}
+
+// DeadlineMove is an imported deadline whose due time changed.
+type DeadlineMove struct {
+	From models.Deadline
+	To   models.Deadline
+}
+
+// DeadlineChanges is what an import would change in a user's deadlines.
+// Updated deadlines only had their title or module changed.
+type DeadlineChanges struct {
+	Added   []models.Deadline
+	Moved   []DeadlineMove
+	Updated []models.Deadline
+	Removed []models.Deadline
+}
+
+func (c DeadlineChanges) Empty() bool {
+	return len(c.Added)+len(c.Moved)+len(c.Updated)+len(c.Removed) == 0
+}
+
+// DiffDeadlines compares the deadlines previously imported into existing
+// with a fresh import, matching them by UID. Deadlines that have passed are
+// left alone, so Moodle dropping old events from its export removes nothing.
+// Deadlines in the result carry the ID and chat of the stored deadline they
+// replace.
+func DiffDeadlines(existing, imported []models.Deadline, now time.Time) DeadlineChanges {
+	stored := make(map[string]models.Deadline)
+	for _, deadline := range existing {
+		if deadline.UID != "" {
+			stored[deadline.UID] = deadline
+		}
+	}
+
+	var changes DeadlineChanges
+	seen := make(map[string]bool)
+	for _, deadline := range imported {
+		if seen[deadline.UID] {
+			continue
+		}
+		seen[deadline.UID] = true
+		old, ok := stored[deadline.UID]
+		if !ok {
+			if deadline.Due.After(now) {
+				changes.Added = append(changes.Added, deadline)
+			}
+			continue
+		}
+		deadline.ID, deadline.ChatID = old.ID, old.ChatID
+		switch {
+		case !old.Due.After(now):
+		case !deadline.Due.Equal(old.Due):
+			changes.Moved = append(changes.Moved, DeadlineMove{From: old, To: deadline})
+		case deadline.Title != old.Title || deadline.Module != old.Module:
+			changes.Updated = append(changes.Updated, deadline)
+		}
+	}
+	for _, deadline := range existing {
+		if deadline.UID != "" && !seen[deadline.UID] && deadline.Due.After(now) {
+			changes.Removed = append(changes.Removed, deadline)
+		}
+	}
+	return changes
+}
@@ -0,0 +1,133 @@
+package timetable
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+
+	ical "github.com/arran4/golang-ical"
+)
+
+func TestMoodleDeadlines(t *testing.T) {
+	due := time.Date(2025, time.November, 14, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		summary    string
+		categories string
+		want       *models.Deadline // nil when the event is not a deadline
+	}{
+		{"is due", "Coursework 1 is due", "COMP0010", &models.Deadline{Module: "COMP0010", Title: "Coursework 1"}},
+		{"(due)", "Lab report (due)", "COMP0010", &models.Deadline{Module: "COMP0010", Title: "Lab report"}},
+		{"due", "Essay due", "COMP0010", &models.Deadline{Module: "COMP0010", Title: "Essay"}},
+		{"closes", "Quiz 3 closes", "COMP0010", &models.Deadline{Module: "COMP0010", Title: "Quiz 3"}},
+		{"suffix in another case", "Coursework 1 IS DUE", "COMP0010", &models.Deadline{Module: "COMP0010", Title: "Coursework 1"}},
+		{"opening is not a deadline", "Quiz 3 opens", "COMP0010", nil},
+		{"due in the middle is not a deadline", "Due dates announced", "COMP0010", nil},
+		{"shortname with the year", "Coursework 1 is due", "COMP0010_24-25", &models.Deadline{Module: "COMP0010", Title: "Coursework 1"}},
+		{"lower-case shortname", "Coursework 1 is due", "comp0010_24-25", &models.Deadline{Module: "COMP0010", Title: "Coursework 1"}},
+		{"code from the summary", "COMP0002 Coursework is due", "Programming", &models.Deadline{Module: "COMP0002", Title: "COMP0002 Coursework"}},
+		{"raw course name", "Coursework is due", "Programming", &models.Deadline{Module: "Programming", Title: "Coursework"}},
+		{"no course", "Coursework is due", "", &models.Deadline{Title: "Coursework"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cal := ical.NewCalendar()
+			event := cal.AddEvent("1@moodle")
+			event.SetStartAt(due)
+			event.SetSummary(tt.summary)
+			if tt.categories != "" {
+				event.SetProperty(ical.ComponentPropertyCategories, tt.categories)
+			}
+			// Round trip through the ICS text, as an import would.
+			parsed, err := ical.ParseCalendar(strings.NewReader(cal.Serialize()))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := MoodleDeadlines(parsed)
+			if tt.want == nil {
+				if len(got) != 0 {
+					t.Errorf("got %+v, want no deadlines", got)
+				}
+				return
+			}
+			want := *tt.want
+			want.Due, want.UID = due, "1@moodle"
+			if len(got) != 1 || !got[0].Due.Equal(want.Due) {
+				t.Fatalf("got %+v, want [%+v]", got, want)
+			}
+			got[0].Due = want.Due
+			if got[0] != want {
+				t.Errorf("got %+v, want %+v", got[0], want)
+			}
+		})
+	}
+}
+
+func TestDiffDeadlines(t *testing.T) {
+	now := time.Date(2025, time.November, 1, 12, 0, 0, 0, time.UTC)
+	later := now.Add(7 * 24 * time.Hour)
+	past := now.Add(-24 * time.Hour)
+	stored := func(id int64, uid, title string, due time.Time) models.Deadline {
+		return models.Deadline{ID: id, ChatID: 100, Module: "COMP0010", Title: title, Due: due, UID: uid}
+	}
+	imported := func(uid, title string, due time.Time) models.Deadline {
+		return models.Deadline{Module: "COMP0010", Title: title, Due: due, UID: uid}
+	}
+	carried := func(d models.Deadline, id int64) models.Deadline {
+		d.ID, d.ChatID = id, 100
+		return d
+	}
+
+	tests := []struct {
+		name     string
+		existing []models.Deadline
+		imported []models.Deadline
+		want     DeadlineChanges
+	}{
+		{"unchanged", []models.Deadline{stored(1, "a", "CW1", later)}, []models.Deadline{imported("a", "CW1", later)}, DeadlineChanges{}},
+		{"added",
+			nil,
+			[]models.Deadline{imported("a", "CW1", later)},
+			DeadlineChanges{Added: []models.Deadline{imported("a", "CW1", later)}}},
+		{"added in the past is skipped", nil, []models.Deadline{imported("a", "CW1", past)}, DeadlineChanges{}},
+		{"moved",
+			[]models.Deadline{stored(1, "a", "CW1", later)},
+			[]models.Deadline{imported("a", "CW1", later.Add(time.Hour))},
+			DeadlineChanges{Moved: []DeadlineMove{{From: stored(1, "a", "CW1", later), To: carried(imported("a", "CW1", later.Add(time.Hour)), 1)}}}},
+		{"renamed",
+			[]models.Deadline{stored(1, "a", "CW1", later)},
+			[]models.Deadline{imported("a", "Coursework 1", later)},
+			DeadlineChanges{Updated: []models.Deadline{carried(imported("a", "Coursework 1", later), 1)}}},
+		{"removed",
+			[]models.Deadline{stored(1, "a", "CW1", later), stored(2, "b", "CW2", later)},
+			[]models.Deadline{imported("a", "CW1", later)},
+			DeadlineChanges{Removed: []models.Deadline{stored(2, "b", "CW2", later)}}},
+		{"past deadlines are left alone",
+			[]models.Deadline{stored(1, "a", "CW1", past), stored(2, "b", "CW2", past)},
+			[]models.Deadline{imported("a", "CW1", later)},
+			DeadlineChanges{}},
+		{"deadlines added by hand are kept",
+			[]models.Deadline{stored(1, "", "Revision", later)},
+			nil,
+			DeadlineChanges{}},
+		{"duplicate UIDs count once",
+			nil,
+			[]models.Deadline{imported("a", "CW1", later), imported("a", "CW1 again", later)},
+			DeadlineChanges{Added: []models.Deadline{imported("a", "CW1", later)}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DiffDeadlines(tt.existing, tt.imported, now)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+			if got.Empty() != reflect.DeepEqual(tt.want, DeadlineChanges{}) {
+				t.Errorf("Empty() = %v", got.Empty())
+			}
+		})
+	}
+}
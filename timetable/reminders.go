@@ -0,0 +1,81 @@
+package timetable
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+)
+
+// SessionTypes are the kinds of session SessionType recognises.
+var SessionTypes = []string{"lecture", "lab", "practical", "tutorial", "seminar", "workshop"}
+
+var sessionTypePattern = regexp.MustCompile(`(?i)\b(lecture|lab|laboratory|practical|tutorial|seminar|workshop)s?\b`)
+
+// SessionType returns the kind of session named in title, such as "lecture"
+// or "lab", or "" if it names none.
+func SessionType(title string) string {
+	match := sessionTypePattern.FindStringSubmatch(title)
+	if match == nil {
+		return ""
+	}
+	if sessionType := strings.ToLower(match[1]); sessionType != "laboratory" {
+		return sessionType
+	}
+	return "lab"
+}
+
+// ReminderMinutes returns how many minutes before lecture to remind the user,
+// longest first, using the most specific of offsets that match it.
+func ReminderMinutes(offsets []models.ReminderOffset, lecture Lecture) []int {
+	best := -1
+	var minutes []int
+	for _, offset := range offsets {
+		if offset.Module != "" && offset.Module != lecture.Module ||
+			offset.SessionType != "" && offset.SessionType != lecture.Type {
+			continue
+		}
+		specificity := 0
+		if offset.Module != "" {
+			specificity += 2
+		}
+		if offset.SessionType != "" {
+			specificity++
+		}
+		switch {
+		case specificity > best:
+			best, minutes = specificity, []int{offset.Minutes}
+		case specificity == best && !slices.Contains(minutes, offset.Minutes):
+			minutes = append(minutes, offset.Minutes)
+		}
+	}
+	slices.SortFunc(minutes, func(a, b int) int { return b - a })
+	return minutes
+}
+
+// LectureKey identifies lecture by its start time and a hash of its UID, as
+// button data is too short to hold the UID itself.
+func LectureKey(lecture Lecture) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(lecture.UID))
+	return fmt.Sprintf("%d_%08x", lecture.Start.Unix(), hash.Sum32())
+}
+
+// FindLecture returns the lecture in lectures with the given LectureKey.
+func FindLecture(lectures []Lecture, key string) (Lecture, bool) {
+	start, _, _ := strings.Cut(key, "_")
+	unix, err := strconv.ParseInt(start, 10, 64)
+	if err != nil {
+		return Lecture{}, false
+	}
+	for _, lecture := range lectures {
+		if lecture.Start.Unix() == unix && LectureKey(lecture) == key {
+			return lecture, true
+		}
+	}
+	return Lecture{}, false
+}
@@ -0,0 +1,44 @@
+package timetable
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+)
+
+func TestReminderMinutes(t *testing.T) {
+	offset := func(minutes int, sessionType, module string) models.ReminderOffset {
+		return models.ReminderOffset{Minutes: minutes, SessionType: sessionType, Module: module}
+	}
+	offsets := []models.ReminderOffset{
+		offset(15, "", ""),
+		offset(60, "", ""),
+		offset(30, "lab", ""),
+		offset(45, "", "COMP0010"),
+		offset(5, "", "COMP0010"),
+		offset(90, "lab", "COMP0010"),
+	}
+	tests := []struct {
+		name    string
+		offsets []models.ReminderOffset
+		lecture Lecture
+		want    []int
+	}{
+		{"general offsets, longest first", offsets, Lecture{Module: "COMP0002", Type: "lecture"}, []int{60, 15}},
+		{"session type beats general", offsets, Lecture{Module: "COMP0002", Type: "lab"}, []int{30}},
+		{"module beats general", offsets, Lecture{Module: "COMP0010", Type: "lecture"}, []int{45, 5}},
+		{"module and type beat module", offsets, Lecture{Module: "COMP0010", Type: "lab"}, []int{90}},
+		{"module beats session type", offsets[:5], Lecture{Module: "COMP0010", Type: "lab"}, []int{45, 5}},
+		{"no module or type", offsets, Lecture{}, []int{60, 15}},
+		{"nothing matches", []models.ReminderOffset{offset(30, "lab", "")}, Lecture{Type: "lecture"}, nil},
+		{"duplicates count once", []models.ReminderOffset{offset(10, "", ""), offset(10, "", "")}, Lecture{}, []int{10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ReminderMinutes(tt.offsets, tt.lecture); !slices.Equal(got, tt.want) {
+				t.Errorf("ReminderMinutes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package timetable
+
+import (
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+)
+
+// Store holds the per-user data a timetable is built from besides the
+// calendar itself.
+type Store interface {
+	GetRules(chatID int64) ([]models.Rule, error)
+	GetEvents(chatID int64) ([]models.Event, error)
+}
+
+const (
+	// eventsBehind and eventsAhead bound how far weekly events are repeated.
+	eventsBehind = 90 * 24 * time.Hour
+	eventsAhead  = 365 * 24 * time.Hour
+)
+
+type compiledRule struct {
+	models.Rule
+	title *regexp.Regexp
+}
+
+// CompileTitlePattern compiles a title pattern the way rules match it:
+// case-insensitively, anywhere in the title.
+func CompileTitlePattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("(?i)" + pattern)
+}
+
+func (r compiledRule) matches(lecture Lecture) bool {
+	if r.Match == models.MatchModule {
+		return lecture.Module != "" && lecture.Module == r.Pattern
+	}
+	return r.title != nil && r.title.MatchString(lecture.Title)
+}
+
+// ApplyRules hides and renames lectures by rules. The first rule that matches
+// a lecture decides what happens to it. Rules with a pattern that no longer
+// compiles are ignored.
+func ApplyRules(lectures []Lecture, rules []models.Rule) []Lecture {
+	if len(rules) == 0 {
+		return lectures
+	}
+	compiled := make([]compiledRule, 0, len(rules))
+	for _, rule := range rules {
+		c := compiledRule{Rule: rule}
+		if rule.Match == models.MatchTitle {
+			re, err := CompileTitlePattern(rule.Pattern)
+			if err != nil {
+				continue
+			}
+			c.title = re
+		}
+		compiled = append(compiled, c)
+	}
+
+	result := make([]Lecture, 0, len(lectures))
+	for _, lecture := range lectures {
+		hidden := false
+		for _, rule := range compiled {
+			if !rule.matches(lecture) {
+				continue
+			}
+			if rule.Action == models.RuleHide {
+				hidden = true
+			} else {
+				lecture.Title = rule.Replacement
+			}
+			break
+		}
+		if !hidden {
+			result = append(result, lecture)
+		}
+	}
+	return result
+}
+
+// HasTimetable reports whether user has anything ForUser builds a timetable
+// from: a calendar link or events of their own. Failing to read the events
+// counts as having some, so that ForUser reports the error.
+func HasTimetable(store Store, user *models.User) bool {
+	if user.WebCalURL != "" {
+		return true
+	}
+	events, err := store.GetEvents(user.ChatID)
+	return err != nil || len(events) > 0
+}
+
+// ForUser fetches user's calendar and returns all of its lectures together
+// with the user's own events, in start order and with the user's rules
+// applied. Everything shown to the user should come from here.
+func ForUser(store Store, user *models.User) ([]Lecture, error) {
+	var lectures []Lecture
+	if user.WebCalURL != "" {
+		cal, err := FetchCalendar(user.WebCalURL)
+		if err != nil {
+			return nil, err
+		}
+		lectures = AllLectures(cal)
+	}
+
+	events, err := store.GetEvents(user.ChatID)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) > 0 {
+		now := time.Now()
+		lectures = append(lectures, EventLectures(events, now.Add(-eventsBehind), now.Add(eventsAhead))...)
+		sort.SliceStable(lectures, func(i, j int) bool {
+			return lectures[i].Start.Before(lectures[j].Start)
+		})
+	}
+
+	rules, err := store.GetRules(user.ChatID)
+	if err != nil {
+		return nil, err
+	}
+	return ApplyRules(lectures, rules), nil
+}
@@ -0,0 +1,89 @@
+package timetable
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/models"
+)
+
+func TestApplyRules(t *testing.T) {
+	start := time.Date(2025, time.October, 6, 9, 0, 0, 0, time.UTC)
+	lecture := func(title string) Lecture {
+		return Lecture{UID: title, Title: title, Module: ModuleCode(title), Start: start, End: start.Add(time.Hour)}
+	}
+	lectures := []Lecture{
+		lecture("COMP0001 Lecture"),
+		lecture("COMP0002 Drop-in Session"),
+		lecture("COMP00012 Seminar"),
+		lecture("Careers fair"),
+	}
+	hide := func(match, pattern string) models.Rule {
+		return models.Rule{Action: models.RuleHide, Match: match, Pattern: pattern}
+	}
+	rename := func(match, pattern, to string) models.Rule {
+		return models.Rule{Action: models.RuleRename, Match: match, Pattern: pattern, Replacement: to}
+	}
+
+	tests := []struct {
+		name  string
+		rules []models.Rule
+		want  []string // titles of the lectures left, in order
+	}{
+		{"no rules", nil, []string{"COMP0001 Lecture", "COMP0002 Drop-in Session", "COMP00012 Seminar", "Careers fair"}},
+		{"hide module", []models.Rule{hide(models.MatchModule, "COMP0002")},
+			[]string{"COMP0001 Lecture", "COMP00012 Seminar", "Careers fair"}},
+		// Module codes match whole; COMP00012 has no code at all.
+		{"module match is exact", []models.Rule{hide(models.MatchModule, "COMP0001")},
+			[]string{"COMP0002 Drop-in Session", "COMP00012 Seminar", "Careers fair"}},
+		{"module rule skips lectures without a code", []models.Rule{hide(models.MatchModule, "")},
+			[]string{"COMP0001 Lecture", "COMP0002 Drop-in Session", "COMP00012 Seminar", "Careers fair"}},
+		{"hide title case-insensitively", []models.Rule{hide(models.MatchTitle, "drop-in")},
+			[]string{"COMP0001 Lecture", "COMP00012 Seminar", "Careers fair"}},
+		{"hide title regexp", []models.Rule{hide(models.MatchTitle, `^COMP\d+ (Lecture|Seminar)$`)},
+			[]string{"COMP0002 Drop-in Session", "Careers fair"}},
+		{"rename module", []models.Rule{rename(models.MatchModule, "COMP0001", "Algorithms")},
+			[]string{"Algorithms", "COMP0002 Drop-in Session", "COMP00012 Seminar", "Careers fair"}},
+		{"rename title", []models.Rule{rename(models.MatchTitle, "careers", "Careers Fair (optional)")},
+			[]string{"COMP0001 Lecture", "COMP0002 Drop-in Session", "COMP00012 Seminar", "Careers Fair (optional)"}},
+		{"first match wins: rename before hide", []models.Rule{
+			rename(models.MatchModule, "COMP0002", "Office hours"),
+			hide(models.MatchTitle, "COMP"),
+		}, []string{"Office hours", "Careers fair"}},
+		{"first match wins: hide before rename", []models.Rule{
+			hide(models.MatchTitle, "COMP0002"),
+			rename(models.MatchModule, "COMP0002", "Office hours"),
+		}, []string{"COMP0001 Lecture", "COMP00012 Seminar", "Careers fair"}},
+		// A later rule does not see the title an earlier one gave.
+		{"renamed lectures are not matched again", []models.Rule{
+			rename(models.MatchModule, "COMP0001", "Careers talk"),
+			rename(models.MatchTitle, "careers", "Fair"),
+		}, []string{"Careers talk", "COMP0002 Drop-in Session", "COMP00012 Seminar", "Fair"}},
+		{"invalid pattern is ignored", []models.Rule{
+			hide(models.MatchTitle, "(unclosed"),
+			hide(models.MatchModule, "COMP0001"),
+		}, []string{"COMP0002 Drop-in Session", "COMP00012 Seminar", "Careers fair"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, l := range ApplyRules(lectures, tt.rules) {
+				got = append(got, l.Title)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ApplyRules = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Renaming changes the title only, so module rules and reminders still
+	// find the lecture by its code.
+	renamed := ApplyRules(lectures, []models.Rule{rename(models.MatchModule, "COMP0001", "Algorithms")})
+	if renamed[0].Module != "COMP0001" || renamed[0].UID != "COMP0001 Lecture" {
+		t.Errorf("renamed lecture = %+v, want its module and UID kept", renamed[0])
+	}
+	if lectures[0].Title != "COMP0001 Lecture" {
+		t.Error("ApplyRules changed the lectures passed in")
+	}
+}
@@ -6,21 +6,80 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/artem-streltsov/ucl-timetable-bot/metrics"
+	"github.com/artem-streltsov/ucl-timetable-bot/utils"
+
 	ical "github.com/arran4/golang-ical"
 )
 
-var ukLocation, _ = time.LoadLocation("Europe/London")
-
 type Lecture struct {
-	Title    string
+	UID   string
+	Title string
+	// Module is the module code found in the title, or "" if it has none.
+	Module string
+	// Type is the kind of session found in the title, such as "lab", or ""
+	// if it has none.
+	Type     string
 	Start    time.Time
 	End      time.Time
 	Location string
 }
 
+var moduleCodePattern = regexp.MustCompile(`\b[A-Z]{4}\d{4}\b`)
+
+type cachedCalendar struct {
+	cal       *ical.Calendar
+	fetchedAt time.Time
+}
+
+var (
+	cacheTTL time.Duration
+	cacheMu  sync.Mutex
+	cache    = make(map[string]cachedCalendar)
+)
+
+// SetCacheTTL sets how long FetchCalendar reuses a calendar it has already
+// fetched. A zero TTL disables caching.
+func SetCacheTTL(ttl time.Duration) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	cacheTTL = ttl
+}
+
 func FetchCalendar(link string) (*ical.Calendar, error) {
+	cacheMu.Lock()
+	ttl := cacheTTL
+	cached, ok := cache[link]
+	cacheMu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < ttl {
+		return cached.cal, nil
+	}
+
+	start := time.Now()
+	cal, err := fetchCalendar(link)
+	metrics.CalendarFetchDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.CalendarFetchErrors.Inc()
+		return nil, err
+	}
+
+	if ttl > 0 {
+		cacheMu.Lock()
+		for key, entry := range cache {
+			if time.Since(entry.fetchedAt) >= ttl {
+				delete(cache, key)
+			}
+		}
+		cache[link] = cachedCalendar{cal: cal, fetchedAt: time.Now()}
+		cacheMu.Unlock()
+	}
+	return cal, nil
+}
+
+func fetchCalendar(link string) (*ical.Calendar, error) {
 	if strings.HasPrefix(strings.ToLower(link), "webcal://") {
 		link = "https://" + link[len("webcal://"):]
 	}
@@ -40,50 +99,107 @@ func FetchCalendar(link string) (*ical.Calendar, error) {
 	return cal, nil
 }
 
-func GetLectures(cal *ical.Calendar, day time.Time) ([]Lecture, error) {
+// AllLectures returns every event in cal, ordered by start time.
+func AllLectures(cal *ical.Calendar) []Lecture {
 	var lectures []Lecture
 	for _, event := range cal.Events() {
-		start, err := event.GetStartAt()
-		if err != nil {
-			continue
-		}
-		end, err := event.GetEndAt()
-		if err != nil {
-			continue
-		}
-		start = start.In(ukLocation)
-		end = end.In(ukLocation)
-		if start.Year() == day.Year() && start.YearDay() == day.YearDay() {
-			lecture := Lecture{
-				Title:    event.GetProperty(ical.ComponentPropertySummary).Value,
-				Start:    start,
-				End:      end,
-				Location: event.GetProperty(ical.ComponentPropertyLocation).Value,
-			}
+		if lecture, ok := lectureFromEvent(event); ok {
 			lectures = append(lectures, lecture)
 		}
 	}
 	sort.SliceStable(lectures, func(i, j int) bool {
 		return lectures[i].Start.Before(lectures[j].Start)
 	})
-	return lectures, nil
+	return lectures
 }
 
-func GetLecturesInRange(cal *ical.Calendar, startDay, endDay time.Time) (map[string][]Lecture, error) {
-	lecturesMap := make(map[string][]Lecture)
-	day := startDay
-	for !day.After(endDay) {
-		lectures, err := GetLectures(cal, day)
-		if err != nil {
-			return nil, err
+// OnDay returns the lectures that start on day.
+func OnDay(lectures []Lecture, day time.Time) []Lecture {
+	var result []Lecture
+	for _, lecture := range lectures {
+		if lecture.Start.Year() == day.Year() && lecture.Start.YearDay() == day.YearDay() {
+			result = append(result, lecture)
 		}
-		if len(lectures) > 0 {
-			dayKey := day.Format("Monday")
-			lecturesMap[dayKey] = lectures
+	}
+	return result
+}
+
+func lectureFromEvent(event *ical.VEvent) (Lecture, bool) {
+	start, err := event.GetStartAt()
+	if err != nil {
+		return Lecture{}, false
+	}
+	end, err := event.GetEndAt()
+	if err != nil {
+		return Lecture{}, false
+	}
+	title := propertyValue(event, ical.ComponentPropertySummary)
+	return Lecture{
+		UID:      event.Id(),
+		Title:    title,
+		Module:   ModuleCode(title),
+		Type:     SessionType(title),
+		Start:    start.In(utils.Location()),
+		End:      end.In(utils.Location()),
+		Location: propertyValue(event, ical.ComponentPropertyLocation),
+	}, true
+}
+
+func propertyValue(event *ical.VEvent, property ical.ComponentProperty) string {
+	if p := event.GetProperty(property); p != nil {
+		return p.Value
+	}
+	return ""
+}
+
+// Day is the lectures on one date.
+type Day struct {
+	Date     time.Time
+	Lectures []Lecture
+}
+
+// NextOnDay returns the first of lectures that starts after now, followed by
+// the rest of that day's lectures. lectures must be sorted by start time.
+func NextOnDay(lectures []Lecture, now time.Time) []Lecture {
+	for i, lecture := range lectures {
+		if !lecture.Start.After(now) {
+			continue
 		}
-		day = day.AddDate(0, 0, 1)
+		day := lecture.Start.In(utils.Location()).Format(time.DateOnly)
+		end := i + 1
+		for end < len(lectures) && lectures[end].Start.In(utils.Location()).Format(time.DateOnly) == day {
+			end++
+		}
+		return lectures[i:end]
 	}
-	return lecturesMap, nil
+	return nil
+}
+
+// GroupByDay returns the days from startDay to endDay that have lectures, in
+// date order, each with its lectures in the order given. It makes a single
+// pass over lectures.
+func GroupByDay(lectures []Lecture, startDay, endDay time.Time) []Day {
+	first, last := startDay.Format(time.DateOnly), endDay.Format(time.DateOnly)
+	index := make(map[string]int)
+	var days []Day
+	for _, lecture := range lectures {
+		start := lecture.Start.In(utils.Location())
+		key := start.Format(time.DateOnly)
+		if key < first || key > last {
+			continue
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(days)
+			index[key] = i
+			days = append(days, Day{Date: time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, utils.Location())})
+		}
+		days[i].Lectures = append(days[i].Lectures, lecture)
+	}
+	sort.Slice(days, func(i, j int) bool {
+		return days[i].Date.Before(days[j].Date)
+	})
+	return days
 }
 
 func FormatLectures(lectures []Lecture) string {
@@ -109,3 +225,9 @@ func CleanTitle(title string) string {
 	title = strings.TrimSpace(title)
 	return title
 }
+
+// ModuleCode returns the UCL module code in title, such as COMP0010, or ""
+// if there is none.
+func ModuleCode(title string) string {
+	return moduleCodePattern.FindString(title)
+}
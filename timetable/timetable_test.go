@@ -0,0 +1,71 @@
+package timetable
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/artem-streltsov/ucl-timetable-bot/utils"
+)
+
+func TestGroupByDay(t *testing.T) {
+	at := func(day, hour int) time.Time {
+		return time.Date(2025, time.October, day, hour, 0, 0, 0, utils.Location())
+	}
+	lecture := func(title string, day, hour int) Lecture {
+		return Lecture{Title: title, Start: at(day, hour), End: at(day, hour+1)}
+	}
+	// Mondays 13 and 20 October, given out of date order.
+	lectures := []Lecture{
+		lecture("COMP0002 Lecture", 20, 9),
+		lecture("COMP0001 Lecture", 13, 9),
+		lecture("COMP0001 Lab", 13, 14),
+		lecture("COMP0003 Lecture", 14, 11),
+		lecture("COMP0004 Lecture", 21, 10),
+	}
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+		want       map[string][]string // titles by date
+		dates      []string
+	}{
+		{"two Mondays stay separate", at(13, 0), at(20, 0),
+			map[string][]string{
+				"2025-10-13": {"COMP0001 Lecture", "COMP0001 Lab"},
+				"2025-10-14": {"COMP0003 Lecture"},
+				"2025-10-20": {"COMP0002 Lecture"},
+			},
+			[]string{"2025-10-13", "2025-10-14", "2025-10-20"}},
+		{"end day is included", at(20, 23), at(21, 0),
+			map[string][]string{
+				"2025-10-20": {"COMP0002 Lecture"},
+				"2025-10-21": {"COMP0004 Lecture"},
+			},
+			[]string{"2025-10-20", "2025-10-21"}},
+		{"no lectures in range", at(15, 0), at(19, 0), nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			days := GroupByDay(lectures, tt.start, tt.end)
+			var dates []string
+			for _, day := range days {
+				date := day.Date.Format(time.DateOnly)
+				dates = append(dates, date)
+				var titles []string
+				for _, lecture := range day.Lectures {
+					titles = append(titles, lecture.Title)
+				}
+				if !slices.Equal(titles, tt.want[date]) {
+					t.Errorf("%s: lectures = %v, want %v", date, titles, tt.want[date])
+				}
+				if day.Date.Hour() != 0 || day.Date.Location() != utils.Location() {
+					t.Errorf("%s: Date = %v, want local midnight", date, day.Date)
+				}
+			}
+			if !slices.Equal(dates, tt.dates) {
+				t.Errorf("days = %v, want %v", dates, tt.dates)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dayMonthLayouts are the day-and-month formats ParseDate accepts, tried in
+// order. Formats without a year are completed by nearestYear.
+var dayMonthLayouts = []struct {
+	layout  string
+	hasYear bool
+}{
+	{"2006-01-02", true},
+	{"2 Jan 2006", true},
+	{"2 January 2006", true},
+	{"Jan 2 2006", true},
+	{"January 2 2006", true},
+	{"2/1/2006", true},
+	{"2 Jan", false},
+	{"2 January", false},
+	{"Jan 2", false},
+	{"January 2", false},
+	{"2/1", false},
+}
+
+// ParseDate reads a date relative to now, returning midnight of that day in
+// now's location. It accepts:
+//
+//   - today, tomorrow and yesterday
+//   - a weekday such as "tue", meaning the next one on or after today, or
+//     "next tue", the next one after today
+//   - "in 3 days" or "in 2 weeks"
+//   - 2026-11-03, "3 Nov", "3 November 2026", "Nov 3" and 3/11/2026
+//
+// A date without a year is the one closest to now.
+func ParseDate(s string, now time.Time) (time.Time, bool) {
+	s = strings.Join(strings.Fields(strings.ToLower(strings.ReplaceAll(s, ",", " "))), " ")
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch s {
+	case "today":
+		return today, true
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), true
+	case "yesterday":
+		return today.AddDate(0, 0, -1), true
+	}
+
+	if rest, ok := strings.CutPrefix(s, "in "); ok {
+		return addPeriod(today, rest)
+	}
+
+	if rest, ok := strings.CutPrefix(s, "next "); ok {
+		if weekday, ok := ParseWeekday(rest); ok {
+			days := (int(weekday)-int(today.Weekday())+6)%7 + 1
+			return today.AddDate(0, 0, days), true
+		}
+		return time.Time{}, false
+	}
+	if weekday, ok := ParseWeekday(s); ok {
+		return today.AddDate(0, 0, (int(weekday)-int(today.Weekday())+7)%7), true
+	}
+
+	for _, format := range dayMonthLayouts {
+		date, err := time.ParseInLocation(format.layout, s, now.Location())
+		if err != nil {
+			continue
+		}
+		if !format.hasYear {
+			return nearestYear(date, today)
+		}
+		return date, true
+	}
+	return time.Time{}, false
+}
+
+// addPeriod adds "N day(s)" or "N week(s)" to day.
+func addPeriod(day time.Time, period string) (time.Time, bool) {
+	count, unit, ok := strings.Cut(period, " ")
+	n, err := strconv.Atoi(count)
+	if !ok || err != nil || n < 0 {
+		return time.Time{}, false
+	}
+	switch unit {
+	case "day", "days":
+		return day.AddDate(0, 0, n), true
+	case "week", "weeks":
+		return day.AddDate(0, 0, 7*n), true
+	}
+	return time.Time{}, false
+}
+
+// nearestYear moves date, parsed without a year, to the year that puts it
+// closest to today. It fails for 29 February when no nearby year has one.
+func nearestYear(date, today time.Time) (time.Time, bool) {
+	best := time.Time{}
+	for _, year := range []int{today.Year() - 1, today.Year(), today.Year() + 1} {
+		candidate := time.Date(year, date.Month(), date.Day(), 0, 0, 0, 0, today.Location())
+		if candidate.Day() != date.Day() {
+			continue // 29 Feb outside a leap year
+		}
+		if best.IsZero() || absDuration(candidate.Sub(today)) < absDuration(best.Sub(today)) {
+			best = candidate
+		}
+	}
+	return best, !best.IsZero()
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// ParseDateRange reads two dates as accepted by ParseDate, optionally
+// separated by "to" or "-", such as "3 Nov to 14 Nov" or "today next fri".
+func ParseDateRange(s string, now time.Time) (from, to time.Time, ok bool) {
+	words := strings.Fields(s)
+	for i := 1; i < len(words); i++ {
+		first, second := words[:i], words[i:]
+		if len(second) > 1 && (strings.EqualFold(second[0], "to") || second[0] == "-") {
+			second = second[1:]
+		}
+		from, okFrom := ParseDate(strings.Join(first, " "), now)
+		to, okTo := ParseDate(strings.Join(second, " "), now)
+		if okFrom && okTo {
+			return from, to, true
+		}
+	}
+	return time.Time{}, time.Time{}, false
+}
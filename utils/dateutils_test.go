@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParseDate(t *testing.T) {
+	// A Wednesday, partway through the day.
+	wed := time.Date(2025, time.October, 15, 14, 30, 0, 0, time.UTC)
+	tests := []struct {
+		input string
+		now   time.Time
+		want  time.Time // zero when the input is invalid
+	}{
+		{"today", wed, date(2025, time.October, 15)},
+		{"Tomorrow", wed, date(2025, time.October, 16)},
+		{"yesterday", wed, date(2025, time.October, 14)},
+
+		// Weekdays: on or after today, or strictly after with "next".
+		{"wed", wed, date(2025, time.October, 15)},
+		{"tue", wed, date(2025, time.October, 21)},
+		{"Friday", wed, date(2025, time.October, 17)},
+		{"next wed", wed, date(2025, time.October, 22)},
+		{"next tue", wed, date(2025, time.October, 21)},
+		{"next  Thursday", wed, date(2025, time.October, 16)},
+
+		{"in 3 days", wed, date(2025, time.October, 18)},
+		{"in 1 day", wed, date(2025, time.October, 16)},
+		{"in 0 days", wed, date(2025, time.October, 15)},
+		{"in 2 weeks", wed, date(2025, time.October, 29)},
+
+		{"3 Nov", wed, date(2025, time.November, 3)},
+		{"3 november", wed, date(2025, time.November, 3)},
+		{"Nov 3", wed, date(2025, time.November, 3)},
+		{"3/11", wed, date(2025, time.November, 3)},
+		{"3 Nov 2026", wed, date(2026, time.November, 3)},
+		{"November 3, 2026", wed, date(2026, time.November, 3)},
+		{"2026-11-03", wed, date(2026, time.November, 3)},
+		{"3/11/2026", wed, date(2026, time.November, 3)},
+
+		// Without a year, the closest date wins, across the new year too.
+		{"3 Jan", date(2025, time.December, 20), date(2026, time.January, 3)},
+		{"20 Dec", date(2026, time.January, 5), date(2025, time.December, 20)},
+		{"1 Jul", date(2025, time.December, 20), date(2025, time.July, 1)},
+
+		// 29 February only exists in leap years.
+		{"29 Feb", date(2027, time.June, 1), date(2028, time.February, 29)},
+		{"29 Feb", date(2025, time.June, 1), date(2024, time.February, 29)},
+		{"29 Feb", date(2026, time.June, 1), time.Time{}},
+		{"29 Feb 2028", wed, date(2028, time.February, 29)},
+		{"29 Feb 2025", wed, time.Time{}},
+
+		{"", wed, time.Time{}},
+		{"someday", wed, time.Time{}},
+		{"next", wed, time.Time{}},
+		{"next month", wed, time.Time{}},
+		{"in three days", wed, time.Time{}},
+		{"in -1 days", wed, time.Time{}},
+		{"in 3 months", wed, time.Time{}},
+		{"32 Oct", wed, time.Time{}},
+		{"31 Nov", wed, time.Time{}},
+		{"13/13/2025", wed, time.Time{}},
+	}
+	for _, tt := range tests {
+		got, ok := ParseDate(tt.input, tt.now)
+		if tt.want.IsZero() {
+			if ok {
+				t.Errorf("ParseDate(%q, %s) = %s, want invalid", tt.input, tt.now.Format(time.DateOnly), got.Format(time.DateOnly))
+			}
+			continue
+		}
+		if !ok || !got.Equal(tt.want) {
+			t.Errorf("ParseDate(%q, %s) = %s, %v, want %s", tt.input, tt.now.Format(time.DateOnly), got.Format(time.DateOnly), ok, tt.want.Format(time.DateOnly))
+		}
+	}
+}
+
+func TestParseDateRange(t *testing.T) {
+	wed := time.Date(2025, time.October, 15, 14, 30, 0, 0, time.UTC)
+	tests := []struct {
+		input    string
+		from, to time.Time // zero when the input is invalid
+	}{
+		{"3 Nov to 14 Nov", date(2025, time.November, 3), date(2025, time.November, 14)},
+		{"3 Nov - 14 Nov", date(2025, time.November, 3), date(2025, time.November, 14)},
+		{"3 Nov 14 Nov", date(2025, time.November, 3), date(2025, time.November, 14)},
+		{"today next fri", date(2025, time.October, 15), date(2025, time.October, 17)},
+		{"tomorrow TO in 2 weeks", date(2025, time.October, 16), date(2025, time.October, 29)},
+		{"2025-12-20 to 3/1/2026", date(2025, time.December, 20), date(2026, time.January, 3)},
+
+		{"", time.Time{}, time.Time{}},
+		{"3 Nov", time.Time{}, time.Time{}},
+		{"3 Nov to", time.Time{}, time.Time{}},
+		{"to 14 Nov", time.Time{}, time.Time{}},
+		{"3 Nov to someday", time.Time{}, time.Time{}},
+	}
+	for _, tt := range tests {
+		from, to, ok := ParseDateRange(tt.input, wed)
+		if tt.from.IsZero() {
+			if ok {
+				t.Errorf("ParseDateRange(%q) = %s, %s, want invalid", tt.input, from.Format(time.DateOnly), to.Format(time.DateOnly))
+			}
+			continue
+		}
+		if !ok || !from.Equal(tt.from) || !to.Equal(tt.to) {
+			t.Errorf("ParseDateRange(%q) = %s, %s, %v, want %s, %s", tt.input, from.Format(time.DateOnly), to.Format(time.DateOnly), ok, tt.from.Format(time.DateOnly), tt.to.Format(time.DateOnly))
+		}
+	}
+}
@@ -1,22 +1,36 @@
 package utils
 
 import (
+	"fmt"
 	"strconv"
 	"strings"
 	"time"
 )
 
-var ukLocation, _ = time.LoadLocation("Europe/London")
+var location, _ = time.LoadLocation("Europe/London")
 
+// SetLocation changes the timezone all schedules and dates are computed in.
+// It must be called before the bot starts.
+func SetLocation(loc *time.Location) {
+	location = loc
+}
+
+func Location() *time.Location {
+	return location
+}
+
+// MaxReminderOffset is the most minutes before a session a reminder can be
+// sent.
+const MaxReminderOffset = 240
+
+// IsValidOffset reports whether offsetStr is a reminder offset of 1 to
+// MaxReminderOffset minutes.
 func IsValidOffset(offsetStr string) bool {
 	offset, err := strconv.Atoi(offsetStr)
 	if err != nil {
 		return false
 	}
-	if offset >= 1 && offset <= 60 {
-		return true
-	}
-	return false
+	return offset >= 1 && offset <= MaxReminderOffset
 }
 
 func IsValidTime(timeStr string) bool {
@@ -36,12 +50,7 @@ func IsValidDay(dayStr string) bool {
 }
 
 func GetNextTime(timeStr string) time.Time {
-	now := time.Now().In(ukLocation)
-	parsedTime, _ := time.Parse("15:04", timeStr)
-	nextTime := time.Date(now.Year(), now.Month(), now.Day(), parsedTime.Hour(), parsedTime.Minute(), 0, 0, now.Location())
-	if nextTime.Before(now) {
-		nextTime = nextTime.Add(24 * time.Hour)
-	}
+	nextTime := GetNextDayTime(timeStr)
 	if nextTime.Weekday() == time.Saturday {
 		nextTime = nextTime.Add(2 * 24 * time.Hour)
 	} else if nextTime.Weekday() == time.Sunday {
@@ -50,11 +59,23 @@ func GetNextTime(timeStr string) time.Time {
 	return nextTime
 }
 
+// GetNextDayTime returns the next time the clock shows timeStr, on any day
+// of the week.
+func GetNextDayTime(timeStr string) time.Time {
+	now := time.Now().In(location)
+	parsedTime, _ := time.Parse("15:04", timeStr)
+	nextTime := time.Date(now.Year(), now.Month(), now.Day(), parsedTime.Hour(), parsedTime.Minute(), 0, 0, now.Location())
+	if nextTime.Before(now) {
+		nextTime = nextTime.Add(24 * time.Hour)
+	}
+	return nextTime
+}
+
 func GetNextWeekTime(weekTimeStr string) time.Time {
 	parts := strings.SplitN(weekTimeStr, " ", 2)
 	dayStr, timeStr := parts[0], parts[1]
 	weekday := getWeekday(dayStr)
-	now := time.Now().In(ukLocation)
+	now := time.Now().In(location)
 	parsedTime, _ := time.Parse("15:04", timeStr)
 	nextTime := time.Date(now.Year(), now.Month(), now.Day(), parsedTime.Hour(), parsedTime.Minute(), 0, 0, now.Location())
 	for nextTime.Weekday() != weekday || nextTime.Before(now) {
@@ -83,3 +104,35 @@ func getWeekday(dayStr string) time.Weekday {
 		return time.Sunday
 	}
 }
+
+// ParseWeekday reads a day name, either abbreviated ("fri") or in full
+// ("Friday"), ignoring case.
+func ParseWeekday(s string) (time.Weekday, bool) {
+	s = strings.ToLower(s)
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		if s == name || s == name[:3] {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+// FormatCountdown describes d as the largest two units, such as "3d 4h" or
+// "25m". Durations under a minute read "now".
+func FormatCountdown(d time.Duration) string {
+	d = d.Truncate(time.Minute)
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	case minutes > 0:
+		return fmt.Sprintf("%dm", minutes)
+	default:
+		return "now"
+	}
+}